@@ -35,6 +35,7 @@ type unitMemory struct {
 //
 // The function simulates AI decision-making by considering firing at enemies within range,
 // moving towards visible enemies, and finally moving towards the chosen target.
+// It returns when the connection of the client is closed.
 func RunAI(client *remote.Client) {
 
 	// Create a memory to store target coordinates for units.
@@ -43,8 +44,15 @@ func RunAI(client *remote.Client) {
 
 	// Main AI loop
 	for {
-		time.Sleep(50 * time.Millisecond) // Prevent server denial of service (DoS) by pacing requests.
-		world := client.Status()          // Get the current state of the game world from the server.
+		select {
+		case <-client.Done():
+			return // Stop the AI when the connection is closed.
+		case <-time.After(50 * time.Millisecond): // Pace requests to stay within the server rate limits (see remote.Limits).
+		}
+		world := client.Status() // Get the current state of the game world from the server.
+		if world == nil {
+			continue // NEXT AI LOOP
+		}
 
 		// Get all enemy bases on the map.
 		targets := make([]*core.Tile, 0, 8)
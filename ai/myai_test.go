@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/SchnorcherSepp/TankWars2/remote/remotetest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunAI(t *testing.T) {
+	world, err := remotetest.ParseWorld("terrain:\nG G G B\n\nunits:\nT1. . .\n")
+	require.NoError(t, err)
+	s := remotetest.NewServer(t, world, 1)
+	client, err := s.Connect()
+	require.NoError(t, err)
+	go RunAI(client)
+	require.NoError(t, s.Step(1)) // visibility
+
+	// the tank moves to the enemy base
+	move, err := s.WaitCommand(1, core.MOVE)
+	require.NoError(t, err)
+	assert.Equal(t, "MOVE 0 0 3 0", move.Command)
+	assert.Contains(t, move.Response, `"OK":true`)
+	assert.Empty(t, s.Commands(1, "DEBUG")) // no debug annotations
+
+	// until the tank has left its tile
+	for i := 0; i < 10 && world.Tile(0, 0).Unit != nil; i++ {
+		require.NoError(t, s.Step(core.GameSpeed))
+	}
+	assert.Nil(t, world.Tile(0, 0).Unit)
+	assert.Equal(t, uint8(core.RED), world.Tile(1, 0).Unit.Player)
+}
@@ -0,0 +1,53 @@
+package ai
+
+/*
+  This file provides the registry of the bots that a server can run in-process (see remote.Server.ConnectBot).
+  The basic AI (RunAI) is registered as "basic". Own bots can be added with Register.
+*/
+
+import (
+	"github.com/SchnorcherSepp/TankWars2/remote"
+	"sort"
+	"sync"
+)
+
+// Bot controls a player with the given client until the game ends (BLOCKING!).
+type Bot func(client *remote.Client)
+
+// registry contains all registered bots by name.
+var registry = struct {
+	sync.Mutex
+	bots map[string]Bot
+}{bots: map[string]Bot{
+	"basic": RunAI,
+}}
+
+// Register adds a bot with the given name (an existing bot is replaced).
+func Register(name string, bot Bot) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	registry.bots[name] = bot
+}
+
+// Lookup returns the bot with the given name.
+func Lookup(name string) (Bot, bool) {
+	registry.Lock()
+	defer registry.Unlock()
+
+	bot, ok := registry.bots[name]
+	return bot, ok
+}
+
+// Names returns the sorted names of all registered bots.
+func Names() []string {
+	registry.Lock()
+	defer registry.Unlock()
+
+	names := make([]string, 0, len(registry.bots))
+	for name := range registry.bots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -72,7 +72,9 @@ func processFire(world *World) {
 
 			// Attack target unit
 			targetUnit := target.Unit
-			if targetUnit != nil {
+			if targetUnit == nil {
+				world.addOutcome(attacker, OutcomeMiss, 0, false)
+			} else {
 
 				// calc and add damage to target unit
 				damage, critical := calcDamage(attacker.Demoralized, targetUnit.Armour)
@@ -82,9 +84,12 @@ func processFire(world *World) {
 				}
 
 				// Eliminate target unit if health is zero or negative
-				if targetUnit.Health <= 0 {
+				killed := targetUnit.Health <= 0
+				if killed {
+					world.addOutcome(targetUnit, OutcomeDied, 0, false)
 					target.Unit = nil // Remove unit from tile
 				}
+				world.addOutcome(attacker, OutcomeHit, damage, killed)
 			}
 		}
 	}
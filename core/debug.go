@@ -0,0 +1,180 @@
+package core
+
+/*
+  This file provides debug annotations of the players. An AI can mark tiles, draw lines between tiles
+  and label tiles to show what it is "thinking" (e.g. targets and planned paths). The annotations are
+  stored per player in the world, expire after a lifetime in iterations (see Update) and are only
+  visible to their own player (see Censorship). They don't affect the game.
+*/
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// debug annotation kinds
+const (
+	DebugMark  = "MARK"  // marker on a tile
+	DebugLine  = "LINE"  // line from a tile to another tile
+	DebugLabel = "LABEL" // text on a tile
+)
+
+// debug annotation limits
+const (
+	MaxDebugShapes = 500            // Max. number of annotations per player (the oldest are removed)
+	MaxDebugTTL    = 60 * GameSpeed // Max. lifetime of an annotation in iterations
+	MaxDebugText   = 32             // Max. length of a label
+)
+
+// debugColors are the named colors of the annotations.
+var debugColors = map[string]string{
+	"RED":    "#ff0000",
+	"GREEN":  "#00ff00",
+	"BLUE":   "#0000ff",
+	"YELLOW": "#ffff00",
+	"ORANGE": "#ff8000",
+	"PURPLE": "#8000ff",
+	"CYAN":   "#00ffff",
+	"WHITE":  "#ffffff",
+	"BLACK":  "#000000",
+}
+
+//--------  Struct  --------------------------------------------------------------------------------------------------//
+
+// DebugShape is a debug annotation of a player.
+type DebugShape struct {
+	Kind    string // Kind of the annotation (see DebugMark, DebugLine and DebugLabel)
+	X       int    // Column of the tile
+	Y       int    // Row of the tile
+	X2      int    `json:",omitempty"` // Column of the end tile (DebugLine)
+	Y2      int    `json:",omitempty"` // Row of the end tile (DebugLine)
+	Text    string `json:",omitempty"` // Text (DebugLabel)
+	Color   string // Color as '#rrggbb'
+	Expires uint64 // Iteration at which the annotation is removed
+}
+
+// DebugColor returns a color name (e.g. 'red') or hex color (e.g. '#ff8000' or 'ff8000') as '#rrggbb'.
+func DebugColor(s string) (string, bool) {
+	if c, ok := debugColors[strings.ToUpper(s)]; ok {
+		return c, true
+	}
+	hex := strings.ToLower(strings.TrimPrefix(s, "#"))
+	if len(hex) != 6 || strings.Trim(hex, "0123456789abcdef") != "" {
+		return "", false
+	}
+	return "#" + hex, true
+}
+
+//--------  Getter  --------------------------------------------------------------------------------------------------//
+
+// DebugShapes returns a copy of the annotations of the player (0 = all players, sorted by player).
+// Unlike reading the field directly, this is safe while another goroutine calls AddDebug or Update.
+func (w *World) DebugShapes(player uint8) []DebugShape {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	// sort players (stable order)
+	players := make([]uint8, 0, len(w.Debug))
+	for p := range w.Debug {
+		if player == 0 || player == p {
+			players = append(players, p)
+		}
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i] < players[j]
+	})
+
+	// copy annotations
+	list := make([]DebugShape, 0)
+	for _, p := range players {
+		for _, s := range w.Debug[p] {
+			if s != nil {
+				list = append(list, *s)
+			}
+		}
+	}
+	return list
+}
+
+//--------  Setter  --------------------------------------------------------------------------------------------------//
+
+// AddDebug adds an annotation of the player that is removed after ttl iterations.
+// The tiles must exist, the color is normalized (see DebugColor) and the text is limited to MaxDebugText.
+// If the player has too many annotations, the oldest one is removed.
+func (w *World) AddDebug(player uint8, shape DebugShape, ttl int) error {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	// check input
+	if w.Tile(shape.X, shape.Y) == nil {
+		return ErrInvalidInput
+	}
+	switch shape.Kind {
+	case DebugMark:
+		shape.X2, shape.Y2, shape.Text = 0, 0, ""
+	case DebugLine:
+		if w.Tile(shape.X2, shape.Y2) == nil {
+			return ErrInvalidInput
+		}
+		shape.Text = ""
+	case DebugLabel:
+		if shape.Text == "" || len(shape.Text) > MaxDebugText {
+			return fmt.Errorf("label must have 1 to %d characters", MaxDebugText)
+		}
+		shape.X2, shape.Y2 = 0, 0
+	default:
+		return fmt.Errorf("unknown debug kind %q", shape.Kind)
+	}
+	color, ok := DebugColor(shape.Color)
+	if !ok {
+		return fmt.Errorf("unknown color %q", shape.Color)
+	}
+	if ttl < 1 || ttl > MaxDebugTTL {
+		return fmt.Errorf("lifetime must be 1 to %d iterations", MaxDebugTTL)
+	}
+	shape.Color = color
+	shape.Expires = w.Iteration + uint64(ttl)
+
+	// add annotation
+	if w.Debug == nil {
+		w.Debug = make(map[uint8][]*DebugShape)
+	}
+	list := append(w.Debug[player], &shape)
+	if len(list) > MaxDebugShapes {
+		list = list[len(list)-MaxDebugShapes:]
+	}
+	w.Debug[player] = list
+	return nil
+}
+
+// ClearDebug removes all annotations of the player.
+func (w *World) ClearDebug(player uint8) {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	delete(w.Debug, player)
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// expireDebug removes all expired annotations.
+func expireDebug(world *World) {
+	if world == nil {
+		return
+	}
+
+	for player, list := range world.Debug {
+		keep := list[:0]
+		for _, s := range list {
+			if s != nil && s.Expires > world.Iteration {
+				keep = append(keep, s)
+			}
+		}
+		if len(keep) == 0 {
+			delete(world.Debug, player)
+		} else {
+			world.Debug[player] = keep
+		}
+	}
+}
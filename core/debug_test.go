@@ -0,0 +1,121 @@
+package core
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDebugColor(t *testing.T) {
+	c, ok := DebugColor("red")
+	assert.True(t, ok)
+	assert.Equal(t, "#ff0000", c)
+	c, ok = DebugColor("FF8000")
+	assert.True(t, ok)
+	assert.Equal(t, "#ff8000", c)
+	c, ok = DebugColor("#0a0B0c")
+	assert.True(t, ok)
+	assert.Equal(t, "#0a0b0c", c)
+
+	// error
+	_, ok = DebugColor("pink")
+	assert.False(t, ok)
+	_, ok = DebugColor("#12345g")
+	assert.False(t, ok)
+}
+
+func TestAddDebug(t *testing.T) {
+	world := NewWorld(5, 5)
+	world.Iteration = 10
+
+	// valid annotations
+	assert.NoError(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, X: 1, Y: 1, Color: "red", Text: "x"}, 5))
+	assert.NoError(t, world.AddDebug(RED, DebugShape{Kind: DebugLine, X: 0, Y: 0, X2: 4, Y2: 4, Color: "00ff00"}, 1))
+	assert.NoError(t, world.AddDebug(BLUE, DebugShape{Kind: DebugLabel, X: 2, Y: 3, Color: "blue", Text: "target"}, 2))
+	assert.Len(t, world.Debug[RED], 2)
+	assert.Equal(t, DebugShape{Kind: DebugMark, X: 1, Y: 1, Color: "#ff0000", Expires: 15}, *world.Debug[RED][0])
+
+	// invalid annotations
+	assert.ErrorIs(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, X: 5, Y: 1, Color: "red"}, 5), ErrInvalidInput)
+	assert.ErrorIs(t, world.AddDebug(RED, DebugShape{Kind: DebugLine, X: 1, Y: 1, X2: -1, Color: "red"}, 5), ErrInvalidInput)
+	assert.Error(t, world.AddDebug(RED, DebugShape{Kind: DebugLabel, X: 1, Y: 1, Color: "red"}, 5))
+	assert.Error(t, world.AddDebug(RED, DebugShape{Kind: "CIRCLE", X: 1, Y: 1, Color: "red"}, 5))
+	assert.Error(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, X: 1, Y: 1, Color: "pink"}, 5))
+	assert.Error(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, X: 1, Y: 1, Color: "red"}, 0))
+	assert.Error(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, X: 1, Y: 1, Color: "red"}, MaxDebugTTL+1))
+	assert.Len(t, world.Debug[RED], 2)
+
+	// limit
+	for i := 0; i < MaxDebugShapes; i++ {
+		assert.NoError(t, world.AddDebug(GREEN, DebugShape{Kind: DebugMark, Y: i % 5, Color: "red"}, 1))
+	}
+	assert.NoError(t, world.AddDebug(GREEN, DebugShape{Kind: DebugMark, X: 4, Color: "red"}, 1))
+	assert.Len(t, world.Debug[GREEN], MaxDebugShapes)
+	assert.Equal(t, 1, world.Debug[GREEN][0].Y) // oldest removed
+	assert.Equal(t, 4, world.Debug[GREEN][MaxDebugShapes-1].X)
+
+	// clear
+	world.ClearDebug(GREEN)
+	assert.Nil(t, world.Debug[GREEN])
+}
+
+func TestDebugShapes(t *testing.T) {
+	world := NewWorld(5, 5)
+	assert.NoError(t, world.AddDebug(BLUE, DebugShape{Kind: DebugMark, X: 2, Color: "blue"}, 5))
+	assert.NoError(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, X: 1, Color: "red"}, 5))
+	assert.Empty(t, world.DebugShapes(GREEN))
+
+	// copies of all players (sorted by player)
+	list := world.DebugShapes(0)
+	assert.Equal(t, []int{1, 2}, []int{list[0].X, list[1].X})
+	list[0].X = 4
+	assert.Equal(t, 1, world.Debug[RED][0].X)
+	assert.Len(t, world.DebugShapes(BLUE), 1)
+
+	// concurrent writes (see go test -race)
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			_ = world.AddDebug(GREEN, DebugShape{Kind: DebugMark, Color: "red"}, 1)
+			world.Update()
+		}
+		close(done)
+	}()
+	for i := 0; i < 100; i++ {
+		_ = world.DebugShapes(0)
+	}
+	<-done
+}
+
+func TestExpireDebug(t *testing.T) {
+	world := NewWorld(5, 5)
+	assert.NoError(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, Color: "red"}, 1))
+	assert.NoError(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, Color: "red"}, 2))
+	assert.NoError(t, world.AddDebug(BLUE, DebugShape{Kind: DebugMark, Color: "red"}, 1))
+
+	world.Update()
+	assert.Len(t, world.Debug[RED], 1)
+	assert.NotContains(t, world.Debug, uint8(BLUE))
+	world.Update()
+	assert.Empty(t, world.Debug)
+
+	// frozen world
+	assert.NoError(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, Color: "red"}, 1))
+	world.SetFreeze(true)
+	world.Update()
+	assert.Len(t, world.Debug[RED], 1)
+
+	// error
+	expireDebug(nil)
+}
+
+func TestCensorshipDebug(t *testing.T) {
+	world := NewWorld(5, 5)
+	assert.NoError(t, world.AddDebug(RED, DebugShape{Kind: DebugMark, Color: "red"}, 10))
+	assert.NoError(t, world.AddDebug(BLUE, DebugShape{Kind: DebugMark, Color: "blue"}, 10))
+
+	red := Censorship(world, RED)
+	assert.Len(t, red.Debug, 1)
+	assert.Equal(t, "#ff0000", red.Debug[RED][0].Color)
+	assert.Nil(t, Censorship(world, GREEN).Debug)
+	assert.Len(t, world.Debug, 2) // original is not changed
+}
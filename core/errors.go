@@ -0,0 +1,21 @@
+package core
+
+/*
+  This file defines the errors returned by the commands of the game world (see Move, Fire and Schedule).
+  The error messages are part of the network protocol and must not be changed.
+*/
+
+import "errors"
+
+// command errors
+var (
+	ErrInvalidInput  = errors.New("input is nil")                                   // start or target tile does not exist
+	ErrNoUnit        = errors.New("no player unit found")                           // no unit of the player on the start tile
+	ErrBusy          = errors.New("unit is already processing a command")           // unit has an activity
+	ErrNoPath        = errors.New("target is not a neighbor and no path was found") // pathfinding failed
+	ErrInvalidTarget = errors.New("invalid target for this unit")                   // unit can't enter the target tile
+	ErrNotInRange    = errors.New("target is not in range")                         // target is outside the fire range
+	ErrNoAmmo        = errors.New("no ammunition")                                  // unit has less than one ammunition
+	ErrPastIteration = errors.New("iteration has already passed")                   // scheduled command is too late
+	ErrQueueFull     = errors.New("too many scheduled commands")                    // see MaxScheduled
+)
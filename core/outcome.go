@@ -0,0 +1,113 @@
+package core
+
+/*
+  This file reports the outcome of the commands. Every accepted MOVE or FIRE gets a command ID
+  (see Activity.ID), which is unique per player. When a command ends, the Update() function adds an
+  Outcome to the queue of the player: a move is completed or aborted because the target tile is occupied,
+  a shot hits a unit or misses, a unit with a command dies and a scheduled command is rejected (see Schedule).
+  The queue is read with TakeOutcomes. It is not part of the JSON of the world, so it is neither cloned nor
+  sent with the status.
+*/
+
+// outcome results
+const (
+	OutcomeCompleted = "COMPLETED" // the move has finished
+	OutcomeOccupied  = "OCCUPIED"  // the move was aborted, because the target tile is occupied
+	OutcomeHit       = "HIT"       // the shot has hit a unit
+	OutcomeMiss      = "MISS"      // the shot has hit no unit (only the terrain)
+	OutcomeDied      = "DIED"      // the unit was destroyed before the command has finished
+	OutcomeRejected  = "REJECTED"  // the scheduled command was invalid when it should start (see Schedule)
+)
+
+// MaxOutcomes is the max. number of outcomes per player (the oldest are removed).
+const MaxOutcomes = 500
+
+//--------  Struct  --------------------------------------------------------------------------------------------------//
+
+// Outcome is the result of a command of a player.
+type Outcome struct {
+	Command   uint64 // Command ID (see Activity.ID)
+	Name      string // Name of the command (MOVE, FIRE)
+	Unit      int    // ID of the unit
+	Type      byte   `json:",omitempty"` // Type of the unit (see UNITS)
+	Result    string // Result of the command (see OutcomeCompleted, OutcomeOccupied, ...)
+	Error     string `json:",omitempty"` // Reason of the rejection (OutcomeRejected)
+	From      [2]int // Starting coordinates of the command
+	To        [2]int // Destination coordinates of the command
+	Damage    int    `json:",omitempty"` // Damage dealt to the target unit (OutcomeHit)
+	Killed    bool   `json:",omitempty"` // Indicates if the target unit was destroyed (OutcomeHit)
+	Iteration uint64 // Iteration of the outcome
+}
+
+//--------  Getter  --------------------------------------------------------------------------------------------------//
+
+// TakeOutcomes returns and removes all outcomes of the player (oldest first).
+func (w *World) TakeOutcomes(player uint8) []*Outcome {
+	return w.TakeOutcomesFunc(player, nil)
+}
+
+// TakeOutcomesFunc returns and removes the outcomes of the player for which match returns true (nil = all).
+// The other outcomes stay in the queue, e.g. for another client of the same player.
+func (w *World) TakeOutcomesFunc(player uint8, match func(o *Outcome) bool) []*Outcome {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	var taken, keep []*Outcome
+	for _, o := range w.outcomes[player] {
+		if match == nil || match(o) {
+			taken = append(taken, o)
+		} else {
+			keep = append(keep, o)
+		}
+	}
+	if len(keep) > 0 {
+		w.outcomes[player] = keep
+	} else {
+		delete(w.outcomes, player)
+	}
+	return taken
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// nextCommandID returns a new command ID of the player.
+func (w *World) nextCommandID(player uint8) uint64 {
+	if w.commandIDs == nil {
+		w.commandIDs = make(map[uint8]uint64)
+	}
+	w.commandIDs[player]++
+	return w.commandIDs[player]
+}
+
+// addOutcome adds the outcome of the unit's current command to the queue of the player.
+// Activities without an ID (e.g. set directly on the unit) are ignored.
+func (w *World) addOutcome(unit *Unit, result string, damage int, killed bool) {
+	if unit == nil || unit.Activity == nil || unit.Activity.ID == 0 {
+		return
+	}
+
+	w.pushOutcome(unit.Player, &Outcome{
+		Command:   unit.Activity.ID,
+		Name:      unit.Activity.Name,
+		Unit:      unit.ID,
+		Type:      unit.Type,
+		Result:    result,
+		From:      unit.Activity.From,
+		To:        unit.Activity.To,
+		Damage:    damage,
+		Killed:    killed,
+		Iteration: w.Iteration,
+	})
+}
+
+// pushOutcome adds the outcome to the queue of the player. If the queue is full, the oldest outcome is removed.
+func (w *World) pushOutcome(player uint8, o *Outcome) {
+	if w.outcomes == nil {
+		w.outcomes = make(map[uint8][]*Outcome)
+	}
+	list := append(w.outcomes[player], o)
+	if len(list) > MaxOutcomes {
+		list = list[len(list)-MaxOutcomes:]
+	}
+	w.outcomes[player] = list
+}
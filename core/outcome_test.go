@@ -0,0 +1,131 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outcomeWorld returns a grass world with attributes set for all units.
+func outcomeWorld(units map[[2]int]*Unit) *World {
+	world := NewWorld(6, 3)
+	for _, t := range world.TileList(0) {
+		t.Type = GRASS
+	}
+	for pos, u := range units {
+		world.Tile(pos[0], pos[1]).Unit = u
+	}
+	updateUnitAttributes(world) // set attributes!!
+	return world
+}
+
+// processCommands processes the commands for n iterations.
+func processCommands(world *World, n int) {
+	for i := 0; i < n; i++ {
+		processMove(world)
+		processFire(world)
+		world.Iteration++
+	}
+}
+
+func TestOutcomeMove(t *testing.T) {
+	tank := NewUnit(RED, TANK)
+	world := outcomeWorld(map[[2]int]*Unit{{0, 0}: tank})
+
+	// completed
+	_, err := world.Move(world.Tile(0, 0), world.Tile(1, 0), RED)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), tank.Activity.ID)
+	processCommands(world, int(tank.Speed)+2)
+	assert.Nil(t, tank.Activity)
+	list := world.TakeOutcomes(RED)
+	require.Len(t, list, 1)
+	assert.Equal(t, Outcome{Command: 1, Name: MOVE, Unit: tank.ID, Type: TANK, Result: OutcomeCompleted,
+		From: [2]int{0, 0}, To: [2]int{1, 0}, Iteration: tank.Speed + 1}, *list[0])
+	assert.Empty(t, world.TakeOutcomes(RED))
+
+	// occupied
+	_, err = world.Move(world.Tile(1, 0), world.Tile(2, 0), RED)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), tank.Activity.ID)
+	world.Tile(2, 0).Unit = NewUnit(BLUE, SOLDIER)
+	processCommands(world, int(tank.Speed)+2)
+	list = world.TakeOutcomes(RED)
+	require.Len(t, list, 1)
+	assert.Equal(t, OutcomeOccupied, list[0].Result)
+	assert.Equal(t, uint64(2), list[0].Command)
+	assert.Equal(t, tank, world.Tile(1, 0).Unit)
+}
+
+func TestOutcomeFire(t *testing.T) {
+	tank := NewUnit(RED, TANK)
+	artillery := NewUnit(BLUE, ARTILLERY)
+	artillery.Health = 1
+	world := outcomeWorld(map[[2]int]*Unit{{0, 0}: tank, {2, 0}: artillery})
+
+	// hit and died
+	require.NoError(t, world.Fire(world.Tile(2, 0), world.Tile(5, 0), BLUE))
+	require.NoError(t, world.Fire(world.Tile(0, 0), world.Tile(2, 0), RED))
+	processCommands(world, int(tank.FireSpeed))
+	list := world.TakeOutcomes(RED)
+	require.Len(t, list, 1)
+	assert.Equal(t, OutcomeHit, list[0].Result)
+	assert.Equal(t, FIRE, list[0].Name)
+	assert.GreaterOrEqual(t, list[0].Damage, 3)
+	assert.True(t, list[0].Killed)
+	list = world.TakeOutcomes(BLUE)
+	require.Len(t, list, 1)
+	assert.Equal(t, Outcome{Command: 1, Name: FIRE, Unit: artillery.ID, Type: ARTILLERY, Result: OutcomeDied,
+		From: [2]int{2, 0}, To: [2]int{5, 0}, Iteration: tank.FireSpeed - 1}, *list[0])
+
+	// miss
+	processCommands(world, 2)
+	require.NoError(t, world.Fire(world.Tile(0, 0), world.Tile(2, 0), RED))
+	processCommands(world, int(tank.FireSpeed))
+	list = world.TakeOutcomes(RED)
+	require.Len(t, list, 1)
+	assert.Equal(t, OutcomeMiss, list[0].Result)
+	assert.Equal(t, uint64(2), list[0].Command)
+}
+
+func TestOutcomeLimit(t *testing.T) {
+	tank := NewUnit(RED, TANK)
+	world := outcomeWorld(map[[2]int]*Unit{{0, 0}: tank})
+	for i := 0; i < MaxOutcomes+5; i++ {
+		tank.Activity = &Activity{ID: uint64(i + 1), Name: MOVE}
+		world.addOutcome(tank, OutcomeCompleted, 0, false)
+	}
+	list := world.TakeOutcomes(RED)
+	require.Len(t, list, MaxOutcomes)
+	assert.Equal(t, uint64(6), list[0].Command)
+
+	// matching outcomes
+	tank.Activity = &Activity{ID: 1, Name: MOVE}
+	world.addOutcome(tank, OutcomeCompleted, 0, false)
+	world.addOutcome(tank, OutcomeOccupied, 0, false)
+	list = world.TakeOutcomesFunc(RED, func(o *Outcome) bool { return o.Result == OutcomeOccupied })
+	require.Len(t, list, 1)
+	assert.Equal(t, OutcomeOccupied, list[0].Result)
+	list = world.TakeOutcomes(RED)
+	require.Len(t, list, 1)
+	assert.Equal(t, OutcomeCompleted, list[0].Result)
+
+	// activities without ID are ignored
+	tank.Activity = &Activity{Name: MOVE}
+	world.addOutcome(tank, OutcomeCompleted, 0, false)
+	assert.Empty(t, world.TakeOutcomes(RED))
+}
+
+func TestCensorshipCommandID(t *testing.T) {
+	world := outcomeWorld(map[[2]int]*Unit{{0, 0}: NewUnit(RED, TANK), {1, 0}: NewUnit(BLUE, TANK)})
+	_, err := world.Move(world.Tile(0, 0), world.Tile(0, 1), RED)
+	require.NoError(t, err)
+	_, err = world.Move(world.Tile(1, 0), world.Tile(1, 1), BLUE)
+	require.NoError(t, err)
+	updateVisibility(world)
+
+	censored := Censorship(world, RED)
+	assert.Equal(t, uint64(1), censored.Tile(0, 0).Unit.Activity.ID)
+	assert.Equal(t, uint64(0), censored.Tile(1, 0).Unit.Activity.ID)
+}
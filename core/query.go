@@ -0,0 +1,94 @@
+package core
+
+/*
+  This file provides read-only queries on the game world (distance, path cost and fire checks).
+  The queries don't change the world and can be evaluated on a censored world (see Censorship).
+*/
+
+//--------  Getter  --------------------------------------------------------------------------------------------------//
+
+// Distance returns the number of steps between two tiles on the hexagonal grid (-1 if a tile is nil).
+// Neighboring tiles have a distance of 1 (see Neighbors and ExtNeighbors).
+func (w *World) Distance(a, b *Tile) int {
+	if a == nil || b == nil {
+		return -1
+	}
+
+	// convert the shifted rows to axial coordinates (every 2nd line is shifted to the right)
+	q1, r1 := a.XCol-(a.YRow-a.YRow%2)/2, a.YRow
+	q2, r2 := b.XCol-(b.YRow-b.YRow%2)/2, b.YRow
+
+	dq, dr := q1-q2, r1-r2
+	return (abs(dq) + abs(dr) + abs(dq+dr)) / 2
+}
+
+// PathCost returns the estimated number of iterations a unit needs to move along the path.
+// Every step takes the speed of the unit on the tile the step starts from (see stats).
+func PathCost(unitType byte, path []*Tile) uint64 {
+	var cost uint64
+	for i := 0; i+1 < len(path); i++ {
+		_, _, _, _, _, speed, _, _ := stats(unitType, path[i].Type)
+		cost += speed
+	}
+	return cost
+}
+
+// CanFire checks whether the unit on the 'from' tile could fire on the 'to' tile.
+// It returns the same errors as Fire without setting a command.
+func (w *World) CanFire(from, to *Tile, playerFilter uint8) error {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	_, err := w.checkFire(from, to, playerFilter)
+	return err
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// checkFire checks the input, the activity, the range and the ammunition of a fire command
+// and returns the unit on the 'from' tile. The caller must hold the lock.
+func (w *World) checkFire(from, to *Tile, playerFilter uint8) (*Unit, error) {
+
+	// check input
+	if from == nil || to == nil {
+		return nil, ErrInvalidInput
+	}
+	unit := from.Unit
+	if unit == nil || (playerFilter != 0 && unit.Player != playerFilter) {
+		return nil, ErrNoUnit
+	}
+
+	// check activity
+	if unit.Activity != nil {
+		return nil, ErrBusy
+	}
+
+	// check neighbors (the same tiles as the RANGE query)
+	ok := false
+	neighbors := w.ExtNeighbors(from, unit.FireRange)
+	for _, tmp := range neighbors {
+		for _, t := range tmp {
+			if t == to {
+				ok = true // 'to' is a neighbor
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, ErrNotInRange
+	}
+
+	// ammunition
+	if unit.Ammunition < 1 {
+		return nil, ErrNoAmmo
+	}
+	return unit, nil
+}
+
+// abs returns the absolute value of an integer.
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
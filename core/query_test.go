@@ -0,0 +1,70 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDistance(t *testing.T) {
+	world := NewWorld(9, 8)
+	assert.Equal(t, -1, world.Distance(nil, world.Tile(0, 0)))
+	assert.Equal(t, 0, world.Distance(world.Tile(3, 3), world.Tile(3, 3)))
+
+	// distance must match the rings of ExtNeighbors
+	for _, center := range []*Tile{world.Tile(0, 0), world.Tile(4, 3), world.Tile(5, 4), world.Tile(8, 7)} {
+		for r, ring := range world.ExtNeighbors(center, 20) {
+			for _, tile := range ring {
+				assert.Equal(t, r+1, world.Distance(center, tile), "%d,%d -> %d,%d", center.XCol, center.YRow, tile.XCol, tile.YRow)
+				assert.Equal(t, r+1, world.Distance(tile, center))
+			}
+		}
+	}
+}
+
+func TestPathCost(t *testing.T) {
+	world := NewWorld(5, 1)
+	for x := 0; x < 5; x++ {
+		world.Tile(x, 0).Type = GRASS
+	}
+	world.Tile(1, 0).Type = FOREST
+	path := FindPath(world, TANK, world.Tile(0, 0), world.Tile(3, 0))
+	assert.Len(t, path, 4)
+
+	_, _, _, _, _, grass, _, _ := stats(TANK, GRASS)
+	_, _, _, _, _, forest, _, _ := stats(TANK, FOREST)
+	assert.Equal(t, 2*grass+forest, PathCost(TANK, path))
+	assert.Equal(t, uint64(0), PathCost(TANK, path[:1]))
+}
+
+func TestCanFire(t *testing.T) {
+	world := NewWorld(6, 6)
+	from := world.Tile(1, 1)
+	from.Unit = NewUnit(1, TANK)
+	from.Unit.FireRange = 2
+	from.Unit.Ammunition = 1
+
+	assert.NoError(t, world.CanFire(from, world.Tile(3, 1), 1))
+	assert.ErrorIs(t, world.CanFire(from, world.Tile(4, 1), 1), ErrNotInRange)
+	assert.ErrorIs(t, world.CanFire(from, from, 1), ErrNotInRange)
+	assert.ErrorIs(t, world.CanFire(from, world.Tile(2, 1), 2), ErrNoUnit)
+	assert.Equal(t, float32(1), from.Unit.Ammunition) // no side effects
+	assert.Nil(t, from.Unit.Activity)
+
+	// the fire range are the rings of ExtNeighbors (see Fire)
+	inRange := make(map[*Tile]bool)
+	for _, ring := range world.ExtNeighbors(from, from.Unit.FireRange) {
+		for _, tile := range ring {
+			inRange[tile] = true
+		}
+	}
+	for y := 0; y < world.YHeight; y++ {
+		for x := 0; x < world.XWidth; x++ {
+			tile := world.Tile(x, y)
+			assert.Equal(t, inRange[tile], world.CanFire(from, tile, 1) == nil, "%d,%d", x, y)
+		}
+	}
+
+	from.Unit.Ammunition = 0
+	assert.ErrorIs(t, world.CanFire(from, world.Tile(2, 1), 1), ErrNoAmmo)
+}
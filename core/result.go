@@ -0,0 +1,109 @@
+package core
+
+/*
+  This file defines the end of a game. A finished game stores its Result in the world,
+  after which the Update() function has no effect anymore. Players who lost their seat
+  by a timeout are recorded as Forfeit and reported in the Result.
+*/
+
+//--------  Struct  --------------------------------------------------------------------------------------------------//
+
+// Result describes the outcome of a finished game.
+type Result struct {
+	Winner    uint8     // Player who won the game (0 = no winner).
+	Reason    string    // Reason why the game has ended.
+	Iteration uint64    // Iteration at which the game has ended.
+	Forfeits  []Forfeit `json:",omitempty"` // Players who lost their seat during the game (see World.Forfeit).
+}
+
+// Forfeit describes a player who lost his seat, e.g. by a join or inactivity timeout.
+type Forfeit struct {
+	Player    uint8  // Player who lost the seat.
+	Reason    string // Reason, e.g. "join timeout" or "inactive".
+	Replaced  bool   // Indicates if a bot took over the seat (the player stays in the game).
+	Iteration uint64 // Iteration of the forfeit.
+}
+
+//--------  Getter  --------------------------------------------------------------------------------------------------//
+
+// GameOver checks whether the game is decided.
+// A player is still in the game as long as he has units or owns a base.
+// The game is over if fewer than two players are left. The remaining player
+// is returned as winner (0 if no player is left).
+func (w *World) GameOver() (winner uint8, over bool) {
+	alive := make(map[uint8]bool)
+
+	// players with units
+	for _, tile := range w.Units(0) {
+		if tile != nil && tile.Unit != nil {
+			alive[tile.Unit.Player] = true
+		}
+	}
+
+	// players with bases
+	for _, tile := range w.TileList(BASE) {
+		if tile != nil && tile.Owner != 0 {
+			alive[tile.Owner] = true
+		}
+	}
+
+	// check players
+	if len(alive) >= 2 {
+		return 0, false
+	}
+	for player := range alive {
+		winner = player
+	}
+	return winner, true
+}
+
+//--------  Setter  --------------------------------------------------------------------------------------------------//
+
+// End finishes the game with the given winner and reason.
+// An already finished game is not changed.
+func (w *World) End(winner uint8, reason string) {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	if w.Result != nil {
+		return // game has already ended
+	}
+
+	w.Result = &Result{
+		Winner:    winner,
+		Reason:    reason,
+		Iteration: w.Iteration,
+		Forfeits:  append([]Forfeit(nil), w.Forfeits...),
+	}
+}
+
+// Forfeit records that the player lost his seat. If the seat was not taken over (see Forfeit.Replaced),
+// all units of the player are removed and his bases become neutral, so he is out of the game (see GameOver).
+// A finished game is not changed.
+func (w *World) Forfeit(player uint8, reason string, replaced bool) {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	if w.Result != nil {
+		return // game has already ended
+	}
+
+	// remove player
+	if !replaced {
+		for _, tile := range w.TileList(0) {
+			if tile.Unit != nil && tile.Unit.Player == player {
+				tile.Unit = nil
+			}
+			if tile.Type == BASE && tile.Owner == player {
+				tile.Owner = 0
+			}
+		}
+	}
+
+	w.Forfeits = append(w.Forfeits, Forfeit{
+		Player:    player,
+		Reason:    reason,
+		Replaced:  replaced,
+		Iteration: w.Iteration,
+	})
+}
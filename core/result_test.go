@@ -0,0 +1,85 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGameOver(t *testing.T) {
+	world := NewWorld(10, 10)
+	world.Tile(1, 1).Unit = NewUnit(RED, TANK)
+	world.Tile(8, 8).Unit = NewUnit(BLUE, TANK)
+
+	winner, over := world.GameOver()
+	assert.False(t, over)
+	assert.Equal(t, uint8(0), winner)
+
+	// blue has no units, but still a base
+	world.Tile(8, 8).Unit = nil
+	world.Tile(9, 9).Type = BASE
+	world.Tile(9, 9).Owner = BLUE
+	_, over = world.GameOver()
+	assert.False(t, over)
+
+	// blue is defeated
+	world.Tile(9, 9).Owner = 0
+	winner, over = world.GameOver()
+	assert.True(t, over)
+	assert.Equal(t, uint8(RED), winner)
+}
+
+func TestEnd(t *testing.T) {
+	world := NewWorld(5, 5)
+	world.Tile(1, 1).Unit = NewUnit(RED, TANK)
+	world.Iteration = 42
+
+	world.End(RED, "test")
+	assert.NotNil(t, world.Result)
+	assert.Equal(t, uint8(RED), world.Result.Winner)
+	assert.Equal(t, "test", world.Result.Reason)
+	assert.Equal(t, uint64(42), world.Result.Iteration)
+
+	// the first result wins
+	world.End(BLUE, "other")
+	assert.Equal(t, uint8(RED), world.Result.Winner)
+
+	// a finished world is not updated anymore
+	world.Update()
+	assert.Equal(t, uint64(42), world.Iteration)
+}
+
+func TestForfeit(t *testing.T) {
+	world := NewWorld(5, 5)
+	world.Tile(1, 1).Unit = NewUnit(RED, TANK)
+	world.Tile(3, 3).Unit = NewUnit(BLUE, TANK)
+	world.Tile(4, 4).Type = BASE
+	world.Tile(4, 4).Owner = BLUE
+	world.Iteration = 7
+
+	// replaced by a bot
+	world.Forfeit(BLUE, "inactive", true)
+	_, over := world.GameOver()
+	assert.False(t, over)
+
+	// blue leaves the game
+	world.Forfeit(BLUE, "join timeout", false)
+	assert.Nil(t, world.Tile(3, 3).Unit)
+	assert.Equal(t, uint8(0), world.Tile(4, 4).Owner)
+	assert.NotNil(t, world.Tile(1, 1).Unit)
+	winner, over := world.GameOver()
+	assert.True(t, over)
+	assert.Equal(t, uint8(RED), winner)
+
+	// reported in the result
+	world.End(RED, "player 1 has won")
+	assert.Equal(t, []Forfeit{
+		{Player: BLUE, Reason: "inactive", Replaced: true, Iteration: 7},
+		{Player: BLUE, Reason: "join timeout", Iteration: 7},
+	}, world.Result.Forfeits)
+
+	// a finished game is not changed
+	world.Forfeit(RED, "inactive", false)
+	assert.NotNil(t, world.Tile(1, 1).Unit)
+	assert.Len(t, world.Forfeits, 2)
+}
@@ -0,0 +1,32 @@
+package core
+
+/*
+  This file defines optional rule overrides of a map. A map can change some game settings (see const.go),
+  e.g. a larger supply range for big maps. Zero values and a nil Rules keep the default settings.
+*/
+
+//--------  Struct  --------------------------------------------------------------------------------------------------//
+
+// Rules are optional overrides of the game settings (see World.Rules).
+type Rules struct {
+	MaxSupply   int     `json:",omitempty"` // Maximum supply distance (0 = MaxSupply)
+	SupplySpeed float32 `json:",omitempty"` // Factor of the ammunition regeneration (0 = SupplySpeed)
+}
+
+//--------  Getter  --------------------------------------------------------------------------------------------------//
+
+// SupplyRange returns the maximum supply distance of the world (see Rules.MaxSupply).
+func (w *World) SupplyRange() int {
+	if w.Rules != nil && w.Rules.MaxSupply > 0 {
+		return w.Rules.MaxSupply
+	}
+	return MaxSupply
+}
+
+// SupplyFactor returns the factor of the ammunition regeneration of the world (see Rules.SupplySpeed).
+func (w *World) SupplyFactor() float32 {
+	if w.Rules != nil && w.Rules.SupplySpeed > 0 {
+		return w.Rules.SupplySpeed
+	}
+	return SupplySpeed
+}
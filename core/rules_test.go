@@ -0,0 +1,33 @@
+package core
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRules(t *testing.T) {
+	world := NewWorld(30, 1)
+	base := world.Tile(0, 0)
+	base.Type = BASE
+	base.Owner = RED
+	assert.Equal(t, MaxSupply, world.SupplyRange())
+	assert.Equal(t, float32(SupplySpeed), world.SupplyFactor())
+
+	// default supply range
+	updateSupply(world)
+	assert.Equal(t, MaxSupply, world.Tile(MaxSupply, 0).Supply[RED])
+	assert.Zero(t, world.Tile(MaxSupply+1, 0).Supply[RED])
+
+	// override
+	world.Rules = &Rules{MaxSupply: 20, SupplySpeed: 2}
+	assert.Equal(t, 20, world.SupplyRange())
+	assert.Equal(t, float32(2), world.SupplyFactor())
+	updateSupply(world)
+	assert.Equal(t, 20, world.Tile(20, 0).Supply[RED])
+	assert.Zero(t, world.Tile(21, 0).Supply[RED])
+
+	// rules are part of the JSON (e.g. STATUS)
+	clone := world.Clone()
+	assert.Equal(t, world.Rules, clone.Rules)
+}
@@ -0,0 +1,159 @@
+package core
+
+/*
+  This file provides scheduled commands. A MOVE or FIRE command can be queued for a later iteration,
+  e.g. to coordinate several units. The command gets its command ID when it is queued, but it is
+  validated when it starts: the Update() function executes it at the beginning of the iteration,
+  like a command sent right before. An invalid command is reported as OutcomeRejected (see TakeOutcomes).
+  The optional filter of a command checks the unit when the command starts (see UnitFilter).
+*/
+
+// MaxScheduled is the max. number of scheduled commands per player.
+const MaxScheduled = 100
+
+//--------  Struct  --------------------------------------------------------------------------------------------------//
+
+// Scheduled is a command that starts at a later iteration (see Schedule).
+type Scheduled struct {
+	ID        uint64 // Command ID (see Activity.ID)
+	Player    uint8  // Player of the command (0 = any unit)
+	Name      string // Name of the command (MOVE, FIRE)
+	Unit      int    `json:",omitempty"` // ID of the unit (0 = the unit on the From tile)
+	From      [2]int // Starting coordinates (ignored if Unit is set)
+	To        [2]int // Destination coordinates
+	Iteration uint64 // Iteration at which the command starts
+
+	Filter UnitFilter `json:"-"` // Checks the unit when the command starts (optional)
+}
+
+// UnitFilter checks the unit of a command when it starts (with the world lock held) and returns an error
+// to reject the command, e.g. if a connection only controls some units of the player.
+type UnitFilter func(unit *Unit) error
+
+//--------  Setter  --------------------------------------------------------------------------------------------------//
+
+// Schedule queues a MOVE or FIRE command for the iteration and returns it with its command ID.
+// Only the tiles and the iteration are checked now. The unit and the command are checked when it starts.
+// A command for the current iteration starts with the next Update, like a command sent right now.
+func (w *World) Schedule(cmd Scheduled) (*Scheduled, error) {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	// check input
+	if cmd.Name != MOVE && cmd.Name != FIRE {
+		return nil, ErrInvalidInput
+	}
+	if w.Tile(cmd.To[0], cmd.To[1]) == nil || (cmd.Unit == 0 && w.Tile(cmd.From[0], cmd.From[1]) == nil) {
+		return nil, ErrInvalidInput
+	}
+	if cmd.Iteration < w.Iteration {
+		return nil, ErrPastIteration
+	}
+	count := 0
+	for _, s := range w.scheduled {
+		if s.Player == cmd.Player {
+			count++
+		}
+	}
+	if count >= MaxScheduled {
+		return nil, ErrQueueFull
+	}
+
+	// queue command
+	cmd.ID = w.nextCommandID(cmd.Player)
+	w.scheduled = append(w.scheduled, &cmd)
+	copied := cmd
+	copied.Filter = nil
+	return &copied, nil
+}
+
+// Execute starts a MOVE or FIRE command now, like Move, Fire, MoveUnit and FireUnit (the iteration is ignored).
+// Unlike these functions, it checks the unit with the filter of the command.
+func (w *World) Execute(cmd Scheduled) error {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	if cmd.Name != MOVE && cmd.Name != FIRE {
+		return ErrInvalidInput
+	}
+	_, err := w.start(&cmd)
+	return err
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// runScheduled starts all scheduled commands of the current iteration in the order they were queued.
+func runScheduled(world *World) {
+	if world == nil || len(world.scheduled) == 0 {
+		return
+	}
+
+	keep := world.scheduled[:0]
+	for _, cmd := range world.scheduled {
+		if cmd.Iteration > world.Iteration {
+			keep = append(keep, cmd) // later
+			continue
+		}
+
+		// start command
+		if from, err := world.start(cmd); err != nil {
+			world.rejectScheduled(cmd, from, err)
+		}
+	}
+	world.scheduled = keep
+}
+
+// start finds the unit of a command, checks it with the filter and starts the command (the lock must be held).
+// It returns the tile of the unit (nil if not found).
+func (w *World) start(cmd *Scheduled) (*Tile, error) {
+
+	// find the unit
+	from := w.Tile(cmd.From[0], cmd.From[1])
+	if cmd.Unit != 0 {
+		from = w.UnitTile(cmd.Unit)
+		if from == nil {
+			return nil, ErrNoUnit
+		}
+	}
+	if from == nil {
+		return nil, ErrInvalidInput
+	}
+	to := w.Tile(cmd.To[0], cmd.To[1])
+
+	// check the unit (units of other players are rejected by move and fire)
+	if u := from.Unit; u != nil && cmd.Filter != nil && (cmd.Player == 0 || u.Player == cmd.Player) {
+		if err := cmd.Filter(u); err != nil {
+			return from, err
+		}
+	}
+
+	// start command
+	var err error
+	if cmd.Name == MOVE {
+		_, err = w.move(from, to, cmd.Player, cmd.ID)
+	} else {
+		err = w.fire(from, to, cmd.Player, cmd.ID)
+	}
+	return from, err
+}
+
+// rejectScheduled reports a scheduled command that could not be started.
+func (w *World) rejectScheduled(cmd *Scheduled, from *Tile, err error) {
+	o := &Outcome{
+		Command:   cmd.ID,
+		Name:      cmd.Name,
+		Unit:      cmd.Unit,
+		Result:    OutcomeRejected,
+		Error:     err.Error(),
+		From:      cmd.From,
+		To:        cmd.To,
+		Iteration: w.Iteration,
+	}
+	player := cmd.Player
+	if from != nil && from.Unit != nil && (player == 0 || from.Unit.Player == player) {
+		o.Unit, o.Type = from.Unit.ID, from.Unit.Type // never reveal other units
+		o.From = [2]int{from.XCol, from.YRow}
+		player = from.Unit.Player
+	}
+	w.pushOutcome(player, o)
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	tank := NewUnit(RED, TANK)
+	world := outcomeWorld(map[[2]int]*Unit{{0, 0}: tank})
+	world.Iteration = 10
+
+	// invalid commands
+	_, err := world.Schedule(Scheduled{Player: RED, Name: "JUMP", To: [2]int{1, 0}, Iteration: 20})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = world.Schedule(Scheduled{Player: RED, Name: MOVE, To: [2]int{9, 9}, Iteration: 20})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = world.Schedule(Scheduled{Player: RED, Name: MOVE, To: [2]int{1, 0}, Iteration: 9})
+	assert.ErrorIs(t, err, ErrPastIteration)
+
+	// move by ID at iteration 12
+	cmd, err := world.Schedule(Scheduled{Player: RED, Name: MOVE, Unit: tank.ID, To: [2]int{1, 0}, Iteration: 12})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), cmd.ID)
+	processScheduled(world, 2)
+	assert.Nil(t, tank.Activity)
+	processScheduled(world, 1)
+	require.NotNil(t, tank.Activity)
+	assert.Equal(t, uint64(1), tank.Activity.ID)
+	assert.Equal(t, uint64(12), tank.Activity.Start)
+	assert.Empty(t, world.TakeOutcomes(RED))
+}
+
+func TestScheduleRejected(t *testing.T) {
+	tank := NewUnit(RED, TANK)
+	enemy := NewUnit(BLUE, TANK)
+	world := outcomeWorld(map[[2]int]*Unit{{0, 0}: tank, {3, 0}: enemy})
+
+	// busy at the start
+	_, err := world.Move(world.Tile(0, 0), world.Tile(1, 0), RED)
+	require.NoError(t, err)
+	cmd, err := world.Schedule(Scheduled{Player: RED, Name: FIRE, From: [2]int{0, 0}, To: [2]int{0, 1}, Iteration: 1})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), cmd.ID)
+
+	// other player's unit
+	_, err = world.Schedule(Scheduled{Player: RED, Name: MOVE, Unit: enemy.ID, To: [2]int{4, 0}, Iteration: 1})
+	require.NoError(t, err)
+
+	processScheduled(world, 2)
+	list := world.TakeOutcomes(RED)
+	require.Len(t, list, 2)
+	assert.Equal(t, Outcome{Command: 2, Name: FIRE, Unit: tank.ID, Type: TANK, Result: OutcomeRejected, Error: ErrBusy.Error(),
+		From: [2]int{0, 0}, To: [2]int{0, 1}, Iteration: 1}, *list[0])
+	assert.Equal(t, Outcome{Command: 3, Name: MOVE, Unit: enemy.ID, Result: OutcomeRejected, Error: ErrNoUnit.Error(),
+		To: [2]int{4, 0}, Iteration: 1}, *list[1]) // no position or type of the enemy
+	assert.Nil(t, enemy.Activity)
+}
+
+func TestScheduleLimit(t *testing.T) {
+	world := outcomeWorld(nil)
+	for i := 0; i < MaxScheduled; i++ {
+		_, err := world.Schedule(Scheduled{Player: RED, Name: MOVE, To: [2]int{1, 0}, Iteration: 5})
+		require.NoError(t, err)
+	}
+	_, err := world.Schedule(Scheduled{Player: RED, Name: MOVE, To: [2]int{1, 0}, Iteration: 5})
+	assert.ErrorIs(t, err, ErrQueueFull)
+	_, err = world.Schedule(Scheduled{Player: BLUE, Name: MOVE, To: [2]int{1, 0}, Iteration: 5})
+	assert.NoError(t, err)
+}
+
+func TestExecuteFilter(t *testing.T) {
+	tank := NewUnit(RED, TANK)
+	soldier := NewUnit(RED, SOLDIER)
+	world := outcomeWorld(map[[2]int]*Unit{{0, 0}: tank, {3, 0}: soldier})
+	errTank := errors.New("no tanks")
+	noTanks := func(u *Unit) error {
+		if u.Type == TANK {
+			return errTank
+		}
+		return nil
+	}
+
+	// immediate commands
+	assert.ErrorIs(t, world.Execute(Scheduled{Player: RED, Name: MOVE, To: [2]int{1, 0}, Filter: noTanks}), errTank)
+	assert.ErrorIs(t, world.Execute(Scheduled{Player: RED, Name: MOVE, Unit: tank.ID, To: [2]int{1, 0}, Filter: noTanks}), errTank)
+	assert.ErrorIs(t, world.Execute(Scheduled{Player: RED, Name: "JUMP", To: [2]int{1, 0}}), ErrInvalidInput)
+	assert.ErrorIs(t, world.Execute(Scheduled{Player: RED, Name: MOVE, From: [2]int{9, 9}, To: [2]int{1, 0}}), ErrInvalidInput)
+	assert.ErrorIs(t, world.Execute(Scheduled{Player: RED, Name: MOVE, Unit: 999, To: [2]int{1, 0}}), ErrNoUnit)
+	assert.ErrorIs(t, world.Execute(Scheduled{Player: BLUE, Name: MOVE, To: [2]int{1, 0}, Filter: noTanks}), ErrNoUnit)
+	assert.Nil(t, tank.Activity)
+	assert.NoError(t, world.Execute(Scheduled{Player: RED, Name: MOVE, From: [2]int{3, 0}, To: [2]int{4, 0}, Filter: noTanks}))
+	assert.NotNil(t, soldier.Activity)
+
+	// scheduled commands are checked when they start
+	cmd, err := world.Schedule(Scheduled{Player: RED, Name: MOVE, Unit: tank.ID, To: [2]int{1, 0}, Filter: noTanks})
+	require.NoError(t, err)
+	assert.Nil(t, cmd.Filter)
+	processScheduled(world, 1)
+	list := world.TakeOutcomes(RED)
+	require.Len(t, list, 1)
+	assert.Equal(t, OutcomeRejected, list[0].Result)
+	assert.Equal(t, errTank.Error(), list[0].Error)
+	assert.Nil(t, tank.Activity)
+}
+
+// processScheduled starts the scheduled commands and processes the commands for n iterations.
+func processScheduled(world *World, n int) {
+	for i := 0; i < n; i++ {
+		runScheduled(world)
+		processCommands(world, 1)
+	}
+}
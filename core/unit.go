@@ -32,6 +32,7 @@ type Unit struct {
 
 // Activity represents a unit's ongoing activity or command.
 type Activity struct {
+	ID    uint64 `json:",omitempty"` // Command ID, unique per player (see Outcome).
 	Name  string // Name of the activity (MOVE, FIRE)
 	From  [2]int // Starting coordinates of the activity.
 	To    [2]int // Destination coordinates of the activity.
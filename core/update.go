@@ -24,6 +24,7 @@ import (
 // and calculations.
 //
 // It performs the following steps in sequence:
+// - Starts the scheduled commands of the current iteration.
 // - Sets the owner of bases on the map based on unit presence and proximity.
 // - Updates the supply levels on the map, considering changes in base ownership.
 // - Processes movement and firing commands of units.
@@ -32,15 +33,19 @@ import (
 // - Heals units stationed at bases over time and fixes demoralization status.
 // - Updates visibility ranges for units on the map.
 // - Advances the iteration count to mark the completion of the current iteration.
+// - Removes expired debug annotations.
 func (w *World) Update() {
 	w.lock.Lock()         // Acquire the lock to ensure thread safety
 	defer w.lock.Unlock() // Release the lock when the function exits
 
 	// enforce freeze
-	if w.Freeze {
+	if w.Freeze || w.Result != nil {
 		return // so nothing
 	}
 
+	// Start the scheduled commands of this iteration
+	runScheduled(w)
+
 	// Set the owner of bases
 	updateBaseOwner(w)
 
@@ -63,6 +68,9 @@ func (w *World) Update() {
 
 	// Advance the iteration count
 	w.Iteration++
+
+	// Remove expired debug annotations
+	expireDebug(w)
 }
 
 //--------  Helper  --------------------------------------------------------------------------------------------------//
@@ -92,7 +100,7 @@ func updateBaseOwner(world *World) {
 // updateSupply calculates and updates the supply network for military bases in a game world.
 // The supply network is created by determining which fields can be supplied from each
 // own military base within a specified maximum distance. The lower the supply value, the
-// closer the field is to a supply depot and the better the supply (1 to MaxSupply).
+// closer the field is to a supply depot and the better the supply (1 to MaxSupply, see Rules).
 //
 // The supply network is stored in the form of values within a grid, where each value
 // indicates how far the supply network extends from the base. Each Tile's supply data is
@@ -128,7 +136,7 @@ func updateSupply(world *World) {
 		base.Supply[base.Owner] = 1
 
 		// Process all neighbors (map wide) within specified supply range
-		for lvl, tmp := range world.ExtNeighbors(base, MaxSupply) {
+		for lvl, tmp := range world.ExtNeighbors(base, world.SupplyRange()) {
 			lvl += 1
 			for _, tile := range tmp {
 
@@ -226,8 +234,8 @@ func updateUnitAttributes(world *World) {
 		unit.Hidden = hidden
 
 		// refill ammunition based on supply availability, considering maximum supply range
-		if supply > 0 && supply <= MaxSupply {
-			unit.Ammunition += (1 / (float32(supply) * 2 * 30)) * SupplySpeed
+		if supply > 0 && supply <= world.SupplyRange() {
+			unit.Ammunition += (1 / (float32(supply) * 2 * 30)) * world.SupplyFactor()
 		}
 
 		// Limit ammunition to the maximum allowed amount
@@ -286,6 +294,7 @@ func processMove(world *World) {
 
 		// remove old activity if it has ended
 		if unit.Activity.End < world.Iteration {
+			world.addOutcome(unit, OutcomeCompleted, 0, false)
 			unit.Activity = nil // disable
 			continue            // my job is done -> skip
 		}
@@ -309,9 +318,10 @@ func processMove(world *World) {
 
 			// Check if the destination is already occupied
 			if to.Unit != nil {
-				resources.PlaySound(resources.Sounds.Error) // play error sound
-				unit.Activity = nil                         // ABORT moving!
-				continue                                    // my job is done -> skip
+				world.addOutcome(unit, OutcomeOccupied, 0, false) // report the abort
+				resources.PlaySound(resources.Sounds.Error)       // play error sound
+				unit.Activity = nil                               // ABORT moving!
+				continue                                          // my job is done -> skip
 			}
 
 			// MOVE UNIT
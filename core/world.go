@@ -12,7 +12,6 @@ package core
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"sync"
 )
@@ -27,9 +26,19 @@ type World struct {
 	XWidth        int             // The width of the world in tiles.
 	YHeight       int             // The height of the world in tiles.
 	Reinforcement map[uint64]byte // reinforcement for all players with a base. key is iteration, value is unit type.
+	Rules         *Rules          `json:",omitempty"` // optional rule overrides of the map (see Rules)
 
-	Iteration uint64 // Current iteration (game time) of the world.
-	Freeze    bool   // if true, the update function has no effect and the world remains frozen
+	Iteration uint64  // Current iteration (game time) of the world.
+	Freeze    bool    // if true, the update function has no effect and the world remains frozen
+	Result    *Result // result of a finished game (nil while the game is running)
+
+	Forfeits []Forfeit `json:",omitempty"` // players who lost their seat (see Forfeit)
+
+	Debug map[uint8][]*DebugShape `json:",omitempty"` // debug annotations of the players (see AddDebug)
+
+	commandIDs map[uint8]uint64     // last command ID of each player (see Activity.ID)
+	outcomes   map[uint8][]*Outcome // outcome queue of each player (see TakeOutcomes)
+	scheduled  []*Scheduled         // commands of later iterations (see Schedule)
 }
 
 // NewWorld creates a new game world with the specified dimensions and initializes its tiles.
@@ -263,6 +272,42 @@ func (w *World) Json() string {
 	}
 }
 
+// UnitAt returns a copy of the unit on the given tile (nil if there is no unit).
+// Unlike reading the tile directly, this is safe while another goroutine calls Update.
+func (w *World) UnitAt(tile *Tile) *Unit {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	if tile == nil {
+		return nil
+	}
+	return copyUnit(tile.Unit)
+}
+
+// UnitTile returns the tile of the unit with the given ID (nil if there is no such unit).
+// A moving unit stays on its start tile until it switches to the target tile halfway (see Update).
+func (w *World) UnitTile(id int) *Tile {
+	for _, t := range w.Units(0) {
+		if t.Unit.ID == id {
+			return t
+		}
+	}
+	return nil
+}
+
+// UnitByID returns a copy of the unit with the given ID (nil if there is no such unit).
+// Unlike UnitTile, this is safe while another goroutine calls Update.
+func (w *World) UnitByID(id int) *Unit {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	tile := w.UnitTile(id)
+	if tile == nil {
+		return nil
+	}
+	return copyUnit(tile.Unit)
+}
+
 // PlayerCount returns the number of players in this world.
 func (w *World) PlayerCount() int {
 	playerCount := make(map[uint8]bool)
@@ -276,8 +321,35 @@ func (w *World) PlayerCount() int {
 	return len(playerCount)
 }
 
+// CurrentIteration returns the current iteration (game time) of the world.
+// Unlike reading the field directly, this is safe while another goroutine calls Update.
+func (w *World) CurrentIteration() uint64 {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	return w.Iteration
+}
+
+// Frozen reports whether the world is frozen (see Freeze).
+// Unlike reading the field directly, this is safe while another goroutine calls Update.
+func (w *World) Frozen() bool {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	return w.Freeze
+}
+
 //--------  Setter  --------------------------------------------------------------------------------------------------//
 
+// SetFreeze freezes or unfreezes the world (see Freeze).
+// Unlike setting the field directly, this is safe while another goroutine calls Update.
+func (w *World) SetFreeze(freeze bool) {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	w.Freeze = freeze
+}
+
 // Move initiates a movement command for a unit from one tile to another within the game world.
 // The function takes three parameters: the starting tile ('from'), the target tile ('to'),
 // and an optional player filter ('playerFilter') represented as a player ID (uint8).
@@ -303,18 +375,37 @@ func (w *World) Move(from, to *Tile, playerFilter uint8) (newTo *Tile, err error
 	w.lock.Lock()         // Acquire the lock to ensure thread safety
 	defer w.lock.Unlock() // Release the lock when the function exits
 
+	return w.move(from, to, playerFilter, 0)
+}
+
+// MoveUnit is like Move, but finds the unit by its ID (see UnitTile).
+// The command stays valid, even if the client doesn't know the current tile of the unit.
+func (w *World) MoveUnit(id int, to *Tile, playerFilter uint8) (newTo *Tile, err error) {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
+
+	from := w.UnitTile(id)
+	if from == nil {
+		return nil, ErrNoUnit
+	}
+	return w.move(from, to, playerFilter, 0)
+}
+
+// move implements Move (the lock must be held). The activity gets the command ID (0 = new ID).
+func (w *World) move(from, to *Tile, playerFilter uint8, id uint64) (newTo *Tile, err error) {
+
 	// check input
 	if from == nil || to == nil {
-		return nil, errors.New("input is nil")
+		return nil, ErrInvalidInput
 	}
 	unit := from.Unit
 	if unit == nil || (playerFilter != 0 && unit.Player != playerFilter) {
-		return nil, errors.New("no player unit found")
+		return nil, ErrNoUnit
 	}
 
 	// check activity
 	if unit.Activity != nil {
-		return nil, errors.New("unit is already processing a command")
+		return nil, ErrBusy
 	}
 
 	// check neighbors
@@ -338,7 +429,7 @@ func (w *World) Move(from, to *Tile, playerFilter uint8) (newTo *Tile, err error
 		if way != nil && len(way) > 1 {
 			to = way[1]
 		} else {
-			return nil, errors.New("target is not a neighbor and no path was found")
+			return nil, ErrNoPath
 		}
 	}
 
@@ -347,12 +438,16 @@ func (w *World) Move(from, to *Tile, playerFilter uint8) (newTo *Tile, err error
 	// (see pathfinder.canPass())
 	if unit.Type != SOLDIER { // TANK and ARTILLERY
 		if to.Type == MOUNTAIN || to.Type == STRUCTURE || to.Type == WATER {
-			return nil, errors.New("invalid target for this unit")
+			return nil, ErrInvalidTarget
 		}
 	}
 
 	// set command
+	if id == 0 {
+		id = w.nextCommandID(unit.Player)
+	}
 	unit.Activity = &Activity{
+		ID:    id,
 		Name:  MOVE,
 		From:  [2]int{from.XCol, from.YRow},
 		To:    [2]int{to.XCol, to.YRow},
@@ -377,43 +472,37 @@ func (w *World) Fire(from, to *Tile, playerFilter uint8) error {
 	w.lock.Lock()         // Acquire the lock to ensure thread safety
 	defer w.lock.Unlock() // Release the lock when the function exits
 
-	// check input
-	if from == nil || to == nil {
-		return errors.New("input is nil")
-	}
-	unit := from.Unit
-	if unit == nil || (playerFilter != 0 && unit.Player != playerFilter) {
-		return errors.New("no player unit found")
-	}
+	return w.fire(from, to, playerFilter, 0)
+}
 
-	// check activity
-	if unit.Activity != nil {
-		return errors.New("unit is already processing a command")
-	}
+// FireUnit is like Fire, but finds the unit by its ID (see UnitTile).
+func (w *World) FireUnit(id int, to *Tile, playerFilter uint8) error {
+	w.lock.Lock()         // Acquire the lock to ensure thread safety
+	defer w.lock.Unlock() // Release the lock when the function exits
 
-	// check neighbors
-	ok := false
-	neighbors := w.ExtNeighbors(from, unit.FireRange)
-	for _, tmp := range neighbors {
-		for _, t := range tmp {
-			if t == to {
-				ok = true // 'to' is a neighbor
-				break
-			}
-		}
-	}
-	if !ok {
-		return errors.New("target is not in range")
+	from := w.UnitTile(id)
+	if from == nil {
+		return ErrNoUnit
 	}
+	return w.fire(from, to, playerFilter, 0)
+}
 
-	// ammunition
-	if unit.Ammunition < 1 {
-		return errors.New("no ammunition")
+// fire implements Fire (the lock must be held). The activity gets the command ID (0 = new ID).
+func (w *World) fire(from, to *Tile, playerFilter uint8, id uint64) error {
+
+	// check input, activity, range and ammunition (see CanFire)
+	unit, err := w.checkFire(from, to, playerFilter)
+	if err != nil {
+		return err
 	}
 	unit.Ammunition -= 1 // fire one ammunition
 
 	// set command
+	if id == 0 {
+		id = w.nextCommandID(unit.Player)
+	}
 	unit.Activity = &Activity{
+		ID:    id,
 		Name:  FIRE,
 		From:  [2]int{from.XCol, from.YRow},
 		To:    [2]int{to.XCol, to.YRow},
@@ -425,6 +514,19 @@ func (w *World) Fire(from, to *Tile, playerFilter uint8) error {
 
 //--------  Helper  --------------------------------------------------------------------------------------------------//
 
+// copyUnit returns a copy of the unit and its activity (nil if there is no unit).
+func copyUnit(u *Unit) *Unit {
+	if u == nil {
+		return nil
+	}
+	unit := *u
+	if unit.Activity != nil {
+		activity := *unit.Activity
+		unit.Activity = &activity
+	}
+	return &unit
+}
+
 // Censorship applies censorship or information restriction to a given game world.
 // The function takes a world and a player ID (represented as an uint8) as parameters and
 // returns a modified copy of the game world.
@@ -441,8 +543,11 @@ func (w *World) Fire(from, to *Tile, playerFilter uint8) error {
 //   - For tiles that are in Fog of War visibility mode for the specified player and have no visibility,
 //     the owner is reset (if it doesn't belong to the specified player), and any hidden units are removed.
 //   - For tiles in normal view mode that have hidden units, these hidden units are removed.
+//   - The command IDs of units of other players are removed (see Activity.ID).
+//
+// 4. Debug annotations of other players are removed (see AddDebug).
 //
-// 4. The edited copied world returned.
+// 5. The edited copied world returned.
 //
 // Overall, the function enforces a form of visibility restriction and information withholding
 // for the specified player in the game world.
@@ -480,6 +585,18 @@ func Censorship(world *World, player uint8) *World {
 			// hide hidden unit
 			t.Unit = nil
 		}
+
+		// Remove the command IDs of other players.
+		if t.Unit != nil && t.Unit.Player != player && t.Unit.Activity != nil {
+			t.Unit.Activity.ID = 0
+		}
+	}
+
+	// Remove the debug annotations of other players.
+	if list := world.Debug[player]; len(list) > 0 {
+		world.Debug = map[uint8][]*DebugShape{player: list}
+	} else {
+		world.Debug = nil
 	}
 
 	// Return the edited game world.
@@ -88,7 +88,30 @@ func TestMove(t *testing.T) {
 	assert.NotNil(t, unit.Activity)
 
 	_, err = world.Move(from, to, 0)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrBusy)
+
+	_, err = world.Move(to, from, 0)
+	assert.ErrorIs(t, err, ErrNoUnit)
+
+	_, err = world.Move(from, nil, 0)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+func TestSetFreeze(t *testing.T) {
+	world := NewWorld(3, 3)
+
+	world.SetFreeze(true)
+	world.Update()
+	assert.True(t, world.Freeze)
+	assert.True(t, world.Frozen())
+	assert.Equal(t, uint64(0), world.Iteration)
+
+	world.SetFreeze(false)
+	world.Update()
+	assert.False(t, world.Freeze)
+	assert.False(t, world.Frozen())
+	assert.Equal(t, uint64(1), world.Iteration)
+	assert.Equal(t, uint64(1), world.CurrentIteration())
 }
 
 func TestFire(t *testing.T) {
@@ -107,7 +130,88 @@ func TestFire(t *testing.T) {
 	assert.NotNil(t, unit.Activity)
 
 	err = world.Fire(from, to, 0)
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrBusy)
+
+	// no ammunition
+	unit.Activity = nil
+	err = world.Fire(from, to, 0)
+	assert.ErrorIs(t, err, ErrNoAmmo)
+
+	// not in range
+	unit.Ammunition = 1
+	err = world.Fire(from, world.Tile(9, 9), 0)
+	assert.ErrorIs(t, err, ErrNotInRange)
+}
+
+func TestUnitAt(t *testing.T) {
+	world := NewWorld(3, 3)
+	tile := world.Tile(1, 1)
+	assert.Nil(t, world.UnitAt(tile))
+	assert.Nil(t, world.UnitAt(nil))
+
+	tile.Unit = NewUnit(RED, TANK)
+	tile.Unit.Activity = &Activity{Name: MOVE, End: 10}
+
+	unit := world.UnitAt(tile)
+	assert.Equal(t, tile.Unit.ID, unit.ID)
+	assert.Equal(t, *tile.Unit.Activity, *unit.Activity)
+
+	// it is a copy
+	unit.Activity.End = 20
+	assert.Equal(t, uint64(10), tile.Unit.Activity.End)
+}
+
+func TestUnitByID(t *testing.T) {
+	world := NewWorld(3, 3)
+	tile := world.Tile(2, 1)
+	tile.Unit = NewUnit(BLUE, SOLDIER)
+	id := tile.Unit.ID
+
+	assert.Equal(t, tile, world.UnitTile(id))
+	assert.Nil(t, world.UnitTile(id+1))
+	unit := world.UnitByID(id)
+	assert.Equal(t, *tile.Unit, *unit)
+	assert.Nil(t, world.UnitByID(id+1))
+
+	// it is a copy
+	unit.Health = 1
+	assert.Equal(t, 100, tile.Unit.Health)
+}
+
+func TestMoveUnit(t *testing.T) {
+	world := NewWorld(10, 10)
+	from := world.Tile(5, 5)
+	unit := NewUnit(RED, SOLDIER)
+	from.Unit = unit
+
+	_, err := world.MoveUnit(unit.ID, world.Tile(5, 6), BLUE)
+	assert.ErrorIs(t, err, ErrNoUnit) // other player
+	_, err = world.MoveUnit(unit.ID+1, world.Tile(5, 6), RED)
+	assert.ErrorIs(t, err, ErrNoUnit)
+	_, err = world.MoveUnit(unit.ID, nil, RED)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	to, err := world.MoveUnit(unit.ID, world.Tile(5, 6), RED)
+	assert.NoError(t, err)
+	assert.Equal(t, world.Tile(5, 6), to)
+	assert.Equal(t, [2]int{5, 5}, unit.Activity.From)
+	_, err = world.MoveUnit(unit.ID, world.Tile(5, 6), RED)
+	assert.ErrorIs(t, err, ErrBusy)
+}
+
+func TestFireUnit(t *testing.T) {
+	world := NewWorld(10, 10)
+	from := world.Tile(5, 5)
+	from.Type = GRASS
+	unit := NewUnit(RED, TANK)
+	unit.Ammunition = 1
+	from.Unit = unit
+	updateUnitAttributes(world) // set attributes!!
+
+	assert.ErrorIs(t, world.FireUnit(unit.ID+1, world.Tile(5, 6), RED), ErrNoUnit)
+	assert.ErrorIs(t, world.FireUnit(unit.ID, world.Tile(9, 9), RED), ErrNotInRange)
+	assert.NoError(t, world.FireUnit(unit.ID, world.Tile(5, 6), RED))
+	assert.Equal(t, FIRE, unit.Activity.Name)
 }
 
 func TestClone(t *testing.T) {
@@ -14,6 +14,7 @@ from threading import Lock
 # CONFIG
 TCP_IP = '127.0.0.1'
 TCP_PORT = 1234
+SHOW_TARGETS = False  # draw the targets in the debug overlay of the GUI ('D'), costs a command per new target
 
 # TCP connection
 conn = socket.socket(socket.AF_INET, socket.SOCK_STREAM)
@@ -74,6 +75,33 @@ def fire(x1, y1, x2, y2):
     return command("FIRE %d %d %d %d" % (x1, y1, x2, y2))
 
 
+# path returns the path (list of [x, y]) and the cost of the own unit on x1, y1 to the tile x2, y2 (None on error).
+def path(x1, y1, x2, y2):
+    resp = command("PATH %d %d %d %d" % (x1, y1, x2, y2))
+    return None if resp.startswith("err:") else json.loads(resp)
+
+
+# fire_range returns all tiles (list of [x, y]) within the fire range of the own unit on x, y (None on error).
+def fire_range(x, y):
+    resp = command("RANGE %d %d" % (x, y))
+    return None if resp.startswith("err:") else json.loads(resp)
+
+
+# dist returns the distance between two tiles.
+def dist(x1, y1, x2, y2):
+    return int(command("DIST %d %d %d %d" % (x1, y1, x2, y2)))
+
+
+# los checks if the own unit on x1, y1 can fire on the tile x2, y2.
+def los(x1, y1, x2, y2):
+    return command("LOS %d %d %d %d" % (x1, y1, x2, y2)) == "OK"
+
+
+# debug_line draws a line between two tiles in the debug overlay of the GUI for ttl iterations.
+def debug_line(x1, y1, x2, y2, color, ttl):
+    return command("DEBUG LINE %d %d %d %d %s %d" % (x1, y1, x2, y2, color, ttl))
+
+
 # ----------- WORLD ---------------------------------------------------------------------------------------------------#
 
 
@@ -298,6 +326,8 @@ if __name__ == '__main__':
                 first_target = targets[0]
                 um = [first_target.get("XCol"), first_target.get("YRow")]
                 unit_memory[unit_id] = um
+                if SHOW_TARGETS:
+                    debug_line(tile.get("XCol"), tile.get("YRow"), um[0], um[1], "yellow", 90)
             target = get_tile(world, um[0], um[1])
 
             if unit.get("Ammunition", 0) >= 0.8:
@@ -0,0 +1,53 @@
+package gui
+
+/*
+  This file draws the debug annotations of the players (see core.World.AddDebug) as an overlay.
+  The overlay is toggled with 'D' and shows the annotations of the active player (0 = all players).
+*/
+
+import (
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/hajimehoshi/ebiten/v2"
+	"github.com/hajimehoshi/ebiten/v2/ebitenutil"
+	"github.com/hajimehoshi/ebiten/v2/vector"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// drawDebug draws the markers, lines and labels of the debug annotations.
+func (g *Game) drawDebug(screen *ebiten.Image) {
+	if !g.toggleDebug || g.world == nil {
+		return
+	}
+
+	// copy of the annotations (AddDebug is called by the server sessions)
+	for _, s := range g.world.DebugShapes(g.activePlayer) {
+		clr := debugColor(s.Color)
+		posX, posY := calcScreenPosition(s.X, s.Y, true)
+
+		switch s.Kind {
+		case core.DebugMark:
+			vector.StrokeCircle(screen, float32(posX), float32(posY), tileX/3, 4, clr, true)
+		case core.DebugLine:
+			toX, toY := calcScreenPosition(s.X2, s.Y2, true)
+			vector.StrokeLine(screen, float32(posX), float32(posY), float32(toX), float32(toY), 3, clr, true)
+			vector.DrawFilledCircle(screen, float32(toX), float32(toY), 6, clr, true)
+		case core.DebugLabel:
+			txt := strings.ReplaceAll(s.Text, "_", " ")
+			x := posX - (6 / 2 * float64(len(txt)))
+			y := posY + tileY/4
+			vector.DrawFilledRect(screen, float32(x-2), float32(y), float32(6*len(txt)+4), 16, clr, false)
+			ebitenutil.DebugPrintAt(screen, txt, int(x), int(y))
+		}
+	}
+}
+
+// debugColor converts a color '#rrggbb' to a semi-transparent color (gray if invalid).
+func debugColor(s string) color.RGBA {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(s) != 7 {
+		return color.RGBA{R: 128, G: 128, B: 128, A: 200}
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 200}
+}
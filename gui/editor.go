@@ -17,6 +17,7 @@ import (
 	"image/color"
 	"os"
 	"slices"
+	"strings"
 	"time"
 )
 
@@ -27,6 +28,7 @@ var _ ebiten.Game = (*Editor)(nil)
 type Editor struct {
 	world *core.World
 	file  string
+	meta  maps.Meta // metadata of the map file (see maps.File)
 
 	xWidth       int
 	yHeight      int
@@ -35,6 +37,9 @@ type Editor struct {
 
 	activeTile *core.Tile
 	activeType byte
+
+	report      string   // validation report instead of the help text (see 'V')
+	chokepoints [][2]int // chokepoints of the validation report
 }
 
 // RunEditor initializes and runs the map editor with the specified file and world data.
@@ -44,9 +49,14 @@ type Editor struct {
 func RunEditor(file string, world *core.World) {
 
 	// load world from file
-	loadWorld, err := maps.Loader(file)
+	var loadWorld *core.World
+	var meta maps.Meta
+	mapFile, err := maps.Load(file)
 	if err != nil {
 		println(err.Error())
+	} else {
+		loadWorld = mapFile.World()
+		meta = mapFile.Meta
 	}
 
 	// check world
@@ -70,6 +80,7 @@ func RunEditor(file string, world *core.World) {
 	editor := &Editor{
 		world:        world,
 		file:         file,
+		meta:         meta,
 		xWidth:       world.XWidth,  // world dimension X
 		yHeight:      world.YHeight, // world dimension Y
 		screenWidth:  world.XWidth*(tileX+1) + 20 + tileX/2,
@@ -184,13 +195,39 @@ func (e *Editor) processUserInput() {
 	// Save the map to a file when the Control + S keys are pressed
 	if ebiten.IsKeyPressed(ebiten.KeyControl) && inpututil.IsKeyJustPressed(ebiten.KeyS) {
 		println("SAVE:", e.file)
-		// Write the map data to the file
-		if err := os.WriteFile(e.file, []byte(e.world.Json()), 0600); err != nil {
+		// Write the map data to the file (see maps.File)
+		if err := maps.FromWorld(e.world, e.meta).Save(e.file); err != nil {
 			println(err.Error())
 		}
 		// Avoid potential double writing by waiting for a short duration
 		time.Sleep(1 * time.Second)
 	}
+
+	// Toggle the validation report when the V key is pressed
+	if inpututil.IsKeyJustPressed(ebiten.KeyV) {
+		if e.report == "" {
+			e.validate()
+		} else {
+			e.report, e.chokepoints = "", nil
+		}
+	}
+}
+
+// validate creates the validation report of the current map (see maps.Validate and maps.Analyze).
+func (e *Editor) validate() {
+	f := maps.FromWorld(e.world, e.meta)
+	e.report = "\n  'V' close report\n\n"
+	for _, err := range maps.Validate(f) {
+		e.report += "  " + err.Error() + "\n"
+	}
+	report, err := maps.Analyze(f)
+	if err != nil {
+		return // see problems
+	}
+	e.chokepoints = report.Chokepoints
+	for _, line := range strings.Split(report.String(), "\n") {
+		e.report += "  " + line + "\n"
+	}
 }
 
 // changeOwner updates the ownership of the active tile and its associated unit based on the currently pressed keyboard key.
@@ -335,6 +372,12 @@ func (e *Editor) drawTiles(screen *ebiten.Image) {
 				vector.DrawFilledCircle(screen, float32(posX+tileX/2), float32(posY+tileY/2), tileX/2, bgColor, false)
 			}
 
+			// draw chokepoint of the validation report
+			if slices.Contains(e.chokepoints, [2]int{xCol, yRow}) {
+				clr := color.RGBA{R: 255, G: 140, A: 255}
+				vector.StrokeCircle(screen, float32(posX+tileX/2), float32(posY+tileY/2), tileX/3, 3, clr, false)
+			}
+
 			// draw tile owner
 			if tile.Owner > 0 {
 				clr := color.RGBA{R: 77, G: 77, B: 77, A: 222}
@@ -405,11 +448,17 @@ func (e *Editor) drawUnits(screen *ebiten.Image) {
 
 // writeGlobalText write the global text top left.
 func (e *Editor) writeGlobalText(screen *ebiten.Image) {
+	if e.report != "" {
+		ebitenutil.DebugPrint(screen, e.report)
+		return
+	}
+
 	s := "\n"
 	s += "  'Left click' set tile or unit\n"
 	s += "  'Strg + S' save to file\n"
 	s += "  '0-6' change owner\n"
 	s += "  'X' remove unit\n"
+	s += "  'V' validate\n"
 	s += "\n"
 	s += "  'B' BASE\n"
 	s += "  'D' DIRT\n"
@@ -44,6 +44,7 @@ type Game struct {
 	toggleCoordinates bool
 	toggleSupply      bool
 	toggleVisibility  bool
+	toggleDebug       bool
 }
 
 // RunGame initializes the game window and starts the GUI loop.
@@ -117,6 +118,9 @@ func (g *Game) Draw(screen *ebiten.Image) {
 	// draw units
 	g.drawUnits(screen)
 
+	// draw debug annotations
+	g.drawDebug(screen)
+
 	// write global text
 	g.writeGlobalText(screen)
 }
@@ -134,6 +138,7 @@ func (g *Game) writeGlobalText(screen *ebiten.Image) {
 		s += "  - 'C': coordinates\n"
 		s += "  - 'S': supply\n"
 		s += "  - 'V': visibility\n"
+		s += "  - 'D': debug annotations\n"
 		s += "  - 0-9: player view\n"
 		s += "\n"
 	} else {
@@ -193,6 +198,12 @@ func (g *Game) processUserInput() {
 		g.lastCommand = time.Now() // force delay after input
 	}
 
+	// toggle KEY: debug annotations ['D']
+	if ebiten.IsKeyPressed(ebiten.KeyD) {
+		g.toggleDebug = !g.toggleDebug
+		g.lastCommand = time.Now() // force delay after input
+	}
+
 	// activate fire mode
 	g.fireMode = ebiten.IsKeyPressed(ebiten.KeyControl)
 }
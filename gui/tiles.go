@@ -91,7 +91,7 @@ func (g *Game) drawTiles(screen *ebiten.Image) {
 				const size = 16
 
 				if supply > 0 {
-					clr := valueToColor(supply, core.MaxSupply, false)
+					clr := valueToColor(supply, g.world.SupplyRange(), false)
 
 					x := posX + tileX - size - 3
 					y := posY + tileY/4 + 3
@@ -1,6 +1,12 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"github.com/SchnorcherSepp/TankWars2/ai"
@@ -9,7 +15,11 @@ import (
 	"github.com/SchnorcherSepp/TankWars2/gui/resources"
 	"github.com/SchnorcherSepp/TankWars2/maps"
 	"github.com/SchnorcherSepp/TankWars2/remote"
+	"net/http"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -28,7 +38,7 @@ func parseMode() {
 	println()
 
 	// help text for mode
-	help := "Choose mode: local, server, client, editor"
+	help := "Choose mode: local, server, lobby, client, editor, transcript, generate, convert, validate"
 
 	// check args
 	if len(os.Args) < 2 {
@@ -47,10 +57,20 @@ func parseMode() {
 		parseLocal()
 	case "server":
 		parseServer()
+	case "lobby":
+		parseLobby()
 	case "client":
 		parseClient()
 	case "editor":
 		parseEditor()
+	case "transcript":
+		parseTranscript()
+	case "generate":
+		parseGenerate()
+	case "convert":
+		parseConvert()
+	case "validate":
+		parseValidate()
 	default:
 		println(help)
 		os.Exit(4)
@@ -80,15 +100,52 @@ func parseServer() {
 	var mapFile string
 	var host string
 	var port string
+	var wsAddr string
+	var wsOrigins string
+	var adminAddr string
+	var adminToken string
+	var password string
+	var seatPasswords string
+	var seatConns int
+	var seatControls string
+	var tlsCert string
+	var tlsKey string
+	var bots string
+	var joinTimeout time.Duration
+	var inactiveTimeout time.Duration
+	var timeoutBot string
+	var transcript string
+	var lan bool
 	var headless bool
 	var mute bool
+	limits := remote.DefaultLimits
 
 	// parse
 	flag.StringVar(&mapFile, "map", "", "Path to map file")
 	flag.StringVar(&host, "host", "", "Server host")
 	flag.StringVar(&port, "port", "", "Server port")
+	flag.StringVar(&wsAddr, "ws", "", "Listen address (host:port) of the WebSocket gateway for browser clients")
+	flag.StringVar(&wsOrigins, "ws-origins", "", "Origins of other web pages allowed to use the WebSocket gateway, e.g. 'https://example.com' ('*' = all)")
+	flag.StringVar(&adminAddr, "admin", "", "Listen address (host:port) of the HTTP admin API")
+	flag.StringVar(&adminToken, "admin-token", os.Getenv("TANKWARS_ADMIN_TOKEN"), "Token of the admin API (default: $TANKWARS_ADMIN_TOKEN or random)")
 	flag.BoolVar(&headless, "headless", false, "Run in headless mode")
 	flag.BoolVar(&mute, "mute", false, "Mute sound")
+	flag.IntVar(&limits.CommandsPerIteration, "cmd-limit", limits.CommandsPerIteration, "Max. commands per iteration (per second while paused) and client (0 = no limit)")
+	flag.IntVar(&limits.StatusPerSecond, "status-limit", limits.StatusPerSecond, "Max. STATUS per second and client (0 = no limit)")
+	flag.IntVar(&limits.MaxLineLength, "line-limit", limits.MaxLineLength, "Max. command line length in bytes (0 = no limit)")
+	flag.DurationVar(&limits.IdleTimeout, "idle-timeout", limits.IdleTimeout, "Close connections without a command for this time (0 = no limit)")
+	flag.StringVar(&password, "password", os.Getenv("TANKWARS_PASSWORD"), "Password for all seats (default: $TANKWARS_PASSWORD)")
+	flag.StringVar(&seatPasswords, "seat-passwords", "", "Passwords of single seats, e.g. '1=abc,2=def'")
+	flag.IntVar(&seatConns, "seat-conns", 1, "Max. clients per seat chosen with AUTH (see client -seat and -control)")
+	flag.StringVar(&seatControls, "seat-controls", "", "Seat passwords bound to units, e.g. '1:abc=A,U;1:def=T' (see -seat-conns)")
+	flag.StringVar(&tlsCert, "tls-cert", "", "Path to the TLS certificate (PEM)")
+	flag.StringVar(&tlsKey, "tls-key", "", "Path to the TLS private key (PEM)")
+	flag.StringVar(&bots, "bots", "", "In-process bots for single seats, e.g. '2=basic' (bots: "+strings.Join(ai.Names(), ", ")+")")
+	flag.DurationVar(&joinTimeout, "join-timeout", 0, "Start the game without the missing players after this time (0 = wait forever)")
+	flag.DurationVar(&inactiveTimeout, "inactive-timeout", 0, "Max. time of a player without game commands in a running game (0 = no limit)")
+	flag.StringVar(&timeoutBot, "timeout-bot", "", "Bot that takes over the seat after a timeout (default: the player forfeits)")
+	flag.StringVar(&transcript, "transcript", "", "Append all commands and responses of the clients to this file (JSON lines)")
+	flag.BoolVar(&lan, "lan", false, "Announce the server in the local network (see client -discover)")
 	flag.Parse()
 
 	// enforce map, host and port
@@ -97,8 +154,123 @@ func parseServer() {
 		os.Exit(6)
 	}
 
+	// authentication
+	seats, err := parseSeats(seatPasswords)
+	if err != nil {
+		println("err: invalid seat passwords:", err.Error())
+		os.Exit(18)
+	}
+	controls, err := parseSeatControls(seatControls)
+	if err != nil {
+		println("err: invalid seat controls:", err.Error())
+		os.Exit(18)
+	}
+
+	// bots
+	botSeats, err := parseSeats(bots)
+	if err != nil {
+		println("err: invalid bots:", err.Error())
+		os.Exit(18)
+	}
+	for _, name := range botSeats {
+		if _, ok := ai.Lookup(name); !ok {
+			println("err: unknown bot:", name)
+			os.Exit(18)
+		}
+	}
+
+	// timeouts
+	policy, replaceBot := remote.PolicyForfeit, ai.Bot(nil)
+	if timeoutBot != "" {
+		var ok bool
+		if replaceBot, ok = ai.Lookup(timeoutBot); !ok {
+			println("err: unknown bot:", timeoutBot)
+			os.Exit(18)
+		}
+		policy = remote.PolicyBot
+	}
+
+	// TLS
+	var tlsConfig *tls.Config
+	if tlsCert != "" || tlsKey != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+		if err != nil {
+			println("err: invalid TLS certificate:", err.Error())
+			os.Exit(18)
+		}
+		tlsConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	}
+
+	// transcript
+	var t *remote.Transcript
+	if transcript != "" {
+		t = openTranscript(transcript)
+	}
+
 	// run program
-	runServer(mapFile, host, port, headless, mute)
+	config := func(server *remote.Server) {
+		server.Limits = limits
+		server.Password = password
+		server.SeatPasswords = seats
+		server.SeatConnections = seatConns
+		server.SeatControls = controls
+		server.TLSConfig = tlsConfig
+		if wsOrigins != "" {
+			server.WSOrigins = strings.Split(wsOrigins, ",")
+		}
+		server.JoinTimeout = joinTimeout
+		server.InactiveTimeout = inactiveTimeout
+		server.TimeoutPolicy = policy
+		server.TimeoutBot = replaceBot
+		server.Transcript = t
+		if lan {
+			server.Announce = fmt.Sprintf("255.255.255.255:%d", remote.DiscoveryPort)
+			server.MapName = filepath.Base(mapFile)
+		}
+	}
+	runServer(mapFile, host, port, wsAddr, adminAddr, adminToken, config, botSeats, headless, mute)
+}
+
+func parseLobby() {
+	var mapDir string
+	var host string
+	var port string
+	var tlsCert string
+	var tlsKey string
+
+	// parse
+	flag.StringVar(&mapDir, "maps", "maps", "Directory with map files")
+	flag.StringVar(&host, "host", "", "Server host")
+	flag.StringVar(&port, "port", "", "Server port")
+	flag.StringVar(&tlsCert, "tls-cert", "", "Path to the TLS certificate (PEM)")
+	flag.StringVar(&tlsKey, "tls-key", "", "Path to the TLS private key (PEM)")
+	flag.Parse()
+
+	// enforce host and port
+	if host == "" || port == "" {
+		flag.Usage()
+		os.Exit(12)
+	}
+
+	// TLS
+	if tlsCert != "" || tlsKey != "" {
+		cert, err := tls.LoadX509KeyPair(tlsCert, tlsKey)
+		if err != nil {
+			println("err: invalid TLS certificate:", err.Error())
+			os.Exit(18)
+		}
+		l, err := tls.Listen("tcp", host+":"+port, &tls.Config{Certificates: []tls.Certificate{cert}})
+		if err != nil {
+			println("err:", err.Error())
+			os.Exit(31)
+		}
+		fmt.Println("START LOBBY [" + host + ":" + port + "] (TLS)")
+		remote.ServeLobby(l, mapDir) // blocking
+		return
+	}
+
+	// run program (blocking)
+	remote.RunLobby(host, port, mapDir)
 }
 
 func parseClient() {
@@ -106,22 +278,94 @@ func parseClient() {
 	var port string
 	var basicAI bool
 	var headless bool
+	var list bool
+	var create string
+	var game int
+	var password string
+	var seat uint
+	var useTLS bool
+	var tlsCA string
+	var transcript string
+	var discover bool
+	var control string
 
 	// parse
 	flag.StringVar(&host, "host", "", "Server host")
 	flag.StringVar(&port, "port", "", "Server port")
 	flag.BoolVar(&basicAI, "ai", false, "Use basic AI")
 	flag.BoolVar(&headless, "headless", false, "Run in headless mode")
+	flag.BoolVar(&list, "list", false, "List the games of a lobby server")
+	flag.StringVar(&create, "create", "", "Create a game with this map on a lobby server and join it")
+	flag.IntVar(&game, "game", 0, "Join the game with this ID on a lobby server")
+	flag.StringVar(&password, "password", os.Getenv("TANKWARS_PASSWORD"), "Server password (default: $TANKWARS_PASSWORD)")
+	flag.UintVar(&seat, "seat", 0, "Take this seat (player ID) with the password (0 = next free seat)")
+	flag.BoolVar(&useTLS, "tls", false, "Connect with TLS")
+	flag.StringVar(&tlsCA, "tls-ca", "", "Path to the CA certificate (PEM) of the server (enables TLS)")
+	flag.StringVar(&transcript, "transcript", "", "Append all commands and responses to this file (JSON lines)")
+	flag.BoolVar(&discover, "discover", false, "Find servers in the local network and join one (instead of host and port)")
+	flag.StringVar(&control, "control", "", "Control only these units of a shared seat, e.g. 'A,U' or 'T,123' (see server -seat-conns)")
 	flag.Parse()
 
+	// LAN discovery
+	if discover {
+		host, port = discoverServer()
+	}
+
 	// enforce host and port
-	if host == "" || port == "" {
+	if host == "" || port == "" || seat > 255 {
 		flag.Usage()
 		os.Exit(7)
 	}
 
+	// authentication
+	var opts []remote.ClientOption
+	if seat > 0 {
+		opts = append(opts, remote.WithSeat(uint8(seat), password))
+	} else if password != "" {
+		opts = append(opts, remote.WithPassword(password))
+	}
+	if control != "" {
+		opts = append(opts, remote.WithControl(control))
+	}
+
+	// TLS
+	if useTLS || tlsCA != "" {
+		config := new(tls.Config)
+		if tlsCA != "" {
+			pem, err := os.ReadFile(tlsCA)
+			config.RootCAs = x509.NewCertPool()
+			if err != nil || !config.RootCAs.AppendCertsFromPEM(pem) {
+				println("err: invalid CA certificate:", tlsCA)
+				os.Exit(19)
+			}
+		}
+		opts = append(opts, remote.WithTLS(config))
+	}
+
+	// transcript
+	if transcript != "" {
+		opts = append(opts, remote.WithTranscript(openTranscript(transcript)))
+	}
+
+	// lobby: list games
+	if list {
+		listGames(host, port, opts)
+		return
+	}
+
+	// lobby: create game (private with -password)
+	if create != "" {
+		id, err := remote.CreateGame(host, port, create, opts...)
+		if err != nil {
+			println(err.Error())
+			os.Exit(13)
+		}
+		println("created game", id)
+		game = id
+	}
+
 	// run program
-	runClient(host, port, basicAI, headless)
+	runClient(host, port, game, opts, basicAI, headless)
 }
 
 func parseEditor() {
@@ -145,6 +389,125 @@ func parseEditor() {
 	runEditor(mapFile, newWidth, newHeight)
 }
 
+func parseTranscript() {
+	var file string
+	var player int
+	var commands string
+
+	// parse
+	flag.StringVar(&file, "file", "", "Path to transcript file")
+	flag.IntVar(&player, "player", -1, "Show only the commands of this player (-1 = all players)")
+	flag.StringVar(&commands, "command", "", "Show only these commands, e.g. 'MOVE,FIRE'")
+	flag.Parse()
+
+	// enforce file
+	if file == "" {
+		flag.Usage()
+		os.Exit(22)
+	}
+
+	// run program
+	runTranscript(file, player, commands)
+}
+
+func parseGenerate() {
+	var out string
+	var symmetry string
+	var terrain string
+	var units string
+	var reinforce string
+	params := maps.DefaultParams
+
+	// parse
+	flag.StringVar(&out, "out", "", "Path of the new map file")
+	flag.IntVar(&params.Width, "width", params.Width, "Width of the map")
+	flag.IntVar(&params.Height, "height", params.Height, "Height of the map (4 players: odd height)")
+	flag.IntVar(&params.Players, "players", params.Players, "Number of players (2 or 4)")
+	flag.StringVar(&symmetry, "symmetry", "rotation", "Symmetry: rotation (2 players) or mirror")
+	flag.StringVar(&terrain, "terrain", "G=60,F=14,D=6,H=6,M=5,W=5,O=2,S=2", "Weights of the tile types")
+	flag.IntVar(&params.Bases, "bases", params.Bases, "Bases per player (the first one is the start base)")
+	flag.StringVar(&units, "units", string(params.Units), "Start units of each player, e.g. 'ATU'")
+	flag.StringVar(&reinforce, "reinforce", "1800=U,3600=T,5400=A", "Reinforcement, e.g. '1800=U,3600=T' (iteration=unit)")
+	flag.Int64Var(&params.Seed, "seed", 0, "Seed of the map (0 = random)")
+	flag.Parse()
+
+	// enforce output file
+	if out == "" {
+		flag.Usage()
+		os.Exit(24)
+	}
+
+	// check values
+	var err error
+	switch symmetry {
+	case "rotation":
+		params.Symmetry = maps.SymmetryRotation
+	case "mirror":
+		params.Symmetry = maps.SymmetryMirror
+	default:
+		err = fmt.Errorf("invalid symmetry %q", symmetry)
+	}
+	if err == nil {
+		params.Terrain, err = parseTerrain(terrain)
+	}
+	if err == nil {
+		params.Reinforcement, err = parseReinforcement(reinforce)
+	}
+	if err != nil {
+		println("err:", err.Error())
+		os.Exit(24)
+	}
+	params.Units = []byte(strings.ToUpper(units))
+	if params.Seed == 0 {
+		params.Seed = time.Now().UnixNano()
+	}
+
+	// run program
+	runGenerate(out, params)
+}
+
+func parseConvert() {
+	var in string
+	var out string
+	var meta maps.Meta
+
+	// parse
+	flag.StringVar(&in, "in", "", "Path of the map file (JSON or "+maps.TextExt+")")
+	flag.StringVar(&out, "out", "", "Path of the new map file (JSON or "+maps.TextExt+")")
+	flag.StringVar(&meta.Name, "name", "", "Set the name of the map")
+	flag.StringVar(&meta.Author, "author", "", "Set the author of the map")
+	flag.StringVar(&meta.Description, "description", "", "Set the description of the map")
+	flag.Parse()
+
+	// enforce files
+	if in == "" || out == "" {
+		flag.Usage()
+		os.Exit(26)
+	}
+
+	// run program
+	runConvert(in, out, meta)
+}
+
+func parseValidate() {
+	var mapFile string
+	var asJSON bool
+
+	// parse
+	flag.StringVar(&mapFile, "map", "", "Path of the map file (JSON or "+maps.TextExt+")")
+	flag.BoolVar(&asJSON, "json", false, "Print the problems and the report as JSON")
+	flag.Parse()
+
+	// enforce map
+	if mapFile == "" {
+		flag.Usage()
+		os.Exit(28)
+	}
+
+	// run program
+	runValidate(mapFile, asJSON)
+}
+
 //--------------------------------------------------------------------------------------------------------------------//
 
 func runLocal(mapFile string, mute bool) {
@@ -163,7 +526,7 @@ func runLocal(mapFile string, mute bool) {
 	}
 }
 
-func runServer(mapFile, host, port string, headless, mute bool) {
+func runServer(mapFile, host, port, wsAddr, adminAddr, adminToken string, config func(server *remote.Server), bots map[uint8]string, headless, mute bool) {
 	title := fmt.Sprintf("Tank Wars %s (%s)", VERSION, "Server")
 
 	// load map
@@ -173,9 +536,70 @@ func runServer(mapFile, host, port string, headless, mute bool) {
 		os.Exit(10)
 	}
 
+	// reserve the bot seats before clients can connect
+	server := remote.NewServer(host+":"+port, world, world.PlayerCount())
+	config(server)
+	for seat := range bots {
+		if int(seat) > world.PlayerCount() {
+			println("err: bot seat is not a player:", seat)
+			os.Exit(20)
+		}
+		server.BotSeats = append(server.BotSeats, seat)
+	}
+
 	// run server
-	playerCount := world.PlayerCount()
-	go remote.RunServer(host, port, world, playerCount)
+	if err := server.Start(context.Background()); err != nil {
+		println("err:", err.Error())
+		os.Exit(15)
+	}
+	fmt.Println("START SERVER [" + server.Addr().String() + "]")
+
+	// run in-process bots
+	for seat, name := range bots {
+		client, err := server.ConnectBot(seat)
+		if err != nil {
+			println("err:", err.Error())
+			os.Exit(20)
+		}
+		bot, _ := ai.Lookup(name)
+		go bot(client)
+		fmt.Printf("START BOT %s [seat %d]\n", name, seat)
+	}
+
+	// run WebSocket gateway (with the certificate of the server)
+	if wsAddr != "" {
+		go func() {
+			fmt.Println("START WEBSOCKET [" + wsAddr + "]")
+			ws := &http.Server{Addr: wsAddr, Handler: server.WebSocketHandler(), TLSConfig: server.TLSConfig}
+			var err error
+			if ws.TLSConfig != nil {
+				err = ws.ListenAndServeTLS("", "") // wss://
+			} else {
+				err = ws.ListenAndServe()
+			}
+			if err != nil {
+				println("err:", err.Error())
+				os.Exit(16)
+			}
+		}()
+	}
+
+	// run admin API
+	if adminAddr != "" {
+		if adminToken == "" {
+			b := make([]byte, 16)
+			_, _ = rand.Read(b)
+			adminToken = hex.EncodeToString(b)
+			fmt.Println("ADMIN TOKEN: " + adminToken)
+		}
+		go func() {
+			fmt.Println("START ADMIN API [" + adminAddr + "]")
+			if err := http.ListenAndServe(adminAddr, server.AdminHandler(adminToken)); err != nil {
+				println("err:", err.Error())
+				os.Exit(17)
+			}
+		}()
+	}
 
 	// run gui/server (blocking)
 	if !headless {
@@ -186,23 +610,31 @@ func runServer(mapFile, host, port string, headless, mute bool) {
 	} else {
 		// headless
 		resources.MuteSound = true // play no sound without GUI
-		for {
-			world.Update()
-			time.Sleep(time.Second / core.GameSpeed)
-		}
+		server.RunWorld(nil)
 	}
 }
 
-func runClient(host, port string, basicAI, headless bool) {
+func runClient(host, port string, game int, opts []remote.ClientOption, basicAI, headless bool) {
 	title := fmt.Sprintf("Tank Wars %s (%s)", VERSION, "Client")
 
-	// new client
-	client, err := remote.NewClient(host, port)
+	// new client (direct or lobby game)
+	var client *remote.Client
+	var err error
+	if game > 0 {
+		client, err = remote.JoinGame(host, port, game, opts...)
+	} else {
+		client, err = remote.NewClient(host, port, opts...)
+	}
 	if err != nil {
 		println(err.Error())
 		os.Exit(11)
 	}
 
+	// compact and compressed world updates
+	if err := client.UseCompact(true); err != nil {
+		println(err.Error())
+	}
+
 	// load world from server
 	world := client.Status()
 
@@ -218,13 +650,250 @@ func runClient(host, port string, basicAI, headless bool) {
 			panic(err)
 		}
 	} else {
-		for {
-			time.Sleep(1 * time.Second)
+		<-client.Done()
+		println("disconnected:", client.Err().Error())
+	}
+
+}
+
+func listGames(host, port string, opts []remote.ClientOption) {
+	games, err := remote.ListGames(host, port, opts...)
+	if err != nil {
+		println(err.Error())
+		os.Exit(14)
+	}
+
+	// print games
+	for _, g := range games {
+		state := "waiting"
+		if g.Running {
+			state = "running"
+		}
+		if g.Private {
+			state += " (private)"
+		}
+		fmt.Printf("%4d  %-40s %d/%d  %s\n", g.ID, g.Map, g.Players, g.MaxPlayer, state)
+	}
+}
+
+// discoverServer lists the servers in the local network and returns the chosen one.
+// A single server is chosen automatically.
+func discoverServer() (host, port string) {
+	println("searching servers ...")
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	servers, err := remote.Discover(ctx, fmt.Sprintf(":%d", remote.DiscoveryPort))
+	if err != nil {
+		println("err: discovery:", err.Error())
+		os.Exit(23)
+	}
+	if len(servers) == 0 {
+		println("err: no server found")
+		os.Exit(23)
+	}
+
+	// print servers
+	for i, s := range servers {
+		state := "waiting"
+		if s.Started {
+			state = "running"
 		}
+		fmt.Printf("%2d  %-21s %-30s %d/%d  %d free  %s  protocol %d  auth=%t tls=%t\n",
+			i+1, s.Addr(), s.Map, s.Players, s.MaxPlayer, s.FreeSeats, state, s.Protocol, s.Auth, s.TLS)
 	}
 
+	// choose server
+	n := 1
+	if len(servers) > 1 {
+		fmt.Print("join server: ")
+		if _, err := fmt.Scanln(&n); err != nil || n < 1 || n > len(servers) {
+			println("err: invalid server")
+			os.Exit(23)
+		}
+	}
+	return servers[n-1].Host, servers[n-1].Port
+}
+
+// parseSeats parses values for single seats, e.g. '1=abc,2=def' (see -seat-passwords and -bots).
+func parseSeats(s string) (map[uint8]string, error) {
+	seats := make(map[uint8]string)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		seat, value, ok := strings.Cut(pair, "=")
+		id, err := strconv.ParseUint(strings.TrimSpace(seat), 10, 8)
+		if !ok || err != nil || id == 0 || value == "" {
+			return nil, fmt.Errorf("invalid seat %q", pair)
+		}
+		seats[uint8(id)] = value
+	}
+	return seats, nil
+}
+
+// parseSeatControls parses seat passwords bound to units, e.g. '1:abc=A,U;1:def=T' (see server -seat-controls).
+func parseSeatControls(s string) ([]remote.SeatControl, error) {
+	list := make([]remote.SeatControl, 0)
+	for _, item := range strings.Split(s, ";") {
+		if strings.TrimSpace(item) == "" {
+			continue
+		}
+		seat, rest, ok1 := strings.Cut(item, ":")
+		password, units, ok2 := strings.Cut(rest, "=")
+		id, err := strconv.ParseUint(strings.TrimSpace(seat), 10, 8)
+		if !ok1 || !ok2 || err != nil || id == 0 || password == "" || units == "" {
+			return nil, fmt.Errorf("invalid seat control %q", item)
+		}
+		list = append(list, remote.SeatControl{Seat: uint8(id), Password: password, Units: units})
+	}
+	return list, nil
+}
+
+// parseTerrain parses the weights of tile types, e.g. 'G=60,F=14' (see generate -terrain).
+func parseTerrain(s string) (map[byte]int, error) {
+	terrain := make(map[byte]int)
+	for _, pair := range strings.Split(s, ",") {
+		typ, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		weight, err := strconv.Atoi(value)
+		if !ok || err != nil || len(typ) != 1 {
+			return nil, fmt.Errorf("invalid terrain %q", pair)
+		}
+		terrain[strings.ToUpper(typ)[0]] = weight
+	}
+	return terrain, nil
+}
+
+// parseReinforcement parses a reinforcement schedule, e.g. '1800=U,3600=T' (see generate -reinforce).
+func parseReinforcement(s string) (map[uint64]byte, error) {
+	reinforcement := make(map[uint64]byte)
+	for _, pair := range strings.Split(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		it, typ, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		iteration, err := strconv.ParseUint(it, 10, 64)
+		if !ok || err != nil || len(typ) != 1 {
+			return nil, fmt.Errorf("invalid reinforcement %q", pair)
+		}
+		reinforcement[iteration] = strings.ToUpper(typ)[0]
+	}
+	return reinforcement, nil
+}
+
+// openTranscript opens the transcript file for appending (see -transcript).
+func openTranscript(path string) *remote.Transcript {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		println("err: invalid transcript:", err.Error())
+		os.Exit(21)
+	}
+	return remote.NewTranscript(f)
+}
+
+func runTranscript(file string, player int, commands string) {
+	f, err := os.Open(file)
+	if err != nil {
+		println("err: invalid transcript:", err.Error())
+		os.Exit(21)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	// read
+	entries, err := remote.ReadTranscript(f)
+	if err != nil {
+		println("err: invalid transcript:", err.Error())
+		os.Exit(21)
+	}
+
+	// filter and print
+	var names []string
+	if commands != "" {
+		names = strings.Split(commands, ",")
+	}
+	for _, e := range remote.FilterTranscript(entries, player, names) {
+		fmt.Println(e.String())
+	}
 }
 
 func runEditor(mapFile string, newWidth, newHeight int) {
 	gui.RunEditor(mapFile, core.NewWorld(newWidth, newHeight))
 }
+
+func runGenerate(out string, params maps.Params) {
+	world, err := maps.Generate(params)
+	if err != nil {
+		println("err:", err.Error())
+		os.Exit(25)
+	}
+	meta := maps.Meta{Description: fmt.Sprintf("generated with seed %d", params.Seed)}
+	if err := maps.FromWorld(world, meta).Save(out); err != nil {
+		println("err:", err.Error())
+		os.Exit(25)
+	}
+	fmt.Printf("map %dx%d with %d players written to %s (seed %d)\n", world.XWidth, world.YHeight, world.PlayerCount(), out, params.Seed)
+}
+
+func runConvert(in, out string, meta maps.Meta) {
+	f, err := maps.Load(in)
+	if err != nil {
+		println("err:", err.Error())
+		os.Exit(27)
+	}
+	if meta.Name != "" {
+		f.Meta.Name = meta.Name
+	}
+	if meta.Author != "" {
+		f.Meta.Author = meta.Author
+	}
+	if meta.Description != "" {
+		f.Meta.Description = meta.Description
+	}
+	if err := f.Save(out); err != nil {
+		println("err:", err.Error())
+		os.Exit(27)
+	}
+	fmt.Printf("map %q (%dx%d, %d players) written to %s\n", f.Meta.Name, f.Width, f.Height, f.Meta.Players, out)
+}
+
+func runValidate(mapFile string, asJSON bool) {
+	f, err := maps.Load(mapFile)
+	if err != nil {
+		println("err:", err.Error())
+		os.Exit(29)
+	}
+	problems := make([]string, 0)
+	for _, err := range maps.Validate(f) {
+		problems = append(problems, err.Error())
+	}
+	report, err := maps.Analyze(f)
+	if err != nil {
+		println("err:", err.Error())
+		os.Exit(29)
+	}
+
+	// print
+	if asJSON {
+		b, err := json.MarshalIndent(struct {
+			Problems []string
+			Report   *maps.Report
+		}{problems, report}, "", "  ")
+		if err != nil {
+			println("err:", err.Error())
+			os.Exit(29)
+		}
+		fmt.Println(string(b))
+	} else {
+		fmt.Printf("map %q (%dx%d, %d players)\n", f.Meta.Name, f.Width, f.Height, f.Meta.Players)
+		for _, p := range problems {
+			fmt.Println("problem:", p)
+		}
+		fmt.Print(report.String())
+	}
+
+	// problems are an error (e.g. for scripts)
+	if len(problems) > 0 {
+		os.Exit(30)
+	}
+}
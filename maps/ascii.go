@@ -0,0 +1,363 @@
+package maps
+
+/*
+  This file provides a plain-text map format for reviews in git and for test fixtures. The header has the
+  metadata, the reinforcement and the rules. The terrain has one letter per tile (see core.TILES, '.' = no type),
+  odd rows are indented by exactly one space to show the hex offset (no tabs). The units layer has two characters per tile, the unit type and
+  the player (e.g. 'T1'), so it is aligned with the terrain:
+
+    # comment
+    name: Border Dispute
+    author: ...
+    players: 2
+    teams: 1 2
+    reinforcement: 1200=U 1800=T
+    rules: MaxSupply=20
+
+    terrain:
+    F F G M M
+     F S G M G
+    B G G G F
+
+    units:
+    . . . . .
+     . T1. . .
+    A1. . . U2
+
+  The image IDs of the GUI are not part of the text format (random when loaded).
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// TextExt is the file extension of the text format (see Load and File.Save).
+const TextExt = ".txt"
+
+// layerRow is a row of the terrain or units layer with its line number in the text.
+type layerRow struct {
+	n    int    // Line number (for errors)
+	text string // Row without trailing spaces
+}
+
+//--------  Reader  --------------------------------------------------------------------------------------------------//
+
+// ReadASCII reads and validates a map in the text format.
+// Without a 'players' header, the player count is taken from the units.
+func ReadASCII(r io.Reader) (*File, error) {
+	f := &File{Version: Version}
+	var terrain, units []layerRow
+	players := -1
+
+	// read lines
+	scanner := bufio.NewScanner(r)
+	var block *[]layerRow // current block (terrain or units)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+
+		// blocks end with an empty line
+		if line == "" {
+			block = nil
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if block != nil {
+			*block = append(*block, layerRow{n: n, text: line})
+			continue
+		}
+
+		// header
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("%w: line %d: expected 'key: value'", ErrInvalidMap, n)
+		}
+		value = strings.TrimSpace(value)
+		var err error
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "name":
+			f.Meta.Name = value
+		case "author":
+			f.Meta.Author = value
+		case "description":
+			f.Meta.Description = value
+		case "players":
+			players, err = strconv.Atoi(value)
+		case "teams":
+			f.Meta.Teams, err = parseTeams(value)
+		case "reinforcement":
+			f.Reinforcement = make(map[uint64]string)
+			err = parsePairs(value, func(k, v string) error {
+				it, err := strconv.ParseUint(k, 10, 64)
+				f.Reinforcement[it] = v
+				return err
+			})
+		case "rules":
+			f.Rules = &core.Rules{}
+			err = parsePairs(value, func(k, v string) error {
+				return setRule(f.Rules, k, v)
+			})
+		case "terrain":
+			block = &terrain
+		case "units":
+			block = &units
+		default:
+			err = fmt.Errorf("unknown key %q", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %v", ErrInvalidMap, n, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// layers
+	if err := f.parseTerrain(terrain); err != nil {
+		return nil, err
+	}
+	if err := f.parseUnits(units); err != nil {
+		return nil, err
+	}
+	f.Meta.Players = players
+	if players < 0 {
+		f.Meta.Players = countPlayers(f.Units)
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// parseTerrain sets the size and the tiles of the map from the rows of the terrain layer.
+// All rows must have the width of the first row.
+func (f *File) parseTerrain(rows []layerRow) error {
+	f.Height = len(rows)
+	f.Tiles = make([]string, len(rows))
+	for y, r := range rows {
+		line, err := trimOffset(r.text, y)
+		if err != nil {
+			return fmt.Errorf("%w: line %d: terrain row %d: %v", ErrInvalidMap, r.n, y, err)
+		}
+		row := strings.Join(strings.Fields(line), "")
+		if len(strings.Fields(line)) != len(row) {
+			return fmt.Errorf("%w: line %d: terrain row %d: one character per tile", ErrInvalidMap, r.n, y)
+		}
+		if y == 0 {
+			f.Width = len(row)
+		}
+		if len(row) != f.Width {
+			return fmt.Errorf("%w: line %d: terrain row %d has %d tiles, expected %d", ErrInvalidMap, r.n, y, len(row), f.Width)
+		}
+		f.Tiles[y] = row
+	}
+	return nil
+}
+
+// parseUnits sets the units of the map from the rows of the units layer (two characters per tile).
+func (f *File) parseUnits(rows []layerRow) error {
+	f.Units = make([]Unit, 0)
+	if len(rows) == 0 {
+		return nil // no units
+	}
+	if len(rows) != f.Height {
+		return fmt.Errorf("%w: %d unit rows for %d terrain rows", ErrInvalidMap, len(rows), f.Height)
+	}
+	for y, r := range rows {
+		line, err := trimOffset(r.text, y)
+		if err != nil {
+			return fmt.Errorf("%w: line %d: unit row %d: %v", ErrInvalidMap, r.n, y, err)
+		}
+		if len(line) > 2*f.Width {
+			return fmt.Errorf("%w: line %d: unit row %d is too long", ErrInvalidMap, r.n, y)
+		}
+		for x := 0; 2*x < len(line); x++ {
+			cell := line[2*x:]
+			if len(cell) > 2 {
+				cell = cell[:2]
+			}
+			if cell[0] == '.' || cell[0] == ' ' {
+				continue // no unit
+			}
+			if len(cell) != 2 || cell[1] < '0' || cell[1] > '9' {
+				return fmt.Errorf("%w: line %d: invalid unit %q at %d,%d", ErrInvalidMap, r.n, cell, x, y)
+			}
+			f.Units = append(f.Units, Unit{X: x, Y: y, Player: cell[1] - '0', Type: cell[:1]})
+		}
+	}
+	return nil
+}
+
+// trimOffset removes the hex offset of a layer row: odd rows start with exactly one space, even rows without.
+// Tabs are not allowed, because they would shift the tiles.
+func trimOffset(line string, y int) (string, error) {
+	if strings.Contains(line, "\t") {
+		return "", errors.New("tabs are not allowed")
+	}
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	if indent != y%2 {
+		return "", errors.New("wrong indentation (hex offset: odd rows start with one space, even rows without)")
+	}
+	return line[indent:], nil
+}
+
+// parseTeams parses teams like '1,3 2,4' (teams separated by spaces, players by commas).
+func parseTeams(s string) ([][]uint8, error) {
+	teams := make([][]uint8, 0)
+	for _, field := range strings.Fields(s) {
+		team := make([]uint8, 0)
+		for _, p := range strings.Split(field, ",") {
+			player, err := strconv.ParseUint(p, 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid team %q", field)
+			}
+			team = append(team, uint8(player))
+		}
+		teams = append(teams, team)
+	}
+	return teams, nil
+}
+
+// parsePairs parses pairs like 'key=value key=value' and calls set for each pair.
+func parsePairs(s string, set func(k, v string) error) error {
+	for _, pair := range strings.Fields(s) {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("invalid pair %q", pair)
+		}
+		if err := set(k, v); err != nil {
+			return fmt.Errorf("invalid pair %q", pair)
+		}
+	}
+	return nil
+}
+
+// setRule sets a rule by its name (see core.Rules).
+func setRule(rules *core.Rules, name, value string) error {
+	switch name {
+	case "MaxSupply":
+		n, err := strconv.Atoi(value)
+		rules.MaxSupply = n
+		return err
+	case "SupplySpeed":
+		n, err := strconv.ParseFloat(value, 32)
+		rules.SupplySpeed = float32(n)
+		return err
+	default:
+		return fmt.Errorf("unknown rule %q", name)
+	}
+}
+
+// countPlayers returns the number of players with units.
+func countPlayers(units []Unit) int {
+	players := make(map[uint8]bool)
+	for _, u := range units {
+		players[u.Player] = true
+	}
+	return len(players)
+}
+
+//--------  Writer  --------------------------------------------------------------------------------------------------//
+
+// WriteASCII writes the map in the text format.
+func (f *File) WriteASCII(w io.Writer) error {
+	var b bytes.Buffer
+
+	// header
+	fmt.Fprintf(&b, "name: %s\n", f.Meta.Name)
+	if f.Meta.Author != "" {
+		fmt.Fprintf(&b, "author: %s\n", f.Meta.Author)
+	}
+	if f.Meta.Description != "" {
+		fmt.Fprintf(&b, "description: %s\n", f.Meta.Description)
+	}
+	fmt.Fprintf(&b, "players: %d\n", f.Meta.Players)
+	if len(f.Meta.Teams) > 0 {
+		teams := make([]string, 0, len(f.Meta.Teams))
+		for _, team := range f.Meta.Teams {
+			list := make([]string, 0, len(team))
+			for _, p := range team {
+				list = append(list, strconv.Itoa(int(p)))
+			}
+			teams = append(teams, strings.Join(list, ","))
+		}
+		fmt.Fprintf(&b, "teams: %s\n", strings.Join(teams, " "))
+	}
+	if len(f.Reinforcement) > 0 {
+		iterations := make([]uint64, 0, len(f.Reinforcement))
+		for it := range f.Reinforcement {
+			iterations = append(iterations, it)
+		}
+		sort.Slice(iterations, func(i, j int) bool { return iterations[i] < iterations[j] })
+		list := make([]string, 0, len(iterations))
+		for _, it := range iterations {
+			list = append(list, fmt.Sprintf("%d=%s", it, f.Reinforcement[it]))
+		}
+		fmt.Fprintf(&b, "reinforcement: %s\n", strings.Join(list, " "))
+	}
+	if r := f.Rules; r != nil {
+		list := make([]string, 0, 2)
+		if r.MaxSupply != 0 {
+			list = append(list, fmt.Sprintf("MaxSupply=%d", r.MaxSupply))
+		}
+		if r.SupplySpeed != 0 {
+			list = append(list, "SupplySpeed="+strconv.FormatFloat(float64(r.SupplySpeed), 'f', -1, 32))
+		}
+		fmt.Fprintf(&b, "rules: %s\n", strings.Join(list, " "))
+	}
+
+	// terrain
+	b.WriteString("\nterrain:\n")
+	for y, row := range f.Tiles {
+		if y%2 == 1 {
+			b.WriteByte(' ') // hex offset
+		}
+		for x := 0; x < len(row); x++ {
+			b.WriteByte(row[x])
+			b.WriteByte(' ')
+		}
+		trimEnd(&b)
+	}
+
+	// units
+	if len(f.Units) > 0 {
+		cells := make(map[[2]int]string, len(f.Units))
+		for _, u := range f.Units {
+			cells[[2]int{u.X, u.Y}] = fmt.Sprintf("%s%d", u.Type, u.Player)
+		}
+		b.WriteString("\nunits:\n")
+		for y := 0; y < f.Height; y++ {
+			if y%2 == 1 {
+				b.WriteByte(' ') // hex offset
+			}
+			for x := 0; x < f.Width; x++ {
+				if cell, ok := cells[[2]int{x, y}]; ok {
+					b.WriteString(cell)
+				} else {
+					b.WriteString(". ")
+				}
+			}
+			trimEnd(&b)
+		}
+	}
+
+	_, err := w.Write(b.Bytes())
+	return err
+}
+
+// trimEnd removes trailing spaces of the last line and ends the line.
+func trimEnd(b *bytes.Buffer) {
+	for b.Len() > 0 && b.Bytes()[b.Len()-1] == ' ' {
+		b.Truncate(b.Len() - 1)
+	}
+	b.WriteByte('\n')
+}
@@ -0,0 +1,110 @@
+package maps
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testASCII = `name: Test
+author: me
+players: 2
+teams: 1 2
+reinforcement: 300=U 1200=T
+rules: MaxSupply=20 SupplySpeed=1.5
+
+terrain:
+B G G F
+ G W . G
+G M G B
+
+units:
+T1. . .
+ . . . .
+. . A2U2
+`
+
+func TestReadASCII(t *testing.T) {
+	f, err := ReadASCII(strings.NewReader("# comment\n" + testASCII))
+	require.NoError(t, err)
+	assert.Equal(t, Meta{Name: "Test", Author: "me", Players: 2, Teams: [][]uint8{{1}, {2}}}, f.Meta)
+	assert.Equal(t, 4, f.Width)
+	assert.Equal(t, 3, f.Height)
+	assert.Equal(t, []string{"BGGF", "GW.G", "GMGB"}, f.Tiles)
+	assert.Equal(t, []Unit{{X: 0, Y: 0, Player: 1, Type: "T"}, {X: 2, Y: 2, Player: 2, Type: "A"}, {X: 3, Y: 2, Player: 2, Type: "U"}}, f.Units)
+	assert.Equal(t, map[uint64]string{300: "U", 1200: "T"}, f.Reinforcement)
+	assert.Equal(t, &core.Rules{MaxSupply: 20, SupplySpeed: 1.5}, f.Rules)
+
+	// write the same text
+	var b bytes.Buffer
+	require.NoError(t, f.WriteASCII(&b))
+	assert.Equal(t, testASCII, b.String())
+
+	// player count from the units
+	f, err = ReadASCII(strings.NewReader("terrain:\nG G\n G G\n\nunits:\nT1T3\n . .\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 2, f.Meta.Players)
+	assert.Empty(t, f.Reinforcement)
+}
+
+func TestReadASCIIInvalid(t *testing.T) {
+	for _, text := range []string{
+		"name Test\n",                                // no key
+		"size: 3\n",                                  // unknown key
+		"rules: Fog=1\nterrain:\nG\n",                // unknown rule
+		"reinforcement: 300\nterrain:\nG\n",          // no pair
+		"teams: 1,x\nterrain:\nG\n",                  // invalid team
+		"terrain:\nGG G\n",                           // two characters
+		"terrain:\nG G\n G\n",                        // width
+		"terrain:\nX G\n",                            // tile type
+		"terrain:\nG G\n\nunits:\nT1\n . .\n . .\n",  // unit rows
+		"terrain:\nG G\n\nunits:\nTx\n",              // player
+		"terrain:\nG G\n\nunits:\n. . T1\n",          // too long
+		"players: 3\nterrain:\nG G\n\nunits:\nT1\n",  // players
+		"terrain:\n G G\n",                           // terrain offset
+		"terrain:\nG G\n\tG G\n",                     // terrain tab
+		"terrain:\nG G\n\nunits:\n T1\n",             // unit offset
+		"terrain:\nG G\nG G\n\nunits:\n. .\n. T2\n",  // no offset
+		"terrain:\nG G\n G G\n\nunits:\n. .\n  T2\n", // two spaces
+	} {
+		_, err := ReadASCII(strings.NewReader(text))
+		assert.ErrorIs(t, err, ErrInvalidMap, text)
+	}
+}
+
+func TestReadASCIILines(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"name: x\n\nterrain:\nG G G\n G G\nG G G\n", "line 5: terrain row 1 has 2 tiles, expected 3"},
+		{"terrain:\nG G\n\tG G\n", "line 3: terrain row 1: tabs are not allowed"},
+		{"terrain:\nG G\n G G\n\nunits:\n . T1\n . .\n", "line 6: unit row 0: wrong indentation"},
+		{"terrain:\nG G\n G G\n\n# units\nunits:\nT1. \n . Tx\n", "line 8: invalid unit \"Tx\" at 1,1"},
+	}
+	for _, tt := range tests {
+		_, err := ReadASCII(strings.NewReader(tt.text))
+		assert.ErrorIs(t, err, ErrInvalidMap, tt.text)
+		assert.ErrorContains(t, err, tt.want, tt.text)
+	}
+}
+
+func TestASCIIShippedMaps(t *testing.T) {
+	for _, name := range []string{"1v1_borderdispute_15x08", "1v1_riverisland_21x13", "5va_test_15x08"} {
+		f, err := Load(name + ".json")
+		require.NoError(t, err)
+
+		// convert to text and back
+		path := filepath.Join(t.TempDir(), name+TextExt)
+		require.NoError(t, f.Save(path))
+		text, err := Load(path)
+		require.NoError(t, err)
+		f.Images = nil // not part of the text format
+		assert.Equal(t, f, text)
+	}
+}
@@ -0,0 +1,301 @@
+package maps
+
+/*
+  This file defines the map file format (version 2). A map file has a metadata block, the starting state
+  (terrain, units and reinforcement) and optional rule overrides:
+
+    {
+      "Version": 2,
+      "Meta": {"Name": "Border Dispute", "Author": "...", "Players": 2, "Teams": [[1], [2]]},
+      "Width": 15,
+      "Height": 8,
+      "Tiles": ["FFGMMGBWGGGGGFF", ...],
+      "Images": ["8b9e9b...", ...],
+      "Units": [{"X": 0, "Y": 4, "Player": 1, "Type": "A"}, ...],
+      "Reinforcement": {"1200": "U", "1800": "T"},
+      "Rules": {"MaxSupply": 20}
+    }
+
+  Old map files (version 1) are a marshalled core.World. They are migrated automatically (see Load).
+*/
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Version is the current version of the map file format.
+const Version = 2
+
+// emptyTile marks a tile without type in File.Tiles (e.g. an unfinished map of the editor).
+const emptyTile = '.'
+
+// ErrInvalidMap is returned for invalid map files.
+var ErrInvalidMap = errors.New("invalid map")
+
+//--------  Struct  --------------------------------------------------------------------------------------------------//
+
+// File is a map file (see Version).
+type File struct {
+	Version       int               // Version of the map file format
+	Meta          Meta              // Metadata of the map
+	Width         int               // Width of the map in tiles
+	Height        int               // Height of the map in tiles
+	Tiles         []string          // Tile types, one string per row (see core.TILES, '.' = no type)
+	Images        []string          `json:",omitempty"` // Image IDs of the GUI, one hex string per row (optional, random if missing)
+	Units         []Unit            // Units at the start of the game
+	Reinforcement map[uint64]string `json:",omitempty"` // Reinforcement (key is iteration, value is unit type)
+	Rules         *core.Rules       `json:",omitempty"` // Optional rule overrides
+}
+
+// Meta is the metadata of a map.
+type Meta struct {
+	Name        string    // Name of the map
+	Author      string    `json:",omitempty"` // Author of the map
+	Description string    `json:",omitempty"` // Description, e.g. the idea of the map
+	Players     int       // Number of players (players with units at the start)
+	Teams       [][]uint8 `json:",omitempty"` // Players of each team (informational, empty = no teams)
+}
+
+// Unit is a unit at the start of the game.
+type Unit struct {
+	X      int    // Column of the tile
+	Y      int    // Row of the tile
+	Player uint8  // Player of the unit (see core.PLAYERS)
+	Type   string // Type of the unit (see core.UNITS)
+}
+
+//--------  Convert  -------------------------------------------------------------------------------------------------//
+
+// FromWorld returns the map file of a world. The player count is taken from the units of the world.
+// Only the starting state is used: the tile types, the image IDs, the type and player of the units,
+// the reinforcement and the rules.
+func FromWorld(world *core.World, meta Meta) *File {
+	f := &File{
+		Version: Version,
+		Meta:    meta,
+		Width:   world.XWidth,
+		Height:  world.YHeight,
+		Tiles:   make([]string, world.YHeight),
+		Images:  make([]string, world.YHeight),
+		Units:   make([]Unit, 0),
+	}
+	f.Meta.Players = world.PlayerCount()
+
+	// tiles and units
+	for y := 0; y < world.YHeight; y++ {
+		row := make([]byte, world.XWidth)
+		images := make([]byte, world.XWidth)
+		for x := 0; x < world.XWidth; x++ {
+			tile := world.Tile(x, y)
+			if tile == nil {
+				row[x] = emptyTile
+				continue
+			}
+			row[x] = tile.Type
+			if tile.Type == 0 {
+				row[x] = emptyTile
+			}
+			images[x] = tile.ImageID
+			if u := tile.Unit; u != nil {
+				f.Units = append(f.Units, Unit{X: x, Y: y, Player: u.Player, Type: string(u.Type)})
+			}
+		}
+		f.Tiles[y] = string(row)
+		f.Images[y] = hex.EncodeToString(images)
+	}
+
+	// reinforcement and rules
+	if len(world.Reinforcement) > 0 {
+		f.Reinforcement = make(map[uint64]string, len(world.Reinforcement))
+		for it, typ := range world.Reinforcement {
+			f.Reinforcement[it] = string(typ)
+		}
+	}
+	if world.Rules != nil {
+		rules := *world.Rules
+		f.Rules = &rules
+	}
+	return f
+}
+
+// World creates a new game world with the starting state of the map.
+// The map must be valid (see Validate).
+func (f *File) World() *core.World {
+	world := core.NewWorld(f.Width, f.Height)
+
+	// tiles
+	for y, row := range f.Tiles {
+		images := f.imageRow(y)
+		for x := 0; x < len(row); x++ {
+			tile := world.Tile(x, y)
+			if tile == nil {
+				continue
+			}
+			if row[x] != emptyTile {
+				tile.Type = row[x]
+			}
+			if len(images) == f.Width {
+				tile.ImageID = images[x]
+			}
+		}
+	}
+
+	// units
+	for _, u := range f.Units {
+		if tile := world.Tile(u.X, u.Y); tile != nil && len(u.Type) == 1 {
+			tile.Unit = core.NewUnit(u.Player, u.Type[0])
+		}
+	}
+
+	// reinforcement and rules
+	if len(f.Reinforcement) > 0 {
+		world.Reinforcement = make(map[uint64]byte, len(f.Reinforcement))
+		for it, typ := range f.Reinforcement {
+			if len(typ) == 1 {
+				world.Reinforcement[it] = typ[0]
+			}
+		}
+	}
+	if f.Rules != nil {
+		rules := *f.Rules
+		world.Rules = &rules
+	}
+	return world
+}
+
+// imageRow returns the image IDs of a row (nil if the map has no valid image IDs for this row).
+func (f *File) imageRow(y int) []byte {
+	if y >= len(f.Images) {
+		return nil
+	}
+	ids, err := hex.DecodeString(f.Images[y])
+	if err != nil {
+		return nil
+	}
+	return ids
+}
+
+// Save writes the map file as indented JSON or in the text format (see TextExt).
+func (f *File) Save(path string) error {
+	var b []byte
+	if filepath.Ext(path) == TextExt {
+		var buf bytes.Buffer
+		if err := f.WriteASCII(&buf); err != nil {
+			return err
+		}
+		b = buf.Bytes()
+	} else {
+		var err error
+		if b, err = json.MarshalIndent(f, "", "  "); err != nil {
+			return err
+		}
+		b = append(b, '\n')
+	}
+	return os.WriteFile(path, b, 0644) // maps are shared assets
+}
+
+//--------  Validate  ------------------------------------------------------------------------------------------------//
+
+// Validate checks the format of the map: size, tile and unit types, positions, players, teams, reinforcement
+// and rules. It returns all problems (see errors.Join) and is used by the loader. The playability of the map
+// is checked by the package function Validate.
+func (f *File) Validate() error {
+	return errors.Join(f.formatProblems()...)
+}
+
+// formatProblems returns all problems of the format (see File.Validate).
+func (f *File) formatProblems() []error {
+	var list []error
+	problem := func(format string, a ...any) {
+		list = append(list, fmt.Errorf("%w: "+format, append([]any{ErrInvalidMap}, a...)...))
+	}
+
+	// version and size
+	if f.Version != Version {
+		problem("version %d (expected %d)", f.Version, Version)
+	}
+	if f.Width <= 0 || f.Height <= 0 {
+		problem("size %dx%d", f.Width, f.Height)
+	}
+
+	// tiles
+	if len(f.Tiles) != f.Height {
+		problem("%d rows for height %d", len(f.Tiles), f.Height)
+	}
+	for y, row := range f.Tiles {
+		if len(row) != f.Width {
+			problem("row %d has %d tiles for width %d", y, len(row), f.Width)
+		}
+		for x := 0; x < len(row); x++ {
+			if row[x] != emptyTile && !validType(core.TILES, row[x]) {
+				problem("unknown tile type %q at %d,%d", row[x], x, y)
+			}
+		}
+	}
+	if len(f.Images) != 0 && len(f.Images) != f.Height {
+		problem("%d image rows for height %d", len(f.Images), f.Height)
+	}
+	for y, row := range f.Images {
+		if ids, err := hex.DecodeString(row); err != nil || len(ids) != f.Width {
+			problem("image row %d is not %d hex image IDs", y, f.Width)
+		}
+	}
+
+	// units
+	players := make(map[uint8]bool)
+	taken := make(map[[2]int]bool)
+	for _, u := range f.Units {
+		switch {
+		case u.X < 0 || u.X >= f.Width || u.Y < 0 || u.Y >= f.Height:
+			problem("unit at %d,%d is outside the map", u.X, u.Y)
+		case taken[[2]int{u.X, u.Y}]:
+			problem("two units at %d,%d", u.X, u.Y)
+		case len(u.Type) != 1 || !validType(core.UNITS, u.Type[0]):
+			problem("unknown unit type %q at %d,%d", u.Type, u.X, u.Y)
+		case !bytes.Contains(core.PLAYERS, []byte{u.Player}):
+			problem("unknown player %d at %d,%d", u.Player, u.X, u.Y)
+		}
+		taken[[2]int{u.X, u.Y}] = true
+		players[u.Player] = true
+	}
+	if f.Meta.Players != len(players) {
+		problem("%d players in the metadata, but units of %d players", f.Meta.Players, len(players))
+	}
+
+	// teams
+	inTeam := make(map[uint8]bool)
+	for _, team := range f.Meta.Teams {
+		for _, player := range team {
+			if !players[player] || inTeam[player] {
+				problem("player %d in teams", player)
+			}
+			inTeam[player] = true
+		}
+	}
+
+	// reinforcement
+	iterations := make([]uint64, 0, len(f.Reinforcement))
+	for it := range f.Reinforcement {
+		iterations = append(iterations, it)
+	}
+	sort.Slice(iterations, func(i, j int) bool { return iterations[i] < iterations[j] })
+	for _, it := range iterations {
+		if typ := f.Reinforcement[it]; len(typ) != 1 || !validType(core.UNITS, typ[0]) {
+			problem("unknown reinforcement %q at iteration %d", typ, it)
+		}
+	}
+
+	// rules
+	if r := f.Rules; r != nil && (r.MaxSupply < 0 || r.SupplySpeed < 0) {
+		problem("negative rules")
+	}
+	return list
+}
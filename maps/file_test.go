@@ -0,0 +1,153 @@
+package maps
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadLegacy(t *testing.T) {
+	for name, players := range map[string]int{
+		"1v1_borderdispute_15x08": 2,
+		"1v1_riverisland_21x13":   2,
+		"5va_test_15x08":          5,
+	} {
+		f, err := Load(name + ".json")
+		require.NoError(t, err)
+		assert.Equal(t, Version, f.Version)
+		assert.Equal(t, name, f.Meta.Name)
+		assert.Equal(t, players, f.Meta.Players)
+
+		// same starting state as the marshalled world
+		world, err := Loader(name + ".json")
+		require.NoError(t, err)
+		assert.Equal(t, players, world.PlayerCount())
+		assert.Len(t, world.Units(0), len(f.Units))
+		for _, tile := range world.TileList(0) {
+			assert.Equal(t, f.Tiles[tile.YRow][tile.XCol], tile.Type)
+			assert.Equal(t, fmt.Sprintf("%02x", tile.ImageID), f.Images[tile.YRow][2*tile.XCol:2*tile.XCol+2])
+		}
+	}
+
+	// details
+	f, err := Load("1v1_borderdispute_15x08.json")
+	require.NoError(t, err)
+	assert.Equal(t, "FFGMMGBWGGGGGFF", f.Tiles[0])
+	assert.Contains(t, f.Units, Unit{X: 0, Y: 4, Player: core.RED, Type: "A"})
+	assert.Equal(t, "U", f.Reinforcement[1200])
+	assert.Nil(t, f.Rules)
+}
+
+func TestMigrateLenient(t *testing.T) {
+	world := core.NewWorld(3, 2)
+	for _, tile := range world.TileList(0) {
+		tile.Type = core.GRASS
+	}
+	world.Tile(0, 0).Type = 'X' // unknown tile type
+	world.Tile(1, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(2, 1).Unit = core.NewUnit(core.RED, 'X') // unknown unit type
+	world.Tile(2, 0).Unit = core.NewUnit(9, core.TANK)  // unknown player
+	world.Reinforcement = map[uint64]byte{100: 'X', 200: core.SOLDIER}
+	world.Tiles[2] = append(world.Tiles[2], &core.Tile{XCol: 2, YRow: 2, Type: core.GRASS}) // outside the map
+	b, err := json.Marshal(world)
+	require.NoError(t, err)
+
+	f, err := Parse(b, "old")
+	require.NoError(t, err)
+	assert.Equal(t, []string{".GG", "GGG"}, f.Tiles)
+	assert.Equal(t, []Unit{{X: 1, Y: 0, Player: core.RED, Type: "T"}}, f.Units)
+	assert.Equal(t, 1, f.Meta.Players)
+	assert.Equal(t, map[uint64]string{200: "U"}, f.Reinforcement)
+	assert.Len(t, f.Images, 2)
+	assert.Len(t, f.Images[0], 6) // two hex digits per tile
+
+	// the current version is strict
+	f.Tiles[0] = "XGG"
+	b, err = json.Marshal(f)
+	require.NoError(t, err)
+	_, err = Parse(b, "new")
+	assert.ErrorIs(t, err, ErrInvalidMap)
+}
+
+func TestFileRoundTrip(t *testing.T) {
+	world := core.NewWorld(4, 3)
+	for _, tile := range world.TileList(0) {
+		tile.Type = core.GRASS
+	}
+	world.Tile(1, 2).Type = 0 // no type
+	world.Tile(0, 0).Type = core.BASE
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(3, 2).Unit = core.NewUnit(core.BLUE, core.SOLDIER)
+	world.Reinforcement = map[uint64]byte{100: core.ARTILLERY}
+	world.Rules = &core.Rules{MaxSupply: 20}
+
+	meta := Meta{Name: "Test", Author: "me", Teams: [][]uint8{{1}, {2}}}
+	path := filepath.Join(t.TempDir(), "test.json")
+	require.NoError(t, FromWorld(world, meta).Save(path))
+
+	f, err := Load(path)
+	require.NoError(t, err)
+	meta.Players = 2
+	assert.Equal(t, meta, f.Meta)
+	assert.Equal(t, []string{"BGGG", "GGGG", "G.GG"}, f.Tiles)
+	assert.Equal(t, map[uint64]string{100: "A"}, f.Reinforcement)
+
+	loaded := f.World()
+	for _, tile := range world.TileList(0) {
+		other := loaded.Tile(tile.XCol, tile.YRow)
+		assert.Equal(t, tile.Type, other.Type)
+		assert.Equal(t, tile.ImageID, other.ImageID)
+		assert.Equal(t, tile.Unit == nil, other.Unit == nil)
+	}
+	assert.Equal(t, byte(core.SOLDIER), loaded.Tile(3, 2).Unit.Type)
+	assert.Equal(t, world.Reinforcement, loaded.Reinforcement)
+	assert.Equal(t, 20, loaded.SupplyRange())
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *File {
+		return &File{Version: Version, Meta: Meta{Name: "x", Players: 2}, Width: 3, Height: 2,
+			Tiles: []string{"GGB", "BGG"},
+			Units: []Unit{{X: 2, Y: 0, Player: 1, Type: "T"}, {X: 0, Y: 1, Player: 2, Type: "T"}}}
+	}
+	require.NoError(t, valid().Validate())
+
+	for _, change := range []func(f *File){
+		func(f *File) { f.Version = 1 },
+		func(f *File) { f.Width = 0 },
+		func(f *File) { f.Tiles = f.Tiles[:1] },
+		func(f *File) { f.Tiles[1] = "BG" },
+		func(f *File) { f.Tiles[1] = "BGX" },
+		func(f *File) { f.Images = []string{"0102"} },
+		func(f *File) { f.Images = []string{"010203", "0102"} },
+		func(f *File) { f.Images = []string{"010203", "01020x"} },
+		func(f *File) { f.Units[0].X = 3 },
+		func(f *File) { f.Units[0].Type = "X" },
+		func(f *File) { f.Units[0].Player = 7 },
+		func(f *File) { f.Units[1].X, f.Units[1].Y = 2, 0 },
+		func(f *File) { f.Meta.Players = 3 },
+		func(f *File) { f.Meta.Teams = [][]uint8{{1, 2}, {2}} },
+		func(f *File) { f.Meta.Teams = [][]uint8{{3}} },
+		func(f *File) { f.Reinforcement = map[uint64]string{100: "TT"} },
+		func(f *File) { f.Rules = &core.Rules{MaxSupply: -1} },
+	} {
+		f := valid()
+		change(f)
+		assert.ErrorIs(t, f.Validate(), ErrInvalidMap)
+	}
+}
+
+func TestParseVersion(t *testing.T) {
+	b, err := json.Marshal(map[string]int{"Version": Version + 1})
+	require.NoError(t, err)
+	_, err = Parse(b, "future")
+	assert.ErrorIs(t, err, ErrInvalidMap)
+
+	_, err = Parse([]byte(`{"XWidth": 2, "YHeight": 1}`), "broken") // version 1 without tiles
+	assert.ErrorIs(t, err, ErrInvalidMap)
+}
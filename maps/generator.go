@@ -0,0 +1,432 @@
+package maps
+
+/*
+  This file provides a procedural map generator. A map is generated from a seed, so the same parameters
+  always return the same map. Fairness comes from symmetry: the terrain, the bases and the start units of
+  player 1 are copied to the other players with a mirror or a point rotation on the hex grid.
+
+  The grid shifts every odd row by half a tile to the right (see core.World.Neighbors). So the point rotation
+  is exact for even heights, and the mirror on the horizontal axis (4 players) requires an odd height. The
+  mirror on the vertical axis leaves the last tile of the odd rows without a counterpart.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"math"
+	"math/rand"
+	"sort"
+)
+
+// ErrInvalidParams is returned by Generate for invalid parameters.
+var ErrInvalidParams = errors.New("invalid generator parameters")
+
+// ErrNoPlayableMap is returned by Generate if no attempt has created a map with reachable bases.
+var ErrNoPlayableMap = errors.New("no playable map found")
+
+// maxAttempts is the number of attempts to generate a map with reachable bases.
+const maxAttempts = 50
+
+// Symmetry is the symmetry type of a generated map.
+type Symmetry int
+
+// symmetry types
+const (
+	SymmetryRotation Symmetry = iota // point rotation by 180 degrees (2 players)
+	SymmetryMirror                   // mirror on the vertical axis (2 players) and also on the horizontal axis (4 players)
+)
+
+// Params are the parameters of the map generator (see Generate).
+type Params struct {
+	Width         int             // Width of the map in tiles
+	Height        int             // Height of the map in tiles
+	Players       int             // Number of players (2 or 4)
+	Symmetry      Symmetry        // Symmetry type (4 players require SymmetryMirror)
+	Terrain       map[byte]int    // Weights of the tile types (see core.TILES, without BASE)
+	Bases         int             // Bases per player; the first one is the start base
+	Units         []byte          // Start units of each player; the first one stands on the start base
+	Reinforcement map[uint64]byte // Reinforcement of the map (see core.World.Reinforcement)
+	Seed          int64           // Seed of the random generator
+}
+
+// DefaultParams are the default parameters of the map generator.
+var DefaultParams = Params{
+	Width:    21,
+	Height:   12,
+	Players:  2,
+	Symmetry: SymmetryRotation,
+	Terrain: map[byte]int{
+		core.GRASS:     60,
+		core.FOREST:    14,
+		core.DIRT:      6,
+		core.HILL:      6,
+		core.MOUNTAIN:  5,
+		core.WATER:     5,
+		core.HOLE:      2,
+		core.STRUCTURE: 2,
+	},
+	Bases: 2,
+	Units: []byte{core.ARTILLERY, core.TANK, core.SOLDIER},
+	Reinforcement: map[uint64]byte{
+		1800: core.SOLDIER,
+		3600: core.TANK,
+		5400: core.ARTILLERY,
+	},
+}
+
+// Generate returns a new symmetric map with the given parameters.
+// The map is ready to play: every player has a start base with the start units and all bases
+// can be reached by tanks. The same parameters (incl. the seed) always return the same map.
+func Generate(p Params) (*core.World, error) {
+	if err := p.check(); err != nil {
+		return nil, err
+	}
+
+	g := &generator{
+		p:     p,
+		r:     rand.New(rand.NewSource(p.Seed)),
+		world: core.NewWorld(p.Width, p.Height),
+	}
+	g.initOrbits()
+
+	// try until all bases are reachable
+	for i := 0; i < maxAttempts; i++ {
+		if g.attempt() {
+			g.finish()
+			return g.world, nil
+		}
+	}
+	return nil, ErrNoPlayableMap
+}
+
+// check validates the parameters.
+func (p Params) check() error {
+	switch {
+	case p.Width < 6 || p.Height < 4 || p.Width > math.MaxUint8 || p.Height > math.MaxUint8:
+		return fmt.Errorf("%w: size must be between 6x4 and 255x255", ErrInvalidParams)
+	case p.Players != 2 && p.Players != 4:
+		return fmt.Errorf("%w: %d players (2 or 4)", ErrInvalidParams, p.Players)
+	case p.Symmetry != SymmetryRotation && p.Symmetry != SymmetryMirror:
+		return fmt.Errorf("%w: unknown symmetry %d", ErrInvalidParams, p.Symmetry)
+	case p.Players == 4 && p.Symmetry != SymmetryMirror:
+		return fmt.Errorf("%w: 4 players require the mirror symmetry", ErrInvalidParams)
+	case p.Players == 4 && p.Height%2 == 0:
+		return fmt.Errorf("%w: 4 players require an odd height", ErrInvalidParams)
+	case p.Bases < 1:
+		return fmt.Errorf("%w: at least one base per player", ErrInvalidParams)
+	case len(p.Units) < 1 || len(p.Units) > 7:
+		return fmt.Errorf("%w: 1 to 7 start units per player", ErrInvalidParams)
+	}
+
+	// terrain
+	total := 0
+	for typ, weight := range p.Terrain {
+		if typ == core.BASE || !validType(core.TILES, typ) || weight < 0 {
+			return fmt.Errorf("%w: terrain %q=%d", ErrInvalidParams, typ, weight)
+		}
+		total += weight
+	}
+	if total <= 0 {
+		return fmt.Errorf("%w: no terrain weights", ErrInvalidParams)
+	}
+
+	// units
+	for _, typ := range p.Units {
+		if !validType(core.UNITS, typ) {
+			return fmt.Errorf("%w: unit %q", ErrInvalidParams, typ)
+		}
+	}
+	for it, typ := range p.Reinforcement {
+		if !validType(core.UNITS, typ) {
+			return fmt.Errorf("%w: reinforcement %d=%q", ErrInvalidParams, it, typ)
+		}
+	}
+	return nil
+}
+
+// validType reports whether typ is in the list (see core.TILES and core.UNITS).
+func validType(list []byte, typ byte) bool {
+	for _, t := range list {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+//--------  Generator  -----------------------------------------------------------------------------------------------//
+
+// orbit is a tile of player 1 and its counterparts (index = player - 1, nil = no counterpart).
+// All tiles of an orbit always get the same type.
+type orbit []*core.Tile
+
+// generator holds the state of Generate.
+type generator struct {
+	p     Params
+	r     *rand.Rand
+	world *core.World
+
+	orbits  []orbit     // all orbits of the map
+	orbitOf map[int]int // orbit index of each tile (key: x*Height+y)
+
+	types []byte // type of each orbit
+	units []int  // orbits with start units (same order as Params.Units)
+}
+
+// initOrbits groups all tiles into orbits.
+func (g *generator) initOrbits() {
+	g.orbitOf = make(map[int]int)
+	for x := 0; x < g.p.Width; x++ {
+		for y := 0; y < g.p.Height; y++ {
+			if _, ok := g.orbitOf[g.key(x, y)]; ok {
+				continue // tile is a counterpart of an earlier tile
+			}
+
+			o := make(orbit, g.p.Players)
+			for i := range o {
+				o[i] = g.counterpart(x, y, i)
+				if o[i] != nil {
+					g.orbitOf[g.key(o[i].XCol, o[i].YRow)] = len(g.orbits)
+				}
+			}
+			g.orbits = append(g.orbits, o)
+		}
+	}
+}
+
+// key returns the key of a tile (see orbitOf).
+func (g *generator) key(x, y int) int {
+	return x*g.p.Height + y
+}
+
+// counterpart returns the tile of the player (index = player - 1) for a tile of player 1.
+func (g *generator) counterpart(x, y, player int) *core.Tile {
+	return g.world.Tile(counterpart(g.p.Width, g.p.Height, x, y, g.p.Symmetry, player))
+}
+
+// counterpart returns the position of the player (index = player - 1) for a position of player 1 on a map
+// with the given size and symmetry. Odd rows are shifted by half a tile, so the mirror on the vertical axis
+// maps odd rows by one tile less. The position can be outside the map (no counterpart).
+func counterpart(w, h, x, y int, symmetry Symmetry, player int) (int, int) {
+	mirrorX := func(x, y int) (int, int) { return w - 1 - x - y%2, y }
+	mirrorY := func(x, y int) (int, int) { return x, h - 1 - y } // exact for odd heights
+	rotate := func(x, y int) (int, int) {
+		if h%2 == 0 {
+			return w - 1 - x, h - 1 - y
+		}
+		return mirrorY(mirrorX(x, y))
+	}
+
+	switch {
+	case player == 0:
+		// identity
+	case player == 1 && symmetry == SymmetryRotation:
+		x, y = rotate(x, y)
+	case player == 1:
+		x, y = mirrorX(x, y)
+	case player == 2:
+		x, y = rotate(x, y) // opposite player
+	case player == 3:
+		x, y = mirrorY(x, y)
+	}
+	return x, y
+}
+
+// full reports whether the orbit has a distinct tile for each player.
+func (o orbit) full() bool {
+	known := make(map[*core.Tile]bool)
+	for _, t := range o {
+		if t == nil || known[t] {
+			return false
+		}
+		known[t] = true
+	}
+	return true
+}
+
+// attempt generates the terrain, the bases and the start units and reports whether all bases are reachable.
+func (g *generator) attempt() bool {
+	g.types = make([]byte, len(g.orbits))
+	g.units = nil
+	g.terrain()
+
+	// start base in the home region of player 1
+	home := make([]int, 0)
+	for i, o := range g.orbits {
+		if o.full() && o[0].XCol <= g.p.Width/4 && (g.p.Players == 2 || o[0].YRow <= g.p.Height/4) {
+			home = append(home, i)
+		}
+	}
+	if len(home) == 0 {
+		return false
+	}
+	start := home[g.r.Intn(len(home))]
+	g.types[start] = core.BASE
+	g.units = append(g.units, start)
+
+	// start units around the start base
+	for _, typ := range g.p.Units[1:] {
+		i := g.nearFree(g.orbits[start][0])
+		if i < 0 {
+			return false
+		}
+		if typ != core.SOLDIER && !passable(g.types[i]) {
+			g.types[i] = core.GRASS // tanks and artillery can't stand here
+		}
+		g.units = append(g.units, i)
+	}
+
+	// further bases
+	for n := 1; n < g.p.Bases; n++ {
+		free := make([]int, 0)
+		for i, o := range g.orbits {
+			if o.full() && g.types[i] != core.BASE && !g.hasUnit(i) {
+				free = append(free, i)
+			}
+		}
+		if len(free) == 0 {
+			return false
+		}
+		g.types[free[g.r.Intn(len(free))]] = core.BASE
+	}
+
+	// apply and check paths from the start base to all bases
+	g.apply()
+	from := g.orbits[start][0]
+	for _, base := range g.world.TileList(core.BASE) {
+		if base != from && core.FindPath(g.world, core.TANK, from, base) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// terrain sets the types of all orbits by the weights of Params.Terrain.
+// Every type except the most frequent one (ground) is placed as small random clusters.
+func (g *generator) terrain() {
+
+	// types in a stable order (see core.TILES)
+	var ground byte
+	types := make([]byte, 0, len(core.TILES))
+	total := 0
+	for _, typ := range core.TILES {
+		weight := g.p.Terrain[typ]
+		if weight <= 0 {
+			continue
+		}
+		types = append(types, typ)
+		total += weight
+		if weight > g.p.Terrain[ground] {
+			ground = typ
+		}
+	}
+
+	// clusters
+	for _, typ := range types {
+		if typ == ground {
+			continue
+		}
+		n := len(g.orbits) * g.p.Terrain[typ] / total
+		for n > 0 {
+			free := make([]int, 0)
+			for i, t := range g.types {
+				if t == 0 {
+					free = append(free, i)
+				}
+			}
+			if len(free) == 0 {
+				return
+			}
+
+			// random walk from a free orbit
+			cur := free[g.r.Intn(len(free))]
+			for size := 1 + g.r.Intn(4); size > 0 && n > 0; size-- {
+				g.types[cur] = typ
+				n--
+
+				next := make([]int, 0, 6)
+				for _, t := range g.world.Neighbors(g.orbits[cur][0]) {
+					if i := g.orbitOf[g.key(t.XCol, t.YRow)]; g.types[i] == 0 {
+						next = append(next, i)
+					}
+				}
+				if len(next) == 0 {
+					break
+				}
+				cur = next[g.r.Intn(len(next))]
+			}
+		}
+	}
+
+	// ground
+	for i, t := range g.types {
+		if t == 0 {
+			g.types[i] = ground
+		}
+	}
+}
+
+// nearFree returns the nearest free orbit around a tile of player 1 (-1 = none).
+// A free orbit is full and has no base and no start unit.
+func (g *generator) nearFree(tile *core.Tile) int {
+	for _, ring := range g.world.ExtNeighbors(tile, 3) {
+
+		// stable order, then shuffle
+		sort.Slice(ring, func(i, j int) bool { return g.key(ring[i].XCol, ring[i].YRow) < g.key(ring[j].XCol, ring[j].YRow) })
+		g.r.Shuffle(len(ring), func(i, j int) { ring[i], ring[j] = ring[j], ring[i] })
+
+		for _, t := range ring {
+			i := g.orbitOf[g.key(t.XCol, t.YRow)]
+			if g.orbits[i].full() && g.types[i] != core.BASE && !g.hasUnit(i) {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// hasUnit reports whether the orbit has start units.
+func (g *generator) hasUnit(i int) bool {
+	for _, u := range g.units {
+		if u == i {
+			return true
+		}
+	}
+	return false
+}
+
+// passable reports whether tanks and artillery can stand on the tile type.
+func passable(typ byte) bool {
+	return typ != core.MOUNTAIN && typ != core.STRUCTURE && typ != core.WATER
+}
+
+// apply sets the types of all tiles.
+func (g *generator) apply() {
+	for i, o := range g.orbits {
+		for _, t := range o {
+			if t != nil {
+				t.Type = g.types[i]
+			}
+		}
+	}
+}
+
+// finish places the start units and sets the random values of the map (image IDs, unit IDs and reinforcement).
+func (g *generator) finish() {
+	for _, t := range g.world.TileList(0) {
+		t.ImageID = uint8(g.r.Intn(math.MaxUint8))
+	}
+	for n, i := range g.units {
+		for player, t := range g.orbits[i] {
+			t.Unit = core.NewUnit(uint8(player+1), g.p.Units[n])
+			t.Unit.ID = g.r.Int() // reproducible
+		}
+	}
+	if len(g.p.Reinforcement) > 0 {
+		g.world.Reinforcement = make(map[uint64]byte, len(g.p.Reinforcement))
+		for it, typ := range g.p.Reinforcement {
+			g.world.Reinforcement[it] = typ
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package maps
+
+import (
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerate(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		p := DefaultParams
+		p.Seed = seed
+		world, err := Generate(p)
+		require.NoError(t, err)
+
+		// ready to play
+		assert.Equal(t, 2, world.PlayerCount())
+		assert.Len(t, world.TileList(core.BASE), 2*p.Bases)
+		assert.Len(t, world.Units(core.RED), len(p.Units))
+		assert.Len(t, world.Units(core.BLUE), len(p.Units))
+		assert.Equal(t, p.Reinforcement, world.Reinforcement)
+		for _, tile := range world.TileList(0) {
+			assert.NotZero(t, tile.Type)
+		}
+
+		// point rotation
+		for _, tile := range world.TileList(0) {
+			other := world.Tile(p.Width-1-tile.XCol, p.Height-1-tile.YRow)
+			require.Equal(t, tile.Type, other.Type)
+			if tile.Unit != nil {
+				require.NotNil(t, other.Unit)
+				assert.Equal(t, tile.Unit.Type, other.Unit.Type)
+				assert.NotEqual(t, tile.Unit.Player, other.Unit.Player)
+			}
+		}
+	}
+}
+
+func TestGenerateSeed(t *testing.T) {
+	p := DefaultParams
+	p.Seed = 42
+	a, err := Generate(p)
+	require.NoError(t, err)
+	b, err := Generate(p)
+	require.NoError(t, err)
+	assert.Equal(t, a.Json(), b.Json())
+
+	p.Seed = 43
+	c, err := Generate(p)
+	require.NoError(t, err)
+	assert.NotEqual(t, a.Json(), c.Json())
+}
+
+func TestGenerateMirror(t *testing.T) {
+	p := DefaultParams
+	p.Width, p.Height, p.Players, p.Symmetry, p.Bases = 20, 13, 4, SymmetryMirror, 3
+	p.Units = []byte{core.TANK, core.TANK}
+	world, err := Generate(p)
+	require.NoError(t, err)
+	assert.Equal(t, 4, world.PlayerCount())
+	assert.Len(t, world.TileList(core.BASE), 12)
+
+	for _, tile := range world.TileList(0) {
+		x, y := tile.XCol, tile.YRow
+		if other := world.Tile(p.Width-1-x-y%2, y); other != nil {
+			require.Equal(t, tile.Type, other.Type) // vertical axis
+		}
+		require.Equal(t, tile.Type, world.Tile(x, p.Height-1-y).Type) // horizontal axis
+	}
+}
+
+func TestGenerateInvalid(t *testing.T) {
+	for _, change := range []func(p *Params){
+		func(p *Params) { p.Width = 3 },
+		func(p *Params) { p.Players = 3 },
+		func(p *Params) { p.Symmetry = 7 },
+		func(p *Params) { p.Players = 4 },                             // rotation
+		func(p *Params) { p.Players, p.Symmetry = 4, SymmetryMirror }, // even height
+		func(p *Params) { p.Bases = 0 },
+		func(p *Params) { p.Units = nil },
+		func(p *Params) { p.Units = []byte{'X'} },
+		func(p *Params) { p.Terrain = map[byte]int{core.BASE: 5} },
+		func(p *Params) { p.Terrain = map[byte]int{core.GRASS: 0} },
+		func(p *Params) { p.Reinforcement = map[uint64]byte{100: core.GRASS} },
+	} {
+		p := DefaultParams
+		change(&p)
+		_, err := Generate(p)
+		assert.ErrorIs(t, err, ErrInvalidParams)
+	}
+
+	// impassable terrain
+	p := DefaultParams
+	p.Terrain = map[byte]int{core.WATER: 1}
+	_, err := Generate(p)
+	assert.ErrorIs(t, err, ErrNoPlayableMap)
+}
@@ -1,57 +1,130 @@
 package maps
 
+/*
+  This file loads map files in JSON or in the text format. Old versions of the JSON format are migrated
+  automatically (see File). The migration is lenient like the old loader: tiles outside the size of the map
+  are ignored, unknown tile types become '.' and units or reinforcement of unknown types are removed.
+*/
+
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"github.com/SchnorcherSepp/TankWars2/core"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
-// Loader is a function that loads a game world from a JSON file located at the specified path.
-// It reads the JSON data, parses it into a core.World structure, and creates a new world with
-// the specified dimensions. The function populates the new world's tiles and their attributes,
-// including tile types and associated unit information.
-func Loader(path string) (*core.World, error) {
+//--------  Loader  --------------------------------------------------------------------------------------------------//
 
-	// Read JSON data from the file
+// Load reads a map file in JSON or in the text format (see TextExt). Old versions are migrated,
+// the map name defaults to the file name.
+func Load(path string) (*File, error) {
 	b, err := os.ReadFile(path)
 	if err != nil {
 		return nil, err
 	}
+	name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+
+	// text format
+	if filepath.Ext(path) == TextExt {
+		f, err := ReadASCII(bytes.NewReader(b))
+		if f != nil && f.Meta.Name == "" {
+			f.Meta.Name = name
+		}
+		return f, err
+	}
+	return Parse(b, name)
+}
+
+// Parse parses and validates a map file. Old versions are migrated; name is used for maps without a name.
+func Parse(b []byte, name string) (*File, error) {
+
+	// check version
+	var head struct{ Version int }
+	if err := json.Unmarshal(b, &head); err != nil {
+		return nil, err
+	}
+
+	var f *File
+	switch head.Version {
+	case 0, 1:
+		// version 1: marshalled core.World
+		var err error
+		if f, err = migrateV1(b); err != nil {
+			return nil, err
+		}
+	case Version:
+		f = &File{}
+		if err := json.Unmarshal(b, f); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrInvalidMap, head.Version)
+	}
 
-	// Parse the JSON data into a loadable world structure
+	if f.Meta.Name == "" {
+		f.Meta.Name = name
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// migrateV1 converts a marshalled core.World (version 1) to a map file like the old loader:
+// tiles outside the size are ignored, unknown tile types become '.' and units or reinforcement of
+// unknown types or players are removed.
+func migrateV1(b []byte) (*File, error) {
 	load := core.World{}
 	if err := json.Unmarshal(b, &load); err != nil {
 		return nil, err
 	}
+	if load.XWidth <= 0 || load.YHeight <= 0 {
+		return nil, fmt.Errorf("%w: size %dx%d", ErrInvalidMap, load.XWidth, load.YHeight)
+	}
+	if len(load.Tiles) == 0 {
+		return nil, fmt.Errorf("%w: no tiles", ErrInvalidMap)
+	}
 
-	// Create a new world based on the loaded dimensions
+	// copy the starting state
 	world := core.NewWorld(load.XWidth, load.YHeight)
-	world.Reinforcement = load.Reinforcement
-
-	// Read and populate relevant attributes for each tile in the loaded world
 	for x := range load.Tiles {
 		for y := range load.Tiles[x] {
-
-			// Get tiles from the loaded and new worlds
-			lTile := load.Tile(x, y)  // Tile from the loaded world
-			wTile := world.Tile(x, y) // Tile from the new world
+			lTile, wTile := load.Tiles[x][y], world.Tile(x, y)
 			if lTile == nil || wTile == nil {
-				continue // Skip if either tile is invalid
+				continue // outside the map
 			}
-
-			// Set tile attributes
-			wTile.Type = lTile.Type       // Tile type
-			wTile.ImageID = lTile.ImageID // Tile ID for GUI images
-
-			// Set unit if present in the loaded tile
-			lUnit := lTile.Unit
-			if lUnit != nil {
-				// Create a new unit for the new world
-				wTile.Unit = core.NewUnit(lUnit.Player, lUnit.Type)
+			if validType(core.TILES, lTile.Type) {
+				wTile.Type = lTile.Type
+			}
+			wTile.ImageID = lTile.ImageID
+			if u := lTile.Unit; u != nil && validType(core.UNITS, u.Type) && bytes.Contains(core.PLAYERS, []byte{u.Player}) {
+				wTile.Unit = core.NewUnit(u.Player, u.Type)
 			}
 		}
 	}
+	for it, typ := range load.Reinforcement {
+		if validType(core.UNITS, typ) {
+			if world.Reinforcement == nil {
+				world.Reinforcement = make(map[uint64]byte)
+			}
+			world.Reinforcement[it] = typ
+		}
+	}
+	world.Rules = load.Rules
+
+	f := FromWorld(world, Meta{})
+	f.Meta.Players = countPlayers(f.Units)
+	return f, nil
+}
 
-	// Return the loaded world, the count of unique players, and any error
-	return world, nil
+// Loader loads a game world from a map file (see Load and File.World).
+func Loader(path string) (*core.World, error) {
+	f, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return f.World(), nil
 }
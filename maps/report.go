@@ -0,0 +1,341 @@
+package maps
+
+/*
+  This file checks the playability of maps and creates a fairness report. Validate returns all problems of a map,
+  e.g. a tank on water or a map without bases. Analyze compares the players: the path length from the start of
+  each player to every base for each unit type, the supply coverage, the chokepoints between the players and the
+  symmetry of the map. Paths ignore units, because they move during the game.
+*/
+
+import (
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"sort"
+	"strings"
+)
+
+// unitNames are the names of the unit types in problems.
+var unitNames = map[byte]string{core.ARTILLERY: "artillery", core.SOLDIER: "soldier", core.TANK: "tank"}
+
+//--------  Validate  ------------------------------------------------------------------------------------------------//
+
+// Validate checks the format (see File.Validate) and the playability of a map and returns all problems
+// (nil = no problems). A playable map has at least two players with the IDs 1 to n, at least one base,
+// a type for every tile and all units on tiles they can stand on.
+func Validate(f *File) []error {
+	list := f.formatProblems()
+	if !f.validSize() {
+		return list // the tiles can't be checked
+	}
+	problem := func(format string, a ...any) {
+		list = append(list, fmt.Errorf("%w: "+format, append([]any{ErrInvalidMap}, a...)...))
+	}
+
+	// players
+	players := make(map[uint8]bool)
+	var last uint8
+	for _, u := range f.Units {
+		players[u.Player] = true
+		if u.Player > last {
+			last = u.Player
+		}
+	}
+	if len(players) < 2 {
+		problem("less than two players")
+	}
+	for p := uint8(1); p < last; p++ {
+		if !players[p] {
+			problem("player %d has no units", p)
+		}
+	}
+
+	// tiles
+	bases, empty := 0, 0
+	for _, row := range f.Tiles {
+		bases += strings.Count(row, string(rune(core.BASE)))
+		empty += strings.Count(row, string(emptyTile))
+	}
+	if bases == 0 {
+		problem("no base")
+	}
+	if empty > 0 {
+		problem("%d tiles have no type", empty)
+	}
+
+	// units
+	for _, u := range f.Units {
+		if u.X < 0 || u.X >= f.Width || u.Y < 0 || u.Y >= f.Height || len(u.Type) != 1 {
+			continue // see formatProblems
+		}
+		if typ := f.Tiles[u.Y][u.X]; !canStand(u.Type[0], typ) {
+			problem("%s of player %d at %d,%d can't stand on %q", unitNames[u.Type[0]], u.Player, u.X, u.Y, typ)
+		}
+	}
+	return list
+}
+
+// validSize reports whether the size matches the tiles.
+func (f *File) validSize() bool {
+	if f.Width <= 0 || len(f.Tiles) != f.Height {
+		return false
+	}
+	for _, row := range f.Tiles {
+		if len(row) != f.Width {
+			return false
+		}
+	}
+	return true
+}
+
+// canStand reports whether a unit type can stand on a tile type (see core.World.Move).
+func canStand(unitType, tileType byte) bool {
+	if tileType == emptyTile || tileType == 0 {
+		return false
+	}
+	return unitType == core.SOLDIER || passable(tileType)
+}
+
+//--------  Report  --------------------------------------------------------------------------------------------------//
+
+// Report is the fairness report of a map (see Analyze).
+type Report struct {
+	Symmetry      string         // Best symmetry type (rotation or mirror)
+	SymmetryScore float64        // Share of the tiles that match their counterpart incl. units (1 = symmetric)
+	Bases         [][2]int       // Positions of all bases (row by row)
+	Players       []PlayerReport // Report of each player
+	Chokepoints   [][2]int       // Tiles on every tank path between the starts of two players
+	Tiles         int            // Number of tiles
+}
+
+// PlayerReport is the part of a player in the fairness report.
+type PlayerReport struct {
+	Player   uint8            // Player ID
+	Start    [2]int           // Start base (base with a unit of the player), else the first unit
+	Distance map[string][]int // Path length from the start to each base (see Report.Bases) by unit type (-1 = unreachable)
+	Supply   int              // Tiles in the supply range of the start bases
+}
+
+// Analyze creates the fairness report of a map. The map must have a valid format (see File.Validate).
+func Analyze(f *File) (*Report, error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	world := f.World()
+	r := &Report{Tiles: f.Width * f.Height}
+
+	// bases
+	for y, row := range f.Tiles {
+		for x := 0; x < len(row); x++ {
+			if row[x] == core.BASE {
+				r.Bases = append(r.Bases, [2]int{x, y})
+			}
+		}
+	}
+
+	// players
+	starts := make([]*core.Tile, 0)
+	for _, p := range f.players() {
+		pr := PlayerReport{Player: p, Distance: make(map[string][]int)}
+		start, bases := f.start(world, p)
+		pr.Start = [2]int{start.XCol, start.YRow}
+		starts = append(starts, start)
+
+		// distances
+		for _, typ := range core.UNITS {
+			dist := distances(world, start, typ, nil)
+			list := make([]int, len(r.Bases))
+			for i, b := range r.Bases {
+				list[i] = -1
+				if d, ok := dist[world.Tile(b[0], b[1])]; ok {
+					list[i] = d
+				}
+			}
+			pr.Distance[string(typ)] = list
+		}
+
+		// supply
+		supplied := make(map[*core.Tile]bool)
+		for _, base := range bases {
+			supplied[base] = true
+			for _, ring := range world.ExtNeighbors(base, world.SupplyRange()) {
+				for _, t := range ring {
+					supplied[t] = true
+				}
+			}
+		}
+		pr.Supply = len(supplied)
+		r.Players = append(r.Players, pr)
+	}
+
+	// chokepoints and symmetry
+	r.Chokepoints = chokepoints(world, starts)
+	r.Symmetry, r.SymmetryScore = "rotation", f.symmetry(SymmetryRotation)
+	if score := f.symmetry(SymmetryMirror); score > r.SymmetryScore {
+		r.Symmetry, r.SymmetryScore = "mirror", score
+	}
+	return r, nil
+}
+
+// players returns the IDs of all players with units (sorted).
+func (f *File) players() []uint8 {
+	known := make(map[uint8]bool)
+	list := make([]uint8, 0)
+	for _, u := range f.Units {
+		if !known[u.Player] {
+			known[u.Player] = true
+			list = append(list, u.Player)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i] < list[j] })
+	return list
+}
+
+// start returns the start tile and the start bases of the player (bases with a unit of the player).
+// Without a start base, the tile of the first unit is the start.
+func (f *File) start(world *core.World, player uint8) (*core.Tile, []*core.Tile) {
+	var first *core.Tile
+	var bases []*core.Tile
+	for _, u := range f.Units {
+		if u.Player != player {
+			continue
+		}
+		tile := world.Tile(u.X, u.Y)
+		if first == nil {
+			first = tile
+		}
+		if tile.Type == core.BASE {
+			bases = append(bases, tile)
+		}
+	}
+	sort.Slice(bases, func(i, j int) bool {
+		return bases[i].YRow < bases[j].YRow || bases[i].YRow == bases[j].YRow && bases[i].XCol < bases[j].XCol
+	})
+	if len(bases) > 0 {
+		return bases[0], bases
+	}
+	return first, nil
+}
+
+// distances returns the path length from the start to all tiles the unit type can reach (breadth-first search).
+// The blocked tile is never entered.
+func distances(world *core.World, start *core.Tile, unitType byte, blocked *core.Tile) map[*core.Tile]int {
+	dist := map[*core.Tile]int{start: 0}
+	open := []*core.Tile{start}
+	for len(open) > 0 {
+		tile := open[0]
+		open = open[1:]
+		for _, n := range world.Neighbors(tile) {
+			if _, ok := dist[n]; ok || n == blocked || !canStand(unitType, n.Type) {
+				continue
+			}
+			dist[n] = dist[tile] + 1
+			open = append(open, n)
+		}
+	}
+	return dist
+}
+
+// chokepoints returns the tiles on every tank path between the starts of two players (row by row).
+// Such a tile must be on every shortest path, so only these tiles are blocked and tested.
+func chokepoints(world *core.World, starts []*core.Tile) [][2]int {
+	found := make(map[*core.Tile]bool)
+	for i, a := range starts {
+		from := distances(world, a, core.TANK, nil)
+		for _, b := range starts[i+1:] {
+			length, ok := from[b]
+			if !ok {
+				continue // no tank path
+			}
+			to := distances(world, b, core.TANK, nil)
+			for tile, d := range from {
+				if tile == a || tile == b || found[tile] || d+to[tile] != length {
+					continue
+				}
+				if _, ok := to[tile]; !ok {
+					continue
+				}
+				if _, ok := distances(world, a, core.TANK, tile)[b]; !ok {
+					found[tile] = true
+				}
+			}
+		}
+	}
+
+	list := make([][2]int, 0, len(found))
+	for tile := range found {
+		list = append(list, [2]int{tile.XCol, tile.YRow})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i][1] < list[j][1] || list[i][1] == list[j][1] && list[i][0] < list[j][0]
+	})
+	return list
+}
+
+// symmetry returns the share of the tiles that match their counterpart (tile type and unit type of another player).
+// Tiles without counterpart are ignored.
+func (f *File) symmetry(s Symmetry) float64 {
+	units := make(map[[2]int]Unit, len(f.Units))
+	for _, u := range f.Units {
+		units[[2]int{u.X, u.Y}] = u
+	}
+
+	match, total := 0, 0
+	for y, row := range f.Tiles {
+		for x := 0; x < len(row); x++ {
+			cx, cy := counterpart(f.Width, f.Height, x, y, s, 1)
+			if cx < 0 || cx >= f.Width || cy < 0 || cy >= f.Height {
+				continue // no counterpart
+			}
+			total++
+
+			u, hasUnit := units[[2]int{x, y}]
+			v, hasOther := units[[2]int{cx, cy}]
+			switch {
+			case row[x] != f.Tiles[cy][cx] || hasUnit != hasOther:
+			case hasUnit && (u.Type != v.Type || u.Player == v.Player && (x != cx || y != cy)):
+			default:
+				match++
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(match) / float64(total)
+}
+
+// String returns the report as text (see validate mode and the editor).
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "symmetry: %s (%.0f%%)\n", r.Symmetry, 100*r.SymmetryScore)
+	fmt.Fprintf(&b, "bases: %s\n", positions(r.Bases))
+	for _, p := range r.Players {
+		fmt.Fprintf(&b, "player %d: start %d,%d, supply %d of %d tiles (%.0f%%)\n",
+			p.Player, p.Start[0], p.Start[1], p.Supply, r.Tiles, 100*float64(p.Supply)/float64(r.Tiles))
+		for _, typ := range core.UNITS {
+			list := make([]string, 0, len(r.Bases))
+			for _, d := range p.Distance[string(typ)] {
+				if d < 0 {
+					list = append(list, "-")
+				} else {
+					list = append(list, fmt.Sprint(d))
+				}
+			}
+			fmt.Fprintf(&b, "  %s distance to bases: %s\n", unitNames[typ], strings.Join(list, " "))
+		}
+	}
+	fmt.Fprintf(&b, "chokepoints: %s\n", positions(r.Chokepoints))
+	return b.String()
+}
+
+// positions returns a list of positions as text (e.g. '1,2 5,3').
+func positions(list [][2]int) string {
+	if len(list) == 0 {
+		return "none"
+	}
+	s := make([]string, 0, len(list))
+	for _, p := range list {
+		s = append(s, fmt.Sprintf("%d,%d", p[0], p[1]))
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,109 @@
+package maps
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// readTest reads a map in the text format.
+func readTest(t *testing.T, text string) *File {
+	f, err := ReadASCII(strings.NewReader(text))
+	require.NoError(t, err)
+	return f
+}
+
+func TestValidatePlayable(t *testing.T) {
+	f := readTest(t, "terrain:\nB G W G B\n G G W G G\n\nunits:\nT1. . . T2\n . . . . .\n")
+	assert.Empty(t, Validate(f))
+
+	// all problems
+	f = readTest(t, "terrain:\nG G W . G\n G G W G G\n\nunits:\nT1. T3. .\n . . . . .\n")
+	assert.Equal(t, []string{
+		"invalid map: player 2 has no units",
+		"invalid map: no base",
+		"invalid map: 1 tiles have no type",
+		"invalid map: tank of player 3 at 2,0 can't stand on 'W'",
+	}, errorTexts(Validate(f)))
+
+	// format problems
+	f = readTest(t, "terrain:\nB G\n\nunits:\nT1\n")
+	f.Tiles[0] = "BGX"
+	f.Units = append(f.Units, Unit{X: 0, Y: 0, Player: 2, Type: "U"})
+	assert.Equal(t, []string{
+		"invalid map: row 0 has 3 tiles for width 2",
+		"invalid map: unknown tile type 'X' at 2,0",
+		"invalid map: two units at 0,0",
+		"invalid map: 1 players in the metadata, but units of 2 players",
+	}, errorTexts(Validate(f)))
+}
+
+func TestAnalyze(t *testing.T) {
+	f := readTest(t, `
+terrain:
+B G W G B
+ G G W G G
+G G D G G
+ G G W G B
+
+units:
+T1. . . T2
+ . . . . .
+. . . . .
+ . . . . U2
+`)
+	r, err := Analyze(f)
+	require.NoError(t, err)
+	assert.Equal(t, [][2]int{{0, 0}, {4, 0}, {4, 3}}, r.Bases)
+	assert.Equal(t, [][2]int{{2, 2}, {3, 2}}, r.Chokepoints) // the only bridge and its only exit
+	require.Len(t, r.Players, 2)
+
+	p := r.Players[1]
+	assert.Equal(t, uint8(2), p.Player)
+	assert.Equal(t, [2]int{4, 0}, p.Start) // first start base
+	assert.Equal(t, []int{4, 0, 3}, p.Distance["U"])
+	assert.Equal(t, []int{6, 0, 3}, p.Distance["T"]) // over the bridge
+	assert.Equal(t, r.Tiles, p.Supply)
+	assert.Less(t, r.SymmetryScore, 1.0)
+	assert.Contains(t, r.String(), "chokepoints: 2,2 3,2\n")
+
+	// no tank path
+	f.Tiles[2] = "GGWGG"
+	r, err = Analyze(f)
+	require.NoError(t, err)
+	assert.Empty(t, r.Chokepoints)
+	assert.Equal(t, -1, r.Players[0].Distance["T"][1])
+
+	// generated maps are symmetric
+	world, err := Generate(DefaultParams)
+	require.NoError(t, err)
+	r, err = Analyze(FromWorld(world, Meta{}))
+	require.NoError(t, err)
+	assert.Equal(t, "rotation", r.Symmetry)
+	assert.Equal(t, 1.0, r.SymmetryScore)
+	assert.Equal(t, r.Players[0].Supply, r.Players[1].Supply)
+
+	// invalid format
+	f.Tiles[0] = "X"
+	_, err = Analyze(f)
+	assert.ErrorIs(t, err, ErrInvalidMap)
+}
+
+// errorTexts returns the texts of the errors.
+func errorTexts(list []error) []string {
+	texts := make([]string, 0, len(list))
+	for _, err := range list {
+		texts = append(texts, err.Error())
+	}
+	return texts
+}
+
+func TestCanStand(t *testing.T) {
+	assert.True(t, canStand(core.SOLDIER, core.WATER))
+	assert.False(t, canStand(core.TANK, core.WATER))
+	assert.False(t, canStand(core.SOLDIER, emptyTile))
+	assert.True(t, canStand(core.ARTILLERY, core.BASE))
+}
@@ -0,0 +1,172 @@
+package remote
+
+/*
+  This file provides the HTTP admin API of a running server. It is opt-in and every request
+  must be authorized with the admin token (header 'Authorization: Bearer <token>').
+
+    GET  /status                    game status (see AdminStatus)
+    GET  /players                   connected players and their addresses (see PlayerInfo)
+    POST /pause                     freeze the world
+    POST /resume                    unfreeze the world
+    POST /tickrate?rate=60          change the world updates per second
+    POST /kick?player=2             close all connections of a player
+    POST /end?winner=1&reason=...   finish the game with a result
+    GET  /snapshot                  download the complete (uncensored) world as JSON
+*/
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"net/http"
+	"strconv"
+)
+
+// AdminStatus is the game status of the admin API.
+type AdminStatus struct {
+	Iteration uint64       // Current iteration (game time) of the world
+	Freeze    bool         // Indicates if the world is frozen (paused or waiting for players)
+	Started   bool         // Indicates if all players have been connected
+	Online    int          // Number of connected clients
+	MaxPlayer int          // Number of players required to start the game
+	TickRate  int          // World updates per second (see RunWorld)
+	Result    *core.Result // Result of a finished game
+}
+
+// AdminHandler returns the HTTP handler of the admin API.
+// All requests are rejected if the token is empty.
+func (s *Server) AdminHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("/status", adminGet(func(w http.ResponseWriter, r *http.Request) {
+		world := s.world.Clone()
+		if world == nil {
+			adminError(w, http.StatusInternalServerError, "world not available")
+			return
+		}
+		s.mux.Lock()
+		status := &AdminStatus{
+			Iteration: world.Iteration,
+			Freeze:    world.Freeze,
+			Started:   s.started,
+			Online:    len(s.conns),
+			MaxPlayer: s.maxPlayer,
+			TickRate:  s.tickRate,
+			Result:    world.Result,
+		}
+		s.mux.Unlock()
+		adminJSON(w, status)
+	}))
+
+	mux.HandleFunc("/players", adminGet(func(w http.ResponseWriter, r *http.Request) {
+		adminJSON(w, s.Players())
+	}))
+
+	mux.HandleFunc("/pause", adminPost(func(w http.ResponseWriter, r *http.Request) {
+		s.Pause()
+		adminJSON(w, "OK")
+	}))
+
+	mux.HandleFunc("/resume", adminPost(func(w http.ResponseWriter, r *http.Request) {
+		s.Resume()
+		adminJSON(w, "OK")
+	}))
+
+	mux.HandleFunc("/tickrate", adminPost(func(w http.ResponseWriter, r *http.Request) {
+		rate, err := strconv.Atoi(r.FormValue("rate"))
+		if err != nil {
+			adminError(w, http.StatusBadRequest, "invalid rate")
+			return
+		}
+		if err := s.SetTickRate(rate); err != nil {
+			adminError(w, http.StatusConflict, err.Error())
+			return
+		}
+		adminJSON(w, "OK")
+	}))
+
+	mux.HandleFunc("/kick", adminPost(func(w http.ResponseWriter, r *http.Request) {
+		player, err := strconv.ParseUint(r.FormValue("player"), 10, 8)
+		if err != nil {
+			adminError(w, http.StatusBadRequest, "invalid player")
+			return
+		}
+		if s.Kick(uint8(player)) == 0 {
+			adminError(w, http.StatusNotFound, "player not connected")
+			return
+		}
+		adminJSON(w, "OK")
+	}))
+
+	mux.HandleFunc("/end", adminPost(func(w http.ResponseWriter, r *http.Request) {
+		winner, err := strconv.ParseUint(r.FormValue("winner"), 10, 8)
+		if err != nil {
+			adminError(w, http.StatusBadRequest, "invalid winner")
+			return
+		}
+		reason := r.FormValue("reason")
+		if reason == "" {
+			reason = "ended by admin"
+		}
+		s.End(uint8(winner), reason)
+		adminJSON(w, s.world.Clone().Result)
+	}))
+
+	mux.HandleFunc("/snapshot", adminGet(func(w http.ResponseWriter, r *http.Request) {
+		world := s.world.Clone()
+		if world == nil {
+			adminError(w, http.StatusInternalServerError, "world not available")
+			return
+		}
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=snapshot-%d.json", world.Iteration))
+		adminJSON(w, world)
+	}))
+
+	// authorization
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		want := []byte("Bearer " + token)
+		got := []byte(r.Header.Get("Authorization"))
+		if token == "" || subtle.ConstantTimeCompare(want, got) != 1 {
+			adminError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// adminGet allows only GET requests.
+func adminGet(h http.HandlerFunc) http.HandlerFunc {
+	return adminMethod(http.MethodGet, h)
+}
+
+// adminPost allows only POST requests (actions).
+func adminPost(h http.HandlerFunc) http.HandlerFunc {
+	return adminMethod(http.MethodPost, h)
+}
+
+// adminMethod allows only requests with the given method.
+func adminMethod(method string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			adminError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		h(w, r)
+	}
+}
+
+// adminJSON sends a JSON response.
+func adminJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// adminError sends a JSON error response.
+func adminError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"Error": msg})
+}
@@ -0,0 +1,140 @@
+package remote
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// adminRequest sends a request to the admin API and returns the status code and the body.
+func adminRequest(t *testing.T, h http.Handler, method, target, token string) (int, string) {
+	r := httptest.NewRequest(method, target, nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func TestAdminAuth(t *testing.T) {
+	s, _, _ := testServer(t, core.NewWorld(3, 3), 1, nil)
+
+	code, _ := adminRequest(t, s.AdminHandler("secret"), "GET", "/status", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = adminRequest(t, s.AdminHandler("secret"), "GET", "/status", "wrong")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = adminRequest(t, s.AdminHandler(""), "GET", "/status", "")
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = adminRequest(t, s.AdminHandler("secret"), "GET", "/status", "secret")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func TestAdminAPI(t *testing.T) {
+	world := core.NewWorld(3, 3)
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	s, _, _ := testServer(t, world, 2, nil)
+	h := s.AdminHandler("secret")
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", rc.cmd("PLAYER"))
+
+	// status
+	code, body := adminRequest(t, h, "GET", "/status", "secret")
+	require.Equal(t, http.StatusOK, code)
+	status := new(AdminStatus)
+	require.NoError(t, json.Unmarshal([]byte(body), status))
+	assert.Equal(t, AdminStatus{Freeze: true, Online: 1, MaxPlayer: 2, TickRate: core.GameSpeed}, *status)
+
+	// players
+	_, body = adminRequest(t, h, "GET", "/players", "secret")
+	assert.Contains(t, body, `"Player":1`)
+	assert.Contains(t, body, rc.conn.LocalAddr().String())
+
+	// pause and resume
+	code, _ = adminRequest(t, h, "GET", "/resume", "secret")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+	adminRequest(t, h, "POST", "/resume", "secret")
+	assert.False(t, world.Clone().Freeze)
+	adminRequest(t, h, "POST", "/pause", "secret")
+	assert.True(t, world.Clone().Freeze)
+
+	// tick rate requires RunWorld
+	code, _ = adminRequest(t, h, "POST", "/tickrate?rate=60", "secret")
+	assert.Equal(t, http.StatusConflict, code)
+
+	// snapshot
+	code, body = adminRequest(t, h, "GET", "/snapshot", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"XWidth":3`)
+
+	// kick
+	code, _ = adminRequest(t, h, "POST", "/kick?player=2", "secret")
+	assert.Equal(t, http.StatusNotFound, code)
+	code, _ = adminRequest(t, h, "POST", "/kick?player=1", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	_, err := rc.tp.ReadLine()
+	assert.Error(t, err)
+
+	// end
+	code, body = adminRequest(t, h, "POST", "/end?winner=1&reason=test", "secret")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Contains(t, body, `"Reason":"test"`)
+	assert.Equal(t, "test", world.Clone().Result.Reason)
+}
+
+func TestAdminPauseBeforeStart(t *testing.T) {
+	world := core.NewWorld(3, 3)
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(2, 2).Unit = core.NewUnit(core.BLUE, core.TANK)
+	s, _, _ := testServer(t, world, 2, nil)
+	h := s.AdminHandler("secret")
+
+	// the start doesn't undo the pause
+	adminRequest(t, h, "POST", "/pause", "secret")
+	rc1 := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", rc1.cmd("PLAYER"))
+	rc2 := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "2", rc2.cmd("PLAYER"))
+	assert.True(t, world.Clone().Freeze)
+
+	adminRequest(t, h, "POST", "/resume", "secret")
+	assert.False(t, world.Clone().Freeze)
+}
+
+func TestRunWorld(t *testing.T) {
+	world := core.NewWorld(3, 3)
+	s := NewServer("", world, 0)
+	s.Logger = log.New(io.Discard, "", 0)
+	s.Resume()
+
+	updates := 0
+	done := make(chan bool)
+	go func() {
+		s.RunWorld(func() bool {
+			updates++
+			if updates == 1 {
+				assert.NoError(t, s.SetTickRate(1000))
+			}
+			return updates < 20
+		})
+		done <- true
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("world loop not finished")
+	}
+	assert.Equal(t, uint64(20), world.CurrentIteration())
+	assert.Error(t, s.SetTickRate(60)) // loop has ended
+	assert.Error(t, s.SetTickRate(0))
+}
@@ -0,0 +1,225 @@
+package remote
+
+/*
+  This file provides the authentication of a server with passwords (see Server.Password and Server.SeatPasswords).
+  A client must send 'AUTH password' (next free seat) or 'AUTH seat password' (chosen seat) before it
+  gets a player ID. A seat with its own password can only be taken with this password, so it can't be
+  hijacked by the first client that connects. A password of a SeatControl also binds the connection to
+  some units of the seat. The client options for passwords and TLS are at the end.
+*/
+
+import (
+	"crypto/subtle"
+	"crypto/tls"
+	"errors"
+	"strconv"
+	"time"
+)
+
+// errors of the authentication
+var (
+	ErrAuthRequired = errors.New("authentication required") // command sent before AUTH
+	ErrAuthFailed   = errors.New("authentication failed")   // wrong password or seat
+)
+
+// maxAuthFailures is the number of wrong passwords before the connection is closed.
+const maxAuthFailures = 3
+
+// authGrammar contains all commands allowed before the authentication.
+var authGrammar = map[string]commandSpec{
+	"PROTOCOL": gameGrammar["PROTOCOL"],
+	"AUTH":     gameGrammar["AUTH"],
+}
+
+// authRequired reports whether the clients must authenticate with AUTH.
+func (s *Server) authRequired() bool {
+	return s.Password != "" || len(s.SeatPasswords) > 0 || len(s.SeatControls) > 0
+}
+
+// boundSeat reports whether the seat has passwords with a fixed restriction (see SeatControls).
+func (s *Server) boundSeat(seat uint8) bool {
+	for _, sc := range s.SeatControls {
+		if sc.Seat == seat {
+			return true
+		}
+	}
+	return false
+}
+
+// authenticate processes the commands of a new connection until the client has sent a valid AUTH command
+// and joined the game. The connection is rejected after maxAuthFailures wrong passwords.
+func (s *Server) authenticate(ss *session) error {
+	s.mux.Lock()
+	s.pending[ss.conn] = true
+	s.mux.Unlock()
+	defer func() {
+		s.mux.Lock()
+		delete(s.pending, ss.conn)
+		s.mux.Unlock()
+		_ = ss.conn.SetReadDeadline(time.Time{})
+	}()
+
+	for failures := 0; failures < maxAuthFailures; {
+		// close idle connections
+		if timeout := ss.limiter.limits.IdleTimeout; timeout > 0 {
+			_ = ss.conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		// read one line
+		line, err := readLine(ss.tp.R, ss.limiter.limits.MaxLineLength)
+		ss.read(line)
+		if errors.Is(err, ErrLineTooLong) {
+			ss.replyError(CodeLineTooLong, err.Error(), nil)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// parse command
+		cmd, err := parseCommand(line, authGrammar)
+		if !ss.allow(cmd.Name) {
+			continue
+		}
+		if errors.Is(err, ErrInvalidCommand) {
+			ss.replyError(CodeAuthRequired, ErrAuthRequired.Error(), nil)
+			continue
+		}
+		if err != nil {
+			ss.replyError(errorCode(err), err.Error(), errorDetails(err))
+			continue
+		}
+
+		// CHECK COMMANDS
+		switch cmd.Name {
+		case "PROTOCOL":
+			ss.protocol(cmd)
+		case "AUTH":
+			seat, units, ok := s.checkPassword(cmd)
+			if !ok {
+				failures++
+				ss.replyError(CodeAuthFailed, ErrAuthFailed.Error(), nil)
+				continue
+			}
+			if units != "" {
+				if ss.control, err = parseControl(units); err != nil {
+					ss.replyError(CodeAuthFailed, err.Error(), nil)
+					return err
+				}
+				ss.bound = true
+			}
+			player, err := s.join(ss.conn, seat)
+			if err != nil {
+				ss.replyError(CodeAuthFailed, err.Error(), nil)
+				return err
+			}
+			ss.player = player
+			id := strconv.Itoa(int(player))
+			ss.reply(id, []byte(id))
+			return nil
+		}
+	}
+	return ErrAuthFailed
+}
+
+// checkPassword checks the password of an AUTH command and returns the chosen seat (0 = next free seat)
+// and the units of a SeatControl (empty = no restriction).
+// A seat with its own password requires this password, all other seats the global password.
+// A seat of the SeatControls only accepts their passwords.
+func (s *Server) checkPassword(cmd *Command) (uint8, string, bool) {
+	password := cmd.Arg(len(cmd.Args) - 1)
+
+	// seat
+	var seat uint8
+	if len(cmd.Args) == 2 {
+		n, err := strconv.ParseUint(cmd.Arg(0), 10, 8)
+		if err != nil || n == 0 {
+			return 0, "", false
+		}
+		seat = uint8(n)
+	}
+
+	// password with a fixed restriction
+	for _, sc := range s.SeatControls {
+		if sc.Seat == seat && subtle.ConstantTimeCompare([]byte(sc.Password), []byte(password)) == 1 {
+			return seat, sc.Units, true
+		}
+	}
+	if s.boundSeat(seat) || s.botSeat(seat) {
+		return 0, "", false // no global or open access to a bound seat or a bot seat
+	}
+
+	// password
+	want := s.Password
+	if pw := s.SeatPasswords[seat]; seat != 0 && pw != "" {
+		want = pw
+	}
+	if want == "" {
+		return seat, "", true // open seat
+	}
+	return seat, "", subtle.ConstantTimeCompare([]byte(want), []byte(password)) == 1
+}
+
+//--------  Client options  ------------------------------------------------------------------------------------------//
+
+// clientConfig holds the options of NewClient.
+type clientConfig struct {
+	password   string        // Password of the server (see AUTH)
+	seat       uint8         // Chosen seat (0 = next free seat)
+	tls        *tls.Config   // TLS configuration (nil = plain TCP)
+	transcript *Transcript   // Transcript of the connection (nil = none)
+	timeout    time.Duration // Max. time of a command (0 = DefaultTimeout)
+	control    string        // Controlled units of a shared seat (empty = all, see CONTROL)
+}
+
+// ClientOption is an option of NewClient (see WithPassword, WithSeat, WithTLS, WithTimeout, WithTranscript
+// and WithControl).
+type ClientOption func(*clientConfig)
+
+// WithPassword authenticates with the global password of the server and takes the next free seat.
+func WithPassword(password string) ClientOption {
+	return func(c *clientConfig) {
+		c.password = password
+	}
+}
+
+// WithSeat authenticates for the given seat (player ID) with its password.
+func WithSeat(seat uint8, password string) ClientOption {
+	return func(c *clientConfig) {
+		c.seat = seat
+		c.password = password
+	}
+}
+
+// WithTLS connects with TLS. A nil config uses the default configuration (system root CAs).
+func WithTLS(config *tls.Config) ClientOption {
+	return func(c *clientConfig) {
+		if config == nil {
+			config = new(tls.Config)
+		}
+		c.tls = config
+	}
+}
+
+// WithTimeout sets the max. time of a command whose context has no deadline (default: DefaultTimeout).
+// A timeout closes the connection (see ErrDisconnected).
+func WithTimeout(timeout time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = timeout
+	}
+}
+
+// WithTranscript records all commands and responses of the client (see Transcript).
+func WithTranscript(t *Transcript) ClientOption {
+	return func(c *clientConfig) {
+		c.transcript = t
+	}
+}
+
+// WithControl restricts the client to some units of a shared seat, e.g. "A,U" for artillery and soldiers
+// or "T,123" for all tanks and the unit with ID 123 (see CONTROL and Server.SeatConnections).
+func WithControl(units string) ClientOption {
+	return func(c *clientConfig) {
+		c.control = units
+	}
+}
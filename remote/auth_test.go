@@ -0,0 +1,107 @@
+package remote
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfSignedCert creates a TLS certificate for 127.0.0.1.
+func selfSignedCert(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		IsCA:         true,
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestAuth(t *testing.T) {
+	s, _, _ := testServer(t, core.NewWorld(3, 3), 2, func(s *Server) {
+		s.Password = "secret"
+		s.SeatPasswords = map[uint8]string{1: "red"}
+	})
+
+	// commands before AUTH
+	rc := dialRaw(t, s.Addr().String())
+	require.True(t, rc.cmdV2("PROTOCOL 2").OK)
+	assert.Equal(t, CodeAuthRequired, rc.cmdV2("PLAYER").Code)
+	assert.Equal(t, CodeInvalidArgs, rc.cmdV2("AUTH").Code)
+
+	// wrong passwords
+	assert.Equal(t, CodeAuthFailed, rc.cmdV2("AUTH wrong").Code)
+	assert.Equal(t, CodeAuthFailed, rc.cmdV2("AUTH 1 secret").Code) // seat 1 requires its own password
+	assert.Equal(t, CodeAuthFailed, rc.cmdV2("AUTH x red").Code)
+	_, err := rc.tp.ReadLine()
+	assert.Error(t, err) // closed after 3 failures
+
+	// global password takes the next seat without a seat password
+	rc = dialRaw(t, s.Addr().String())
+	assert.Equal(t, "2", rc.cmd("AUTH secret"))
+	assert.Equal(t, "2", rc.cmd("PLAYER"))
+	assert.Equal(t, "2", rc.cmd("AUTH secret")) // already authenticated
+	assert.False(t, s.Started())
+
+	// seat password
+	red := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", red.cmd("AUTH 1 red"))
+	assert.Eventually(t, s.Started, time.Second, 10*time.Millisecond)
+
+	// seat is taken until the connection is closed
+	rc = dialRaw(t, s.Addr().String())
+	assert.Equal(t, "err: seat is taken", rc.cmd("AUTH 1 red"))
+	_ = red.conn.Close()
+	assert.Eventually(t, func() bool { return len(s.Players()) == 1 }, time.Second, 10*time.Millisecond)
+	rc = dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", rc.cmd("AUTH 1 red"))
+}
+
+func TestAuthClient(t *testing.T) {
+	cert := selfSignedCert(t)
+	_, host, port := testServer(t, core.NewWorld(3, 3), 2, func(s *Server) {
+		s.SeatPasswords = map[uint8]string{2: "blue"}
+		s.TLSConfig = &tls.Config{Certificates: []tls.Certificate{cert}}
+	})
+	ca, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+
+	// plain TCP is rejected
+	_, err = NewClient(host, port, WithSeat(2, "blue"))
+	assert.Error(t, err)
+
+	// wrong password
+	_, err = NewClient(host, port, WithTLS(&tls.Config{RootCAs: roots}), WithSeat(2, "red"))
+	assert.ErrorIs(t, err, ErrAuthFailed)
+
+	// seat password
+	c, err := NewClient(host, port, WithTLS(&tls.Config{RootCAs: roots}), WithSeat(2, "blue"))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(2), c.Player())
+
+	// open seat without password
+	c, err = NewClient(host, port, WithTLS(&tls.Config{RootCAs: roots}), WithPassword("any"))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), c.Player())
+}
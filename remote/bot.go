@@ -0,0 +1,54 @@
+package remote
+
+/*
+  This file connects in-process bots to a server. A bot gets a normal Client (see ai.RunAI), but the
+  connection is an in-memory pipe instead of TCP. The bot takes a chosen seat, so the game starts as
+  soon as the remaining seats are filled by human or external clients.
+*/
+
+import (
+	"bufio"
+	"net"
+	"net/textproto"
+)
+
+// botSeat reports whether the seat is reserved for an in-process bot (see BotSeats).
+func (s *Server) botSeat(seat uint8) bool {
+	for _, id := range s.BotSeats {
+		if id == seat {
+			return true
+		}
+	}
+	return false
+}
+
+// ConnectBot connects an in-process client to the given seat (player ID) of the server (0 = next free seat).
+// The seat password is not required (see AUTH). The returned client works like a client created by NewClient.
+func (s *Server) ConnectBot(seat uint8) (*Client, error) {
+	serverConn, clientConn := net.Pipe()
+
+	// assign seat
+	player, err := s.join(serverConn, seat)
+	if err != nil {
+		_ = serverConn.Close()
+		_ = clientConn.Close()
+		return nil, err
+	}
+
+	// handle commands
+	ss := s.newSession(serverConn, textproto.NewReader(bufio.NewReader(serverConn)))
+	ss.player = player
+	go s.handle(ss)
+
+	// use structured responses
+	c := newClient(clientConn)
+	c.setPlayer(player)
+	if err := c.negotiate(); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	// Start a goroutine to continuously update the game world
+	c.startUpdates()
+	return c, nil
+}
@@ -0,0 +1,71 @@
+package remote
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectBot(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(4, 4).Unit = core.NewUnit(core.BLUE, core.TANK)
+	s, host, port := testServer(t, world, 2, func(s *Server) {
+		s.SeatPasswords = map[uint8]string{1: "red", 2: "blue"}
+	})
+
+	// bot takes seat 2 without password
+	bot, err := s.ConnectBot(2)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(2), bot.Player())
+	assert.False(t, s.Started())
+	_, err = s.ConnectBot(2)
+	assert.EqualError(t, err, "seat is taken")
+
+	// bot commands
+	assert.NoError(t, bot.Move(4, 4, 3, 4))
+	assert.ErrorIs(t, bot.Move(0, 0, 1, 0), core.ErrNoUnit)
+	assert.Eventually(t, func() bool {
+		return bot.Snapshot() != nil // world updates
+	}, time.Second, 10*time.Millisecond)
+
+	// human takes the remaining seat
+	c, err := NewClient(host, port, WithSeat(1, "red"))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), c.Player())
+	assert.True(t, s.Started())
+
+	// server closes the bot connection
+	require.NoError(t, s.Close())
+	assert.ErrorIs(t, bot.Move(4, 4, 3, 4), ErrDisconnected)
+	assert.Equal(t, uint8(2), bot.Player()) // cached
+}
+
+func TestBotSeats(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(4, 4).Unit = core.NewUnit(core.BLUE, core.TANK)
+	s, _, _ := testServer(t, world, 2, func(s *Server) {
+		s.Password = "pw"
+		s.BotSeats = []uint8{1}
+	})
+
+	// humans can't take the seat of the bot before it is connected
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "err: authentication failed", rc.cmd("AUTH 1 pw"))
+	assert.Equal(t, "2", rc.cmd("AUTH pw"))
+	assert.False(t, s.Started())
+	bot, err := s.ConnectBot(1)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), bot.Player())
+	assert.True(t, s.Started())
+
+	// invalid configuration
+	s = NewServer("127.0.0.1:0", world, 2)
+	s.BotSeats = []uint8{3}
+	assert.EqualError(t, s.Start(context.Background()), "invalid bot seat 3")
+}
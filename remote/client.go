@@ -2,11 +2,17 @@ package remote
 
 /*
   This file provides a Client struct and related methods for establishing a remote connection to the game server.
+  The client sends one command at a time and polls the world in the background (see Snapshot and OnUpdate).
+  A network error or a timeout closes the connection, because a late response would be read as the response
+  of the next command. After that, all commands return an error matching ErrDisconnected (see Done and Err).
 */
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/SchnorcherSepp/TankWars2/core"
 	"net"
@@ -17,126 +23,574 @@ import (
 	"time"
 )
 
+// errors of the client
+var (
+	ErrClosed       = errors.New("client closed")    // Close was called
+	ErrDisconnected = errors.New("connection lost")  // network error or timeout, the client is closed
+	ErrProtocol     = errors.New("invalid response") // the server sent an unexpected response
+)
+
+// DefaultTimeout is the max. time of a command if the context has no deadline (see WithTimeout).
+const DefaultTimeout = 10 * time.Second
+
+// updateInterval is the time between two world updates (see startUpdates).
+const updateInterval = 100 * time.Millisecond
+
 // Client represents a remote connection to the game server, allowing communication and interaction with the game world.
+// All methods are safe for concurrent use.
 type Client struct {
-	conn *net.TCPConn      // TCP connection to the game server
-	tp   *textproto.Reader // Text protocol reader for the connection
-	mux  *sync.Mutex       // Mutex for thread-safe operations
+	conn    net.Conn          // TCP (or TLS) connection to the game server
+	tp      *textproto.Reader // Text protocol reader for the connection
+	mux     *sync.Mutex       // Mutex of the connection (one command at a time)
+	timeout time.Duration     // Max. time of a command without deadline (see WithTimeout)
+
+	version    int         // Protocol version of the connection (see negotiate)
+	mapInfo    *MapInfo    // Static map for the compact STATUS (nil = full STATUS)
+	gzip       bool        // Indicates if MAP and STATUS payloads are compressed
+	transcript *Transcript // Records the commands and responses (optional, see WithTranscript)
 
-	world *core.World // Current game world status
+	state        sync.Mutex        // Mutex of the fields below
+	world        *core.World       // Current game world status (replaced by every update)
+	player       uint8             // Cached player ID (see Player)
+	err          error             // Reason of the disconnect (nil = connected)
+	done         chan struct{}     // Closed on disconnect (see Done)
+	onUpdate     func(*core.World) // Called after every world update (see OnUpdate)
+	onDisconnect func(err error)   // Called once on disconnect (see OnDisconnect)
 }
 
 // NewClient creates a new Client instance and establishes a connection to the game server at the provided host and port.
-// It initializes the TCP connection and polls the world status every 100ms.
-func NewClient(host, port string) (*Client, error) {
+// It initializes the TCP connection, caches the player ID and polls the world status every 100ms.
+// The options enable TLS, the authentication, timeouts, the transcript and shared seats
+// (see WithTLS, WithPassword, WithSeat, WithTimeout, WithTranscript and WithControl).
+// If the server doesn't support protocol version 2, the client falls back to the plain text responses of version 1
+// (without error details, activities and the result of Schedule).
+func NewClient(host, port string, opts ...ClientOption) (*Client, error) {
 
-	// Resolve TCP address
-	tcpAddr, err := net.ResolveTCPAddr("tcp", host+":"+port)
+	// Establish TCP connection
+	c, cfg, err := dialOptions(host, port, opts)
 	if err != nil {
 		return nil, err
 	}
 
+	// use structured responses
+	if err := c.negotiate(); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	// authenticate
+	if cfg.password != "" || cfg.seat != 0 {
+		if err := c.auth(cfg.seat, cfg.password); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+
+	// restrict units
+	if cfg.control != "" {
+		if _, err := c.Request(context.Background(), "CONTROL "+cfg.control); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+
+	// cache player ID
+	if c.Player() == 0 {
+		if err := c.loadPlayer(); err != nil {
+			_ = c.Close()
+			return nil, err
+		}
+	}
+
+	// Start a goroutine to continuously update the game world
+	c.startUpdates()
+
+	// return
+	return c, nil
+}
+
+// JoinGame connects to a lobby server (see RunLobby) and joins the game with the given ID.
+// The returned client works like a client created by NewClient and takes the same options. The password of
+// WithPassword is the password of a private game (see CreateGame), a lobby game always assigns the next free seat.
+func JoinGame(host, port string, id int, opts ...ClientOption) (*Client, error) {
+
 	// Establish TCP connection
-	conn, err := net.DialTCP("tcp", nil, tcpAddr)
+	c, cfg, err := dialOptions(host, port, opts)
 	if err != nil {
 		return nil, err
 	}
+	if cfg.seat != 0 {
+		_ = c.Close()
+		return nil, errors.New("lobby games assign the seats")
+	}
 
-	// Create a new Client instance
-	c := &Client{
-		conn: conn,
-		tp:   textproto.NewReader(bufio.NewReader(conn)),
-		mux:  new(sync.Mutex),
+	// join game
+	resp, err := c.command(context.Background(), strings.TrimSpace(fmt.Sprintf("JOIN %d %s", id, cfg.password)))
+	if err != nil {
+		return nil, err
+	}
+	player, err := strconv.ParseUint(resp, 10, 8)
+	if err != nil {
+		_ = c.Close()
+		return nil, errors.New(resp)
 	}
+	c.setPlayer(uint8(player))
 
-	// Start a goroutine to continuously update the game world
-	go func(c *Client) {
-		errCount := 0
-		for {
-			// update world
-			if nil != c.updateWorld() {
-				errCount++
-			}
-			// check error count
-			if errCount >= 3 {
-				println("CLIENT: exit update loop")
-				break
-			}
-			// sleep
-			time.Sleep(100 * time.Millisecond)
+	// use structured responses
+	if err := c.negotiate(); err != nil {
+		_ = c.Close()
+		return nil, err
+	}
+
+	// restrict units
+	if cfg.control != "" {
+		if _, err := c.Request(context.Background(), "CONTROL "+cfg.control); err != nil {
+			_ = c.Close()
+			return nil, err
 		}
-	}(c)
+	}
+
+	// Start a goroutine to continuously update the game world
+	c.startUpdates()
 
 	// return
 	return c, nil
 }
 
-// Player returns the player's ID associated with this client session (see core.PLAYERS).
-func (c *Client) Player() uint8 {
-	c.mux.Lock()
-	defer c.mux.Unlock()
+// ListGames returns all open games of a lobby server (see RunLobby).
+// The options WithTLS, WithTimeout and WithTranscript are used, the others are ignored.
+func ListGames(host, port string, opts ...ClientOption) ([]GameInfo, error) {
 
-	resp := c.command("PLAYER")
+	// Establish TCP connection
+	c, _, err := dialOptions(host, port, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func(c *Client) {
+		_ = c.Close()
+	}(c)
+
+	// parse JSON
+	resp, err := c.command(context.Background(), "LIST")
+	if err != nil {
+		return nil, err
+	}
+	games := make([]GameInfo, 0)
+	if err := json.Unmarshal([]byte(resp), &games); err != nil {
+		return nil, errors.New(resp)
+	}
+	return games, nil
+}
+
+// CreateGame creates a new game with the given map on a lobby server (see RunLobby) and returns the game ID.
+// The password of WithPassword makes the game private (see JoinGame). The options WithTLS, WithTimeout and
+// WithTranscript are used, the others are ignored.
+func CreateGame(host, port, mapName string, opts ...ClientOption) (int, error) {
 
-	value, err := strconv.ParseUint(resp, 10, 8)
+	// Establish TCP connection
+	c, cfg, err := dialOptions(host, port, opts)
 	if err != nil {
-		fmt.Println("err:", err)
-		return 0
+		return 0, err
 	}
+	defer func(c *Client) {
+		_ = c.Close()
+	}(c)
 
-	return uint8(value)
+	// create game
+	resp, err := c.command(context.Background(), strings.TrimSpace("CREATE "+mapName+" "+cfg.password))
+	if err != nil {
+		return 0, err
+	}
+	id, err := strconv.Atoi(resp)
+	if err != nil {
+		return 0, errors.New(resp)
+	}
+	return id, nil
+}
+
+//--------  Getter  --------------------------------------------------------------------------------------------------//
+
+// Player returns the player's ID associated with this client session (see core.PLAYERS).
+// The ID is loaded once when the client connects, so it is also available after a disconnect.
+func (c *Client) Player() uint8 {
+	c.state.Lock()
+	defer c.state.Unlock()
+
+	return c.player
 }
 
 // Status returns the current game world status as new World object.
 // This status is the censored version with the information visible to this player (see core.Censorship).
+// Before the first update, Status waits up to one second for the world (see Snapshot).
 func (c *Client) Status() *core.World {
 
 	// wait for world (max 1 sec)
 	for n := 0; n < 10; n++ {
-		if c.world != nil {
-			break
+		if world := c.Snapshot(); world != nil {
+			return world
+		}
+		select {
+		case <-c.Done():
+			return nil
+		case <-time.After(100 * time.Millisecond):
 		}
-		time.Sleep(100 * time.Millisecond)
 	}
 
 	// return world
+	return c.Snapshot()
+}
+
+// Snapshot returns the last world received from the server without waiting (nil before the first update).
+// Every update creates a new world, so the returned world is never changed by the client.
+func (c *Client) Snapshot() *core.World {
+	c.state.Lock()
+	defer c.state.Unlock()
+
 	return c.world
 }
 
+// Done returns a channel that is closed when the connection is closed (see Close and Err).
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason of the disconnect: ErrClosed after Close, an error matching ErrDisconnected after a network
+// error or timeout and nil while the client is connected.
+func (c *Client) Err() error {
+	c.state.Lock()
+	defer c.state.Unlock()
+
+	return c.err
+}
+
+//--------  Setter  --------------------------------------------------------------------------------------------------//
+
+// OnUpdate sets a function that is called with the new world after every update (nil = none).
+// The function is called by the update goroutine and should return quickly.
+func (c *Client) OnUpdate(fn func(world *core.World)) {
+	c.state.Lock()
+	defer c.state.Unlock()
+
+	c.onUpdate = fn
+}
+
+// OnDisconnect sets a function that is called once with the reason when the connection is closed (see Err).
+// If the client is already disconnected, the function is called immediately.
+func (c *Client) OnDisconnect(fn func(err error)) {
+	c.state.Lock()
+	err := c.err
+	c.onDisconnect = fn
+	c.state.Unlock()
+
+	if err != nil && fn != nil {
+		fn(err)
+	}
+}
+
+// Close closes the connection and stops the world updates. Further commands return ErrClosed.
+func (c *Client) Close() error {
+	if !c.disconnect(ErrClosed) {
+		return ErrClosed
+	}
+	return nil
+}
+
+//--------  Commands  ------------------------------------------------------------------------------------------------//
+
 // Fire sends a 'Fire' command to the game server to initiate an attack from one tile to another.
 // (see Fire methode from core.World)
+// A rejected command returns an *Error, which can be checked with errors.Is (e.g. core.ErrNotInRange).
 func (c *Client) Fire(fromX, fromY, toX, toY int) error {
-	c.mux.Lock()
-	defer c.mux.Unlock()
+	return c.FireContext(context.Background(), fromX, fromY, toX, toY)
+}
 
-	resp := c.command(fmt.Sprintf("%s %d %d %d %d", core.FIRE, fromX, fromY, toX, toY))
-	if resp == "OK" {
-		return nil // success
-	} else {
-		return fmt.Errorf("err: %s", resp)
-	}
+// FireContext is like Fire, but the command is aborted (and the connection closed) when the context is done.
+func (c *Client) FireContext(ctx context.Context, fromX, fromY, toX, toY int) error {
+	_, err := c.Request(ctx, fmt.Sprintf("%s %d %d %d %d", core.FIRE, fromX, fromY, toX, toY))
+	return err
 }
 
 // Move sends a 'Move' command to the game server to move a unit from one tile to another.
 // (see Move methode from core.World)
+// A rejected command returns an *Error, which can be checked with errors.Is (e.g. core.ErrBusy).
 func (c *Client) Move(fromX, fromY, toX, toY int) error {
+	return c.MoveContext(context.Background(), fromX, fromY, toX, toY)
+}
+
+// MoveContext is like Move, but the command is aborted (and the connection closed) when the context is done.
+func (c *Client) MoveContext(ctx context.Context, fromX, fromY, toX, toY int) error {
+	_, err := c.Request(ctx, fmt.Sprintf("%s %d %d %d %d", core.MOVE, fromX, fromY, toX, toY))
+	return err
+}
+
+// FireUnit is like Fire, but addresses the unit by its ID (see UFIRE).
+// The command stays valid, even if the unit has moved since the last snapshot.
+func (c *Client) FireUnit(id, toX, toY int) error {
+	return c.FireUnitContext(context.Background(), id, toX, toY)
+}
+
+// FireUnitContext is like FireUnit, but the command is aborted (and the connection closed) when the context is done.
+func (c *Client) FireUnitContext(ctx context.Context, id, toX, toY int) error {
+	_, err := c.Request(ctx, fmt.Sprintf("UFIRE %d %d %d", id, toX, toY))
+	return err
+}
+
+// MoveUnit is like Move, but addresses the unit by its ID (see UMOVE).
+// The command stays valid, even if the unit has moved since the last snapshot.
+func (c *Client) MoveUnit(id, toX, toY int) error {
+	return c.MoveUnitContext(context.Background(), id, toX, toY)
+}
+
+// MoveUnitContext is like MoveUnit, but the command is aborted (and the connection closed) when the context is done.
+func (c *Client) MoveUnitContext(ctx context.Context, id, toX, toY int) error {
+	_, err := c.Request(ctx, fmt.Sprintf("UMOVE %d %d %d", id, toX, toY))
+	return err
+}
+
+// Schedule queues a MOVE, FIRE, UMOVE or UFIRE command (e.g. 'FIRE 1 1 4 2') for the iteration and returns it
+// with its command ID. The command is checked when it starts, a rejection is reported by Results.
+func (c *Client) Schedule(ctx context.Context, iteration uint64, cmd string) (*core.Scheduled, error) {
+	resp, err := c.Request(ctx, fmt.Sprintf("%s @%d", cmd, iteration))
+	if err != nil {
+		return nil, err
+	}
+	queued := new(core.Scheduled)
+	if err := json.Unmarshal(resp.Data, queued); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrProtocol, err)
+	}
+	return queued, nil
+}
+
+// Results returns and removes the outcomes of the player's commands (see RESULTS and core.Outcome).
+// The command ID of an accepted command is the ID of the returned activity (see Request).
+func (c *Client) Results() ([]*core.Outcome, error) {
+	return c.ResultsContext(context.Background())
+}
+
+// ResultsContext is like Results, but the command is aborted (and the connection closed) when the context is done.
+func (c *Client) ResultsContext(ctx context.Context) ([]*core.Outcome, error) {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	resp := c.command(fmt.Sprintf("%s %d %d %d %d", core.MOVE, fromX, fromY, toX, toY))
-	if resp == "OK" {
-		return nil // success
-	} else {
-		return fmt.Errorf("err: %s", resp)
+	var list []*core.Outcome
+	if err := c.payload(ctx, "RESULTS", &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Request sends any in-game command (e.g. 'PATH 1 1 4 2') and returns the structured response (see ProtocolV2).
+// A rejected command returns the response and an *Error. The command is aborted (and the connection closed)
+// when the context is done or after the timeout of the client (see WithTimeout).
+func (c *Client) Request(ctx context.Context, cmd string) (*Response, error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+
+	return c.request(ctx, cmd)
+}
+
+// DebugMark marks a tile in the debug overlay of the GUI for ttl iterations (see core.World.AddDebug).
+// The color is a name (e.g. "red") or a hex color (e.g. "#ff8000").
+func (c *Client) DebugMark(x, y int, color string, ttl int) error {
+	_, err := c.Request(context.Background(), fmt.Sprintf("DEBUG %s %d %d %s %d", core.DebugMark, x, y, color, ttl))
+	return err
+}
+
+// DebugLine draws a line between two tiles in the debug overlay of the GUI for ttl iterations.
+func (c *Client) DebugLine(x1, y1, x2, y2 int, color string, ttl int) error {
+	_, err := c.Request(context.Background(), fmt.Sprintf("DEBUG %s %d %d %d %d %s %d", core.DebugLine, x1, y1, x2, y2, color, ttl))
+	return err
+}
+
+// DebugLabel writes a text on a tile in the debug overlay of the GUI for ttl iterations.
+// Spaces are sent as underscores (the GUI shows them as spaces).
+func (c *Client) DebugLabel(x, y int, color string, ttl int, text string) error {
+	text = strings.Join(strings.Fields(text), "_")
+	_, err := c.Request(context.Background(), fmt.Sprintf("DEBUG %s %d %d %s %d %s", core.DebugLabel, x, y, color, ttl, text))
+	return err
+}
+
+// DebugClear removes all debug annotations of the player.
+func (c *Client) DebugClear() error {
+	_, err := c.Request(context.Background(), "DEBUG CLEAR")
+	return err
+}
+
+// UseCompact switches the world updates to the compact encoding (see MAP and COMPACT).
+// The static map is loaded once, after that every update only transfers the dynamic state.
+// If gzip is true, the payloads are also compressed (see GZIP).
+func (c *Client) UseCompact(gzip bool) error {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	ctx := context.Background()
+
+	// compression
+	if gzip {
+		if _, err := c.request(ctx, "GZIP ON"); err != nil {
+			return err
+		}
+		c.gzip = true
+	}
+
+	// load static map
+	m := new(MapInfo)
+	if err := c.payload(ctx, "MAP", m); err != nil {
+		return err
+	}
+
+	// enable compact status
+	if _, err := c.request(ctx, "COMPACT ON"); err != nil {
+		return err
 	}
+	c.mapInfo = m
+	return nil
 }
 
 //---------------- HELPER --------------------------------------------------------------------------------------------//
 
-// command send the cmd to the server and return the response
-func (c *Client) command(cmd string) string {
-	if c == nil || c.conn == nil || c.tp == nil {
-		return "err: TcpClient connection closed."
+// dial establishes the TCP connection (TLS if a config is given) to the server and returns a new Client without world updates.
+func dial(host, port string, config *tls.Config) (*Client, error) {
+
+	// Resolve TCP address
+	tcpAddr, err := net.ResolveTCPAddr("tcp", host+":"+port)
+	if err != nil {
+		return nil, err
+	}
+
+	// Establish TCP connection
+	var conn net.Conn
+	if config != nil {
+		conn, err = tls.Dial("tcp", tcpAddr.String(), config)
+	} else {
+		conn, err = net.DialTCP("tcp", nil, tcpAddr)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new Client instance
+	return newClient(conn), nil
+}
+
+// dialOptions applies the options and establishes the connection with the TLS configuration, the transcript
+// and the timeout of the options.
+func dialOptions(host, port string, opts []ClientOption) (*Client, *clientConfig, error) {
+	cfg := new(clientConfig)
+	for _, opt := range opts {
+		opt(cfg)
+	}
+
+	c, err := dial(host, port, cfg.tls)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.transcript = cfg.transcript
+	if cfg.timeout > 0 {
+		c.timeout = cfg.timeout
+	}
+	return c, cfg, nil
+}
+
+// newClient returns a new Client for an established connection without world updates.
+func newClient(conn net.Conn) *Client {
+	return &Client{
+		conn:    conn,
+		tp:      textproto.NewReader(bufio.NewReader(conn)),
+		mux:     new(sync.Mutex),
+		timeout: DefaultTimeout,
+		version: ProtocolV1,
+		done:    make(chan struct{}),
+	}
+}
+
+// setPlayer caches the player ID.
+func (c *Client) setPlayer(player uint8) {
+	c.state.Lock()
+	defer c.state.Unlock()
+
+	c.player = player
+}
+
+// loadPlayer requests the player ID from the server (see PLAYER) and caches it.
+func (c *Client) loadPlayer() error {
+	resp, err := c.Request(context.Background(), "PLAYER")
+	if err != nil {
+		return err
+	}
+	value, err := strconv.ParseUint(string(resp.Data), 10, 8)
+	if err != nil {
+		return fmt.Errorf("%w: PLAYER: %v", ErrProtocol, err)
+	}
+	c.setPlayer(uint8(value))
+	return nil
+}
+
+// auth sends the password (and the seat) to the server (see AUTH) and caches the player ID.
+// A wrong password returns an *Error matching ErrAuthFailed.
+func (c *Client) auth(seat uint8, password string) error {
+	cmd := "AUTH " + password
+	if seat != 0 {
+		cmd = fmt.Sprintf("AUTH %d %s", seat, password)
+	}
+	resp, err := c.Request(context.Background(), cmd)
+	if err == nil {
+		if value, err := strconv.ParseUint(string(resp.Data), 10, 8); err == nil {
+			c.setPlayer(uint8(value))
+		}
+	}
+	return err
+}
+
+// startUpdates starts a goroutine to continuously update the game world every 100ms until the client is closed.
+// Rejected updates (e.g. THROTTLED) are skipped, any other error closes the client.
+func (c *Client) startUpdates() {
+	go func(c *Client) {
+		ticker := time.NewTicker(updateInterval)
+		defer ticker.Stop()
+
+		for {
+			// update world
+			err := c.updateWorld()
+			var e *Error
+			if err != nil && !errors.As(err, &e) {
+				c.disconnect(err)
+			}
+
+			// wait
+			select {
+			case <-c.done:
+				return // EXIT
+			case <-ticker.C:
+			}
+		}
+	}(c)
+}
+
+// disconnect closes the connection with the reason and calls OnDisconnect.
+// It returns false if the client was already disconnected.
+func (c *Client) disconnect(reason error) bool {
+	c.state.Lock()
+	if c.err != nil {
+		c.state.Unlock()
+		return false
+	}
+	c.err = reason
+	fn := c.onDisconnect
+	close(c.done)
+	c.state.Unlock()
+
+	_ = c.conn.Close()
+	if fn != nil {
+		fn(reason)
+	}
+	return true
+}
+
+// command sends the cmd to the server and returns the response line (the connection mutex must be locked).
+// A network error or timeout closes the client and returns an error matching ErrDisconnected.
+func (c *Client) command(ctx context.Context, cmd string) (string, error) {
+	if err := c.Err(); err != nil {
+		return "", err
 	}
 
 	// remove protocol break
@@ -144,38 +598,169 @@ func (c *Client) command(cmd string) string {
 	cmd = strings.ReplaceAll(cmd, "\r", "")
 	cmd = strings.ReplaceAll(cmd, "  ", " ")
 
+	// deadline
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		deadline = time.Now().Add(c.timeout)
+	}
+	_ = c.conn.SetDeadline(deadline)
+	stop := context.AfterFunc(ctx, func() {
+		_ = c.conn.SetDeadline(time.Now()) // abort
+	})
+	defer stop()
+
 	// send command
+	sent := time.Now()
 	_, err := c.conn.Write([]byte(fmt.Sprintf("%s\r\n", cmd)))
-	if err != nil {
-		return fmt.Sprintf("err: TcpClient write: %v", err)
-	}
 
 	// read response
-	resp, err := c.tp.ReadLine()
+	var resp string
+	if err == nil {
+		resp, err = c.tp.ReadLine()
+	}
+
+	// disconnect on errors
 	if err != nil {
-		return fmt.Sprintf("err: TcpClient read: %v", err)
+		var netErr net.Error
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		} else if hasDeadline && errors.As(err, &netErr) && netErr.Timeout() && !time.Now().Before(deadline) {
+			err = context.DeadlineExceeded // the connection deadline is faster than the context
+		}
+		err = fmt.Errorf("%w: %w", ErrDisconnected, err)
+		if !c.disconnect(err) {
+			err = c.Err() // closed by another goroutine
+		}
+		return "", err
+	}
+
+	// record
+	if c.transcript != nil {
+		var iteration uint64
+		if world := c.Snapshot(); world != nil {
+			iteration = world.Iteration
+		}
+		c.transcript.Record(TranscriptEntry{
+			Time:      sent,
+			Conn:      c.conn.RemoteAddr().String(),
+			Player:    c.Player(),
+			Iteration: iteration,
+			Command:   cmd,
+			Response:  resp,
+		})
 	}
 
 	// return server response
-	return resp
+	return resp, nil
 }
 
-// updateWorld retrieves the current game world status from the server and override the local world instance.
-func (c *Client) updateWorld() error {
+// request sends the cmd to the server and parses the structured response (see ProtocolV2).
+// A rejected command returns the response and an *Error.
+func (c *Client) request(ctx context.Context, cmd string) (*Response, error) {
+	resp, err := c.command(ctx, cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	// plain text
+	if c.version == ProtocolV1 {
+		r := v1Response(resp)
+		return r, responseError(r)
+	}
+
+	// parse JSON
+	r := &Response{}
+	if err := json.Unmarshal([]byte(resp), r); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrProtocol, resp)
+	}
+	return r, responseError(r)
+}
+
+// payload sends the cmd and parses the JSON payload of the response into v (see MAP and STATUS).
+// Compressed payloads are decompressed first.
+func (c *Client) payload(ctx context.Context, cmd string, v interface{}) error {
+	resp, err := c.request(ctx, cmd)
+	if err != nil {
+		return err
+	}
+
+	// decompress
+	data := []byte(resp.Data)
+	if c.gzip {
+		s := string(data) // version 1 sends the plain base64 text
+		if c.version == ProtocolV2 {
+			if err := json.Unmarshal(data, &s); err != nil {
+				return fmt.Errorf("%w: %v", ErrProtocol, err)
+			}
+		}
+		if data, err = gunzipBase64(s); err != nil {
+			return fmt.Errorf("%w: %v", ErrProtocol, err)
+		}
+	}
+
+	// parse JSON
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrProtocol, err)
+	}
+	return nil
+}
+
+// negotiate switches the connection to structured responses (see ProtocolV2).
+// If the server rejects the command (e.g. an old server without PROTOCOL), the connection keeps version 1.
+func (c *Client) negotiate() error {
 	c.mux.Lock()
 	defer c.mux.Unlock()
 
-	// request status
-	wJSON := c.command("STATUS")
-	retWorld := core.World{}
+	resp, err := c.command(context.Background(), fmt.Sprintf("PROTOCOL %d", ProtocolV2))
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(resp, v1ErrorPrefix) {
+		return nil // version 1
+	}
 
 	// parse JSON
-	if err := json.Unmarshal([]byte(wJSON), &retWorld); err != nil {
-		println("err:", wJSON)
+	r := &Response{}
+	if err := json.Unmarshal([]byte(resp), r); err != nil {
+		return fmt.Errorf("%w: %s", ErrProtocol, resp)
+	}
+	if err := responseError(r); err != nil {
 		return err
 	}
+	c.version = ProtocolV2
+	return nil
+}
+
+// updateWorld retrieves the current game world status from the server, replaces the local world and calls OnUpdate.
+// The world is replaced before the next command can be sent (see remotetest.Server.Step).
+func (c *Client) updateWorld() error {
+	c.mux.Lock()
+
+	// request status
+	var world *core.World
+	var err error
+	if c.mapInfo != nil {
+		cs := new(CompactStatus) // compact status
+		if err = c.payload(context.Background(), "STATUS", cs); err == nil {
+			world = c.mapInfo.World(cs)
+		}
+	} else {
+		world = new(core.World)
+		err = c.payload(context.Background(), "STATUS", world)
+	}
 
 	// set new world
-	c.world = &retWorld
-	return nil
+	var fn func(*core.World)
+	if err == nil {
+		c.state.Lock()
+		c.world = world
+		fn = c.onUpdate
+		c.state.Unlock()
+	}
+	c.mux.Unlock()
+
+	if fn != nil {
+		fn(world)
+	}
+	return err
 }
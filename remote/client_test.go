@@ -0,0 +1,192 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeServer accepts a single connection and answers every command with the reply function
+// (an empty reply sends nothing).
+func fakeServer(t *testing.T, reply func(line string) string) (host, port string) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer func(conn net.Conn) {
+			_ = conn.Close()
+		}(conn)
+		scanner := bufio.NewScanner(conn)
+		for scanner.Scan() {
+			if resp := reply(scanner.Text()); resp != "" {
+				_, _ = conn.Write([]byte(resp + "\r\n"))
+			}
+		}
+	}()
+
+	host, port, err = net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	return host, port
+}
+
+func TestClientUpdates(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	s, host, port := testServer(t, world, 1, nil)
+
+	c, err := NewClient(host, port)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), c.Player())
+	assert.NoError(t, c.Err())
+
+	// callbacks
+	updates := make(chan *core.World, 100)
+	disconnects := make(chan error, 2)
+	c.OnUpdate(func(world *core.World) { updates <- world })
+	c.OnDisconnect(func(err error) { disconnects <- err })
+	select {
+	case w := <-updates:
+		assert.Equal(t, 5, w.XWidth)
+		assert.NotNil(t, c.Snapshot())
+	case <-time.After(time.Second):
+		t.Fatal("no update")
+	}
+
+	// generic request
+	resp, err := c.Request(context.Background(), "DIST 0 0 2 0")
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(resp.Data))
+	_, err = c.Request(context.Background(), "DIST 0 0 9 9")
+	assert.ErrorIs(t, err, core.ErrInvalidInput)
+
+	// scheduled command
+	queued, err := c.Schedule(context.Background(), 100000, "MOVE 0 0 1 0")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(100000), queued.Iteration)
+	assert.NotZero(t, queued.ID)
+
+	// outcomes
+	results, err := c.Results()
+	require.NoError(t, err)
+	assert.Empty(t, results)
+
+	// server closes the connection
+	require.NoError(t, s.Close())
+	select {
+	case err := <-disconnects:
+		assert.ErrorIs(t, err, ErrDisconnected)
+	case <-time.After(time.Second):
+		t.Fatal("no disconnect")
+	}
+	<-c.Done()
+	assert.ErrorIs(t, c.Err(), ErrDisconnected)
+	assert.ErrorIs(t, c.Move(0, 0, 1, 0), ErrDisconnected)
+	assert.Equal(t, uint8(1), c.Player()) // cached
+	assert.ErrorIs(t, c.Close(), ErrClosed)
+	assert.Len(t, disconnects, 0) // called once
+}
+
+func TestClientProtocolV1(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(0, 0).Unit = core.NewUnit(core.BLUE, core.TANK)
+	status, err := json.Marshal(world)
+	require.NoError(t, err)
+
+	// old server without PROTOCOL
+	host, port := fakeServer(t, func(line string) string {
+		switch line {
+		case "PLAYER":
+			return "2"
+		case "STATUS":
+			return string(status)
+		case "MOVE 0 0 1 0":
+			return "OK"
+		case "MOVE 1 1 2 1":
+			return core.ErrNoUnit.Error()
+		case "DIST 0 0 9 9":
+			return "err: " + core.ErrInvalidInput.Error()
+		}
+		return "err: " + ErrInvalidCommand.Error()
+	})
+	c, err := NewClient(host, port)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	assert.Equal(t, ProtocolV1, c.version)
+	assert.Equal(t, uint8(2), c.Player())
+
+	// plain text responses
+	require.Eventually(t, func() bool { return c.Snapshot() != nil }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, uint8(core.BLUE), c.Status().Tile(0, 0).Unit.Player)
+	assert.NoError(t, c.Move(0, 0, 1, 0))
+	assert.ErrorIs(t, c.Move(1, 1, 2, 1), core.ErrNoUnit)
+	_, err = c.Request(context.Background(), "DIST 0 0 9 9")
+	assert.ErrorIs(t, err, core.ErrInvalidInput)
+	resp, err := c.Request(context.Background(), "PLAYER")
+	require.NoError(t, err)
+	assert.Equal(t, "2", string(resp.Data))
+	_, err = c.Request(context.Background(), "UNKNOWN")
+	assert.ErrorIs(t, err, ErrInvalidCommand)
+}
+
+func TestClientClose(t *testing.T) {
+	_, host, port := testServer(t, core.NewWorld(3, 3), 1, nil)
+	c, err := NewClient(host, port)
+	require.NoError(t, err)
+
+	disconnects := make(chan error, 2)
+	c.OnDisconnect(func(err error) { disconnects <- err })
+	require.NoError(t, c.Close())
+	assert.Equal(t, ErrClosed, <-disconnects)
+	assert.Equal(t, ErrClosed, c.Err())
+	assert.ErrorIs(t, c.Fire(0, 0, 1, 0), ErrClosed)
+	assert.ErrorIs(t, c.Close(), ErrClosed)
+
+	// callback after the disconnect
+	c.OnDisconnect(func(err error) { disconnects <- err })
+	assert.Equal(t, ErrClosed, <-disconnects)
+}
+
+func TestClientTimeout(t *testing.T) {
+	host, port := fakeServer(t, func(line string) string {
+		switch line {
+		case "PROTOCOL 2":
+			return `{"OK":true,"Code":"OK"}`
+		case "PLAYER":
+			return `{"OK":true,"Code":"OK","Data":3}`
+		}
+		return "" // never answer
+	})
+	c, err := NewClient(host, port, WithTimeout(50*time.Millisecond))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(3), c.Player())
+
+	// context
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = c.MoveContext(ctx, 0, 0, 1, 0)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.ErrorIs(t, err, ErrDisconnected)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.ErrorIs(t, c.Move(0, 0, 1, 0), ErrDisconnected)
+}
+
+func TestClientProtocolError(t *testing.T) {
+	host, port := fakeServer(t, func(line string) string {
+		return "garbage"
+	})
+	_, err := NewClient(host, port)
+	assert.ErrorIs(t, err, ErrProtocol)
+}
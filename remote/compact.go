@@ -0,0 +1,251 @@
+package remote
+
+/*
+  This file provides the compact world encoding of the network protocol.
+  The static terrain is sent once with the command MAP (see MapInfo). After that, a compact STATUS
+  (see COMPACT command) only carries the units, the base owners, the changed terrain and the supply
+  and visibility of the player as dense grids with one character per tile.
+  Large payloads can additionally be compressed with gzip (see GZIP command).
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MapInfo is the static part of a world (see MAP command).
+// The grids contain one string per row (YRow) with one character per column (XCol).
+type MapInfo struct {
+	XWidth        int             // The width of the world in tiles.
+	YHeight       int             // The height of the world in tiles.
+	Terrain       []string        // Tile types as letters (see core.TILES).
+	ImageID       []string        // Image IDs as two hex digits per tile.
+	Reinforcement map[uint64]byte // Reinforcement for all players with a base (see core.World).
+	Rules         *core.Rules     `json:",omitempty"` // Rule overrides of the map (see core.Rules).
+}
+
+// CompactStatus is the dynamic part of a world for one player (see COMPACT command).
+// The grids contain one string per row (YRow) with one base36 digit per column (XCol).
+type CompactStatus struct {
+	Player     uint8              // Player of this view.
+	Iteration  uint64             // Current iteration (game time) of the world.
+	Freeze     bool               // Indicates if the world is frozen.
+	Result     *core.Result       `json:",omitempty"` // Result of a finished game.
+	Forfeits   []core.Forfeit     `json:",omitempty"` // Players who lost their seat (see core.Forfeit).
+	Units      []CompactUnit      // All visible units.
+	Terrain    []TerrainChange    // Tiles whose type differs from the MapInfo.
+	Owner      []string           // Owner of each tile.
+	Supply     []string           // Supply level of the player on each tile (0 = no supply).
+	Visibility []string           // Visibility of the player on each tile (FogOfWar, NormalView, CloseView).
+	Debug      []*core.DebugShape `json:",omitempty"` // Debug annotations of the player.
+}
+
+// CompactUnit is a unit with its position.
+type CompactUnit struct {
+	X int // Column of the grid
+	Y int // Row of the grid
+	core.Unit
+}
+
+// TerrainChange is a tile whose type has changed (e.g. by fire).
+type TerrainChange struct {
+	X    int    // Column of the grid
+	Y    int    // Row of the grid
+	Type string // New tile type as letter (see core.TILES)
+}
+
+//--------  Encoder  -------------------------------------------------------------------------------------------------//
+
+// NewMapInfo returns the static part of the world.
+func NewMapInfo(world *core.World) *MapInfo {
+	world = world.Clone() // independent copy
+	if world == nil {
+		return nil
+	}
+
+	m := &MapInfo{
+		XWidth:        world.XWidth,
+		YHeight:       world.YHeight,
+		Terrain:       make([]string, world.YHeight),
+		ImageID:       make([]string, world.YHeight),
+		Reinforcement: world.Reinforcement,
+		Rules:         world.Rules,
+	}
+	for y := 0; y < world.YHeight; y++ {
+		terrain := make([]byte, world.XWidth)
+		images := new(strings.Builder)
+		for x := 0; x < world.XWidth; x++ {
+			t := world.Tile(x, y)
+			if t == nil {
+				continue
+			}
+			terrain[x] = t.Type
+			_, _ = fmt.Fprintf(images, "%02x", t.ImageID)
+		}
+		m.Terrain[y] = string(terrain)
+		m.ImageID[y] = images.String()
+	}
+	return m
+}
+
+// NewCompactStatus returns the dynamic part of the world for the player.
+// The world must already be censored for the player (see core.Censorship).
+// Terrain changes are calculated in relation to the MapInfo sent to the player.
+func NewCompactStatus(world *core.World, m *MapInfo, player uint8) *CompactStatus {
+	cs := &CompactStatus{
+		Player:     player,
+		Iteration:  world.Iteration,
+		Freeze:     world.Freeze,
+		Result:     world.Result,
+		Forfeits:   world.Forfeits,
+		Units:      make([]CompactUnit, 0, 30),
+		Terrain:    make([]TerrainChange, 0),
+		Owner:      make([]string, world.YHeight),
+		Supply:     make([]string, world.YHeight),
+		Visibility: make([]string, world.YHeight),
+		Debug:      world.Debug[player],
+	}
+
+	for y := 0; y < world.YHeight; y++ {
+		owner := make([]byte, world.XWidth)
+		supply := make([]byte, world.XWidth)
+		visibility := make([]byte, world.XWidth)
+
+		for x := 0; x < world.XWidth; x++ {
+			t := world.Tile(x, y)
+			if t == nil {
+				continue
+			}
+
+			// grids
+			owner[x] = digit36(int(t.Owner))
+			supply[x] = digit36(t.Supply[player])
+			visibility[x] = digit36(t.Visibility[player])
+
+			// units
+			if t.Unit != nil {
+				cs.Units = append(cs.Units, CompactUnit{X: x, Y: y, Unit: *t.Unit})
+			}
+
+			// changed terrain
+			if m == nil || y >= len(m.Terrain) || x >= len(m.Terrain[y]) || m.Terrain[y][x] != t.Type {
+				cs.Terrain = append(cs.Terrain, TerrainChange{X: x, Y: y, Type: string(t.Type)})
+			}
+		}
+
+		cs.Owner[y] = string(owner)
+		cs.Supply[y] = string(supply)
+		cs.Visibility[y] = string(visibility)
+	}
+	return cs
+}
+
+//--------  Decoder  -------------------------------------------------------------------------------------------------//
+
+// World rebuilds the (censored) world of the player from the static map and a compact status.
+func (m *MapInfo) World(cs *CompactStatus) *core.World {
+	world := core.NewWorld(m.XWidth, m.YHeight)
+	world.Reinforcement = m.Reinforcement
+	world.Rules = m.Rules
+	world.Iteration = cs.Iteration
+	world.Freeze = cs.Freeze
+	world.Result = cs.Result
+	world.Forfeits = cs.Forfeits
+
+	// static map
+	for y := 0; y < m.YHeight && y < len(m.Terrain); y++ {
+		for x := 0; x < m.XWidth && x < len(m.Terrain[y]); x++ {
+			t := world.Tile(x, y)
+			t.Type = m.Terrain[y][x]
+			if y < len(m.ImageID) && 2*x+2 <= len(m.ImageID[y]) {
+				id, _ := strconv.ParseUint(m.ImageID[y][2*x:2*x+2], 16, 8)
+				t.ImageID = uint8(id)
+			}
+		}
+	}
+
+	// changed terrain
+	for _, c := range cs.Terrain {
+		if t := world.Tile(c.X, c.Y); t != nil && len(c.Type) == 1 {
+			t.Type = c.Type[0]
+		}
+	}
+
+	// grids
+	for y := 0; y < m.YHeight; y++ {
+		for x := 0; x < m.XWidth; x++ {
+			t := world.Tile(x, y)
+			t.Owner = uint8(gridValue(cs.Owner, x, y))
+			t.Supply[cs.Player] = gridValue(cs.Supply, x, y)
+			t.Visibility[cs.Player] = gridValue(cs.Visibility, x, y)
+		}
+	}
+
+	// units
+	for _, u := range cs.Units {
+		if t := world.Tile(u.X, u.Y); t != nil {
+			unit := u.Unit
+			t.Unit = &unit
+		}
+	}
+
+	// debug annotations
+	if len(cs.Debug) > 0 {
+		world.Debug = map[uint8][]*core.DebugShape{cs.Player: cs.Debug}
+	}
+	return world
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// digit36 encodes a value as a single base36 digit (values above 35 are limited to 35).
+func digit36(v int) byte {
+	if v < 0 {
+		v = 0
+	}
+	if v > 35 {
+		v = 35
+	}
+	return strconv.FormatInt(int64(v), 36)[0]
+}
+
+// gridValue decodes the base36 digit of a grid (0 if the grid is too small).
+func gridValue(grid []string, x, y int) int {
+	if y >= len(grid) || x >= len(grid[y]) {
+		return 0
+	}
+	v, _ := strconv.ParseInt(grid[y][x:x+1], 36, 64)
+	return int(v)
+}
+
+// gzipBase64 compresses the data with gzip and encodes it as base64 (a single line without line breaks).
+func gzipBase64(b []byte) (string, error) {
+	buf := new(bytes.Buffer)
+	zw := gzip.NewWriter(buf)
+	if _, err := zw.Write(b); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// gunzipBase64 decodes the base64 string and decompresses the gzip data (see gzipBase64).
+func gunzipBase64(s string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(zr)
+}
@@ -0,0 +1,142 @@
+package remote
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// compactWorld returns a small world with units, owners and images.
+func compactWorld() *core.World {
+	world := core.NewWorld(4, 3)
+	for y := 0; y < world.YHeight; y++ {
+		for x := 0; x < world.XWidth; x++ {
+			world.Tile(x, y).Type = core.GRASS
+		}
+	}
+	world.Tile(0, 0).Type = core.BASE
+	world.Tile(0, 0).Owner = core.RED
+	world.Tile(2, 1).Type = core.FOREST
+	world.Tile(3, 2).ImageID = 0xab
+	world.Tile(1, 1).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(1, 1).Visibility[core.RED] = core.CloseView
+	world.Tile(1, 1).Supply[core.RED] = 7
+	world.Update()
+	return world
+}
+
+func TestCompactRoundTrip(t *testing.T) {
+	world := compactWorld()
+	m := NewMapInfo(world)
+	assert.Equal(t, "BGGG", m.Terrain[0])
+	assert.Equal(t, "ab", m.ImageID[2][6:])
+
+	// terrain change after MAP
+	world.Tile(2, 1).Type = core.DIRT
+	require.NoError(t, world.AddDebug(core.RED, core.DebugShape{Kind: core.DebugMark, X: 1, Y: 1, Color: "red"}, 10))
+	require.NoError(t, world.AddDebug(core.BLUE, core.DebugShape{Kind: core.DebugMark, Color: "blue"}, 10))
+	censored := core.Censorship(world, core.RED)
+	cs := NewCompactStatus(censored, m, core.RED)
+	assert.Equal(t, []TerrainChange{{X: 2, Y: 1, Type: "D"}}, cs.Terrain)
+
+	// decode
+	decoded := m.World(cs)
+	assert.Equal(t, censored.Iteration, decoded.Iteration)
+	assert.Equal(t, censored.Debug, decoded.Debug)
+	assert.Len(t, decoded.Debug[core.RED], 1)
+	for y := 0; y < world.YHeight; y++ {
+		for x := 0; x < world.XWidth; x++ {
+			want, got := censored.Tile(x, y), decoded.Tile(x, y)
+			assert.Equal(t, want.Type, got.Type)
+			assert.Equal(t, want.ImageID, got.ImageID)
+			assert.Equal(t, want.Owner, got.Owner)
+			assert.Equal(t, want.Unit, got.Unit)
+			assert.Equal(t, want.Supply[core.RED], got.Supply[core.RED])
+			assert.Equal(t, want.Visibility[core.RED], got.Visibility[core.RED])
+		}
+	}
+}
+
+func TestCompactRules(t *testing.T) {
+	world := compactWorld()
+	world.Rules = &core.Rules{MaxSupply: 20, SupplySpeed: 2}
+	world.Forfeit(core.BLUE, reasonInactive, true)
+
+	// JSON round trip
+	b, err := json.Marshal(NewMapInfo(world))
+	require.NoError(t, err)
+	m := new(MapInfo)
+	require.NoError(t, json.Unmarshal(b, m))
+	b, err = json.Marshal(NewCompactStatus(core.Censorship(world, core.RED), m, core.RED))
+	require.NoError(t, err)
+	cs := new(CompactStatus)
+	require.NoError(t, json.Unmarshal(b, cs))
+
+	// decode
+	decoded := m.World(cs)
+	assert.Equal(t, world.Rules, decoded.Rules)
+	assert.Equal(t, 20, decoded.SupplyRange())
+	assert.Equal(t, float32(2), decoded.SupplyFactor())
+	assert.Equal(t, world.Clone().Forfeits, decoded.Forfeits)
+	assert.Len(t, decoded.Forfeits, 1)
+}
+
+func TestGzipBase64(t *testing.T) {
+	s, err := gzipBase64([]byte(`{"a":1}`))
+	require.NoError(t, err)
+	b, err := gunzipBase64(s)
+	require.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(b))
+
+	_, err = gunzipBase64("no base64!")
+	assert.Error(t, err)
+}
+
+func TestCompactCommands(t *testing.T) {
+	s, _, _ := testServer(t, compactWorld(), 1, nil)
+	rc := dialRaw(t, s.Addr().String())
+	require.True(t, rc.cmdV2("PROTOCOL 2").OK)
+
+	// compact STATUS requires MAP
+	assert.Equal(t, CodeInvalidArgs, rc.cmdV2("COMPACT YES").Code)
+	assert.True(t, rc.cmdV2("COMPACT ON").OK)
+	assert.Equal(t, CodeNoMap, rc.cmdV2("STATUS").Code)
+
+	// map
+	resp := rc.cmdV2("MAP")
+	require.True(t, resp.OK)
+	m := new(MapInfo)
+	require.NoError(t, json.Unmarshal(resp.Data, m))
+	assert.Equal(t, 4, m.XWidth)
+
+	// compressed compact status
+	assert.True(t, rc.cmdV2("GZIP ON").OK)
+	resp = rc.cmdV2("STATUS")
+	require.True(t, resp.OK)
+	var data string
+	require.NoError(t, json.Unmarshal(resp.Data, &data))
+	b, err := gunzipBase64(data)
+	require.NoError(t, err)
+	cs := new(CompactStatus)
+	require.NoError(t, json.Unmarshal(b, cs))
+	assert.Equal(t, uint8(core.RED), cs.Player)
+	require.Len(t, cs.Units, 1)
+	assert.Equal(t, 1, cs.Units[0].X)
+}
+
+func TestClientUseCompact(t *testing.T) {
+	_, host, port := testServer(t, compactWorld(), 1, nil)
+	c, err := NewClient(host, port)
+	require.NoError(t, err)
+	require.NoError(t, c.UseCompact(true))
+	require.NoError(t, c.updateWorld())
+
+	world := c.Snapshot()
+	assert.Equal(t, byte(core.BASE), world.Tile(0, 0).Type)
+	require.NotNil(t, world.Tile(1, 1).Unit)
+	assert.Equal(t, byte(core.TANK), world.Tile(1, 1).Unit.Type)
+	assert.Equal(t, uint8(core.RED), world.Tile(0, 0).Owner)
+}
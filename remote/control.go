@@ -0,0 +1,152 @@
+package remote
+
+/*
+  This file provides the CONTROL command for shared seats. Several connections can control the same player
+  (see Server.SeatConnections), e.g. one bot for the artillery and another one for the soldiers. Every
+  connection can restrict itself to unit types and unit IDs with 'CONTROL A,U' or 'CONTROL T,123';
+  'CONTROL ALL' removes the restriction. The server can also bind a restriction to a seat password
+  (see SeatControl), which the connection can't change. Commands for other units are rejected with
+  ErrNotControlled when they start (see core.UnitFilter) and RESULTS only returns the outcomes of the
+  controlled units. All connections of a seat get the same view.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errors of the restrictions
+var (
+	ErrNotControlled = errors.New("unit is not controlled by this connection") // see CONTROL
+	ErrControlBound  = errors.New("restriction is set by the server")          // see SeatControl
+)
+
+// SeatControl is a password of a shared seat with a fixed restriction (see Server.SeatControls).
+// A connection with 'AUTH seat password' controls only these units and can't change it with CONTROL.
+type SeatControl struct {
+	Seat     uint8  // Player ID
+	Password string // Password of AUTH
+	Units    string // Controlled unit types and IDs, e.g. "A,U" (see CONTROL)
+}
+
+// control is the restriction of a connection to some units of the player (nil = all units).
+type control struct {
+	types map[byte]bool // Controlled unit types (see core.UNITS)
+	ids   map[int]bool  // Controlled unit IDs
+}
+
+// parseControl parses a comma-separated list of unit types and unit IDs (e.g. "A,U,123").
+// "ALL" returns nil (no restriction).
+func parseControl(s string) (*control, error) {
+	if strings.EqualFold(s, "ALL") {
+		return nil, nil
+	}
+
+	c := &control{types: make(map[byte]bool), ids: make(map[int]bool)}
+	for _, item := range strings.Split(s, ",") {
+		if id, err := strconv.Atoi(item); err == nil {
+			c.ids[id] = true
+			continue
+		}
+		item = strings.ToUpper(item)
+		if len(item) != 1 || !strings.Contains(string(core.UNITS), item) {
+			return nil, fmt.Errorf("%q is neither a unit type (%s) nor a unit ID", item, string(core.UNITS))
+		}
+		c.types[item[0]] = true
+	}
+	return c, nil
+}
+
+// allows reports whether the unit is controlled.
+func (c *control) allows(unit *core.Unit) bool {
+	return c == nil || c.types[unit.Type] || c.ids[unit.ID]
+}
+
+// matches reports whether the outcome belongs to a controlled unit (see RESULTS).
+func (c *control) matches(o *core.Outcome) bool {
+	return c == nil || c.types[o.Type] || c.ids[o.Unit]
+}
+
+// filter returns the check of the units when a command starts (nil = all units, see core.UnitFilter).
+func (c *control) filter() core.UnitFilter {
+	if c == nil {
+		return nil
+	}
+	return func(unit *core.Unit) error {
+		if !c.allows(unit) {
+			return ErrNotControlled
+		}
+		return nil
+	}
+}
+
+// String returns the restriction in the syntax of CONTROL (types first, then IDs).
+func (c *control) String() string {
+	if c == nil {
+		return "ALL"
+	}
+	var list []string
+	for _, typ := range core.UNITS {
+		if c.types[typ] {
+			list = append(list, string(typ))
+		}
+	}
+	ids := make([]int, 0, len(c.ids))
+	for id := range c.ids {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		list = append(list, strconv.Itoa(id))
+	}
+	return strings.Join(list, ",")
+}
+
+//--------  Session  -------------------------------------------------------------------------------------------------//
+
+// setControl changes the restriction of the connection (see CONTROL).
+// A restriction of the server can't be changed (see SeatControl).
+func (ss *session) setControl(cmd *Command) {
+	if ss.bound {
+		ss.replyError(CodeNotControlled, ErrControlBound.Error(), map[string]interface{}{"Control": ss.control.String()})
+		return
+	}
+	c, err := parseControl(cmd.Arg(0))
+	if err != nil {
+		ss.replyError(CodeInvalidArgs, err.Error(), map[string]interface{}{"Usage": gameGrammar["CONTROL"].usage})
+		return
+	}
+	ss.control = c
+	text := c.String()
+	ss.reply(text, []byte(strconv.Quote(text)))
+}
+
+// controls checks the unit of a scheduled command when it is queued and rejects the command if the unit is not
+// controlled by the connection. Missing units and units of other players are left to the game world (see ErrNoUnit).
+// The unit is checked again when the command starts (see control.filter).
+func (ss *session) controls(cmd *Command) bool {
+	if ss.control == nil || cmd.At == 0 {
+		return true
+	}
+
+	// find unit
+	var unit *core.Unit
+	switch cmd.Name {
+	case core.FIRE, core.MOVE:
+		unit = ss.world.UnitAt(ss.world.Tile(cmd.Int(0), cmd.Int(1)))
+	case "UFIRE", "UMOVE":
+		unit = ss.world.UnitByID(cmd.Int(0))
+	default:
+		return true // no unit command
+	}
+	if unit == nil || unit.Player != ss.player || ss.control.allows(unit) {
+		return true
+	}
+
+	ss.replyCommand(ErrNotControlled, nil)
+	return false
+}
@@ -0,0 +1,134 @@
+package remote
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseControl(t *testing.T) {
+	c, err := parseControl("all")
+	require.NoError(t, err)
+	assert.Nil(t, c)
+	assert.Equal(t, "ALL", c.String())
+	assert.True(t, c.allows(&core.Unit{Type: core.TANK}))
+
+	c, err = parseControl("u,123,A")
+	require.NoError(t, err)
+	assert.Equal(t, "A,U,123", c.String())
+	assert.True(t, c.allows(&core.Unit{Type: core.SOLDIER}))
+	assert.True(t, c.allows(&core.Unit{Type: core.TANK, ID: 123}))
+	assert.False(t, c.allows(&core.Unit{Type: core.TANK, ID: 7}))
+	assert.True(t, c.matches(&core.Outcome{Unit: 123}))
+	assert.False(t, c.matches(&core.Outcome{Type: core.TANK}))
+
+	_, err = parseControl("A,X")
+	assert.EqualError(t, err, `"X" is neither a unit type (AUT) nor a unit ID`)
+	_, err = parseControl("A,,U")
+	assert.Error(t, err)
+}
+
+func TestSharedSeat(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	tank := core.NewUnit(core.RED, core.TANK)
+	world.Tile(1, 1).Unit = tank
+	world.Tile(3, 3).Unit = core.NewUnit(core.RED, core.ARTILLERY)
+	s, host, port := testServer(t, world, 1, func(s *Server) {
+		s.SeatPasswords = map[uint8]string{1: "red"}
+		s.SeatConnections = 2
+	})
+
+	// two connections share the seat
+	artillery := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", artillery.cmd("AUTH 1 red"))
+	tanks := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", tanks.cmd("AUTH 1 red"))
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "err: seat is taken", rc.cmd("AUTH 1 red"))
+	assert.Len(t, s.Players(), 2)
+
+	// restrictions
+	assert.Equal(t, "A", artillery.cmd("CONTROL a"))
+	assert.Equal(t, "T", tanks.cmd("CONTROL T"))
+	assert.Equal(t, ErrNotControlled.Error(), artillery.cmd("MOVE 1 1 2 1"))
+	assert.Equal(t, ErrNotControlled.Error(), tanks.cmd("MOVE 3 3 3 4 @100")) // scheduled
+	assert.Equal(t, "no player unit found", artillery.cmd("MOVE 0 0 1 0"))    // no unit
+	require.True(t, tanks.cmdV2("PROTOCOL 2").OK)
+	assert.Equal(t, CodeNotControlled, tanks.cmdV2(fmt.Sprintf("UMOVE %d 3 4", world.Tile(3, 3).Unit.ID)).Code)
+	assert.Equal(t, CodeInvalidArgs, tanks.cmdV2("CONTROL X").Code)
+	assert.True(t, tanks.cmdV2("MOVE 1 1 2 1").OK)
+
+	// outcomes of the own units
+	for i := 0; i < 3; i++ {
+		world.Update()
+	}
+	assert.Equal(t, "[]", artillery.cmd("RESULTS"))
+	var list []*core.Outcome
+	require.NoError(t, json.Unmarshal(tanks.cmdV2("RESULTS").Data, &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, tank.ID, list[0].Unit)
+
+	// same view
+	assert.Equal(t, artillery.cmd("STATUS"), string(tanks.cmdV2("STATUS").Data))
+
+	// client option
+	_ = rc.conn.Close()
+	_ = artillery.conn.Close()
+	assert.Eventually(t, func() bool { return len(s.Players()) == 1 }, time.Second, 10*time.Millisecond)
+	_, err := NewClient(host, port, WithSeat(1, "red"), WithControl("X"))
+	assert.ErrorIs(t, err, ErrInvalidArgs)
+	assert.Eventually(t, func() bool { return len(s.Players()) == 1 }, time.Second, 10*time.Millisecond)
+	c, err := NewClient(host, port, WithSeat(1, "red"), WithControl("U"))
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), c.Player())
+	assert.ErrorIs(t, c.MoveUnit(tank.ID, 3, 1), ErrNotControlled)
+}
+
+func TestSeatControl(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(1, 1).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(3, 3).Unit = core.NewUnit(core.RED, core.ARTILLERY)
+	s, _, _ := testServer(t, world, 1, func(s *Server) {
+		s.SeatConnections = 2
+		s.SeatControls = []SeatControl{{Seat: 1, Password: "art", Units: "A"}, {Seat: 1, Password: "tank", Units: "T"}}
+	})
+
+	// the restriction is bound to the password
+	artillery := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "err: authentication failed", artillery.cmd("AUTH 1 wrong"))
+	assert.Equal(t, "1", artillery.cmd("AUTH 1 art"))
+	assert.Equal(t, "err: "+ErrControlBound.Error(), artillery.cmd("CONTROL ALL"))
+	assert.Equal(t, ErrNotControlled.Error(), artillery.cmd("MOVE 1 1 2 1"))
+	assert.Equal(t, "OK", artillery.cmd("MOVE 3 3 3 4"))
+	tanks := dialRaw(t, s.Addr().String())
+	require.True(t, tanks.cmdV2("PROTOCOL 2").OK)
+	assert.True(t, tanks.cmdV2("AUTH 1 tank").OK)
+	resp := tanks.cmdV2("CONTROL A,T")
+	assert.Equal(t, CodeNotControlled, resp.Code)
+	assert.Equal(t, "T", resp.Details["Control"])
+
+	// bound seats are never assigned automatically
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "2", rc.cmd("AUTH x"))
+
+	// the global password doesn't open a bound seat
+	s, _, _ = testServer(t, world, 2, func(s *Server) {
+		s.Password = "global"
+		s.SeatConnections = 2
+		s.SeatControls = []SeatControl{{Seat: 1, Password: "art", Units: "A"}}
+	})
+	global := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "err: authentication failed", global.cmd("AUTH 1 global"))
+	assert.Equal(t, "2", global.cmd("AUTH global")) // next free seat
+
+	// invalid configuration
+	s = NewServer("127.0.0.1:0", core.NewWorld(3, 3), 1)
+	s.SeatControls = []SeatControl{{Seat: 1, Password: "pw", Units: "X"}}
+	assert.Error(t, s.Start(context.Background()))
+}
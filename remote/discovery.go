@@ -0,0 +1,173 @@
+package remote
+
+/*
+  This file provides the LAN discovery of servers. A server with an announce address (see Server.Announce)
+  sends a UDP packet with its port, map, free seats and protocol version every second, usually as broadcast
+  to 255.255.255.255:41234. Discover listens for these packets and returns the found servers. The host of a
+  server is the sender of the packet, so the server can listen on any address.
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// DiscoveryPort is the default UDP port of the LAN announcements.
+const DiscoveryPort = 41234
+
+// announcePrefix is the first part of every announcement (other packets are ignored).
+var announcePrefix = []byte("TANKWARS2 ")
+
+// announceInterval is the time between two announcements.
+const announceInterval = time.Second
+
+// ServerInfo is the announcement of a server in the local network (see Discover).
+type ServerInfo struct {
+	Host      string `json:",omitempty"` // Host of the server (sender of the announcement)
+	Port      string // TCP port of the server
+	Map       string // Name of the map (see Server.MapName)
+	Players   int    // Number of connected players
+	MaxPlayer int    // Number of players required to start the game
+	FreeSeats int    // Number of seats that can be taken
+	Started   bool   // Indicates if the game has started
+	Protocol  int    // Highest supported protocol version (see ProtocolV2)
+	Auth      bool   // Indicates if a password is required (see AUTH)
+	TLS       bool   // Indicates if the server uses TLS
+}
+
+// Addr returns the TCP address (host:port) of the server.
+func (i ServerInfo) Addr() string {
+	return net.JoinHostPort(i.Host, i.Port)
+}
+
+// Discover listens on the UDP address (e.g. ":41234") until the context is done
+// and returns all announced servers sorted by address.
+func Discover(ctx context.Context, addr string) ([]ServerInfo, error) {
+	conn, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	defer func(conn net.PacketConn) {
+		_ = conn.Close()
+	}(conn)
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetReadDeadline(time.Now()) // stop reading
+	})
+	defer stop()
+
+	// read announcements
+	found := make(map[string]ServerInfo)
+	buf := make([]byte, 2048)
+	for ctx.Err() == nil {
+		n, from, err := conn.ReadFrom(buf)
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				break // context done
+			}
+			return nil, err
+		}
+		if info, ok := parseAnnouncement(buf[:n], from); ok {
+			found[info.Addr()] = info // keep the latest
+		}
+	}
+
+	// sort
+	list := make([]ServerInfo, 0, len(found))
+	for _, info := range found {
+		list = append(list, info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Addr() < list[j].Addr()
+	})
+	return list, nil
+}
+
+//--------  Server  --------------------------------------------------------------------------------------------------//
+
+// announce sends the announcements of the server until it is closed.
+func (s *Server) announce(port string) {
+	defer s.wg.Done()
+
+	conn, err := net.Dial("udp", s.Announce)
+	if err != nil {
+		s.logf("err: announce: %v\n", err)
+		return
+	}
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	ticker := time.NewTicker(announceInterval)
+	defer ticker.Stop()
+	for {
+		b, _ := json.Marshal(s.serverInfo(port))
+		_, _ = conn.Write(append(announcePrefix, b...)) // errors are repeated every second
+
+		select {
+		case <-s.done:
+			return // EXIT
+		case <-ticker.C:
+		}
+	}
+}
+
+// serverInfo returns the current announcement of the server.
+func (s *Server) serverInfo(port string) ServerInfo {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	// taken seats
+	taken := make(map[uint8]bool)
+	for _, player := range s.conns {
+		if player != 0 {
+			taken[player] = true
+		}
+	}
+	free := 0
+	for id := 1; id <= s.maxPlayer; id++ {
+		if !taken[uint8(id)] && !s.lost[uint8(id)] {
+			free++
+		}
+	}
+
+	return ServerInfo{
+		Port:      port,
+		Map:       s.MapName,
+		Players:   len(taken),
+		MaxPlayer: s.maxPlayer,
+		FreeSeats: free,
+		Started:   s.started,
+		Protocol:  ProtocolV2,
+		Auth:      s.authRequired(),
+		TLS:       s.TLSConfig != nil,
+	}
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// parseAnnouncement parses an announcement and sets the host to the sender.
+func parseAnnouncement(b []byte, from net.Addr) (ServerInfo, bool) {
+	var info ServerInfo
+	if !bytes.HasPrefix(b, announcePrefix) {
+		return info, false
+	}
+	if err := json.Unmarshal(b[len(announcePrefix):], &info); err != nil {
+		return info, false
+	}
+	if _, err := strconv.ParseUint(info.Port, 10, 16); err != nil {
+		return info, false
+	}
+	udp, ok := from.(*net.UDPAddr)
+	if !ok {
+		return info, false
+	}
+	info.Host = udp.IP.String()
+	return info, true
+}
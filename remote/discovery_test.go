@@ -0,0 +1,83 @@
+package remote
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// freeUDPAddr returns a free local UDP address.
+func freeUDPAddr(t *testing.T) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := conn.LocalAddr().String()
+	require.NoError(t, conn.Close())
+	return addr
+}
+
+func TestDiscover(t *testing.T) {
+	addr := freeUDPAddr(t)
+
+	// listen before the first announcement
+	type result struct {
+		list []ServerInfo
+		err  error
+	}
+	found := make(chan result, 1)
+	ctx, cancel := context.WithTimeout(context.Background(), announceInterval+500*time.Millisecond)
+	defer cancel()
+	go func() {
+		list, err := Discover(ctx, addr)
+		found <- result{list, err}
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	// server with one player
+	s, host, port := testServer(t, core.NewWorld(3, 3), 2, func(s *Server) {
+		s.Announce = addr
+		s.MapName = "map01.json"
+		s.Password = "secret"
+	})
+	_, err := NewClient(host, port, WithPassword("secret"))
+	require.NoError(t, err)
+
+	// latest announcement
+	r := <-found
+	require.NoError(t, r.err)
+	require.Len(t, r.list, 1)
+	assert.Equal(t, ServerInfo{
+		Host:      "127.0.0.1",
+		Port:      port,
+		Map:       "map01.json",
+		Players:   1,
+		MaxPlayer: 2,
+		FreeSeats: 1,
+		Protocol:  ProtocolV2,
+		Auth:      true,
+	}, r.list[0])
+	assert.Equal(t, s.Addr().String(), r.list[0].Addr())
+
+	// error
+	_, err = Discover(context.Background(), "no-address")
+	assert.Error(t, err)
+}
+
+func TestParseAnnouncement(t *testing.T) {
+	from := &net.UDPAddr{IP: net.ParseIP("192.168.1.5"), Port: 5000}
+	info, ok := parseAnnouncement([]byte(`TANKWARS2 {"Port":"1234","Map":"x","MaxPlayer":2}`), from)
+	assert.True(t, ok)
+	assert.Equal(t, "192.168.1.5:1234", info.Addr())
+
+	// invalid packets
+	_, ok = parseAnnouncement([]byte(`{"Port":"1234"}`), from)
+	assert.False(t, ok)
+	_, ok = parseAnnouncement([]byte(`TANKWARS2 {"Port":"x"}`), from)
+	assert.False(t, ok)
+	_, ok = parseAnnouncement([]byte(`TANKWARS2 {`), from)
+	assert.False(t, ok)
+}
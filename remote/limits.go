@@ -0,0 +1,124 @@
+package remote
+
+/*
+  This file provides the rate limits of a client connection. A misbehaving client should not be able to
+  stall the simulation for everyone: Every STATUS clones the world under the world lock (see core.Censorship)
+  and every command competes with the Update function for the same lock.
+*/
+
+import (
+	"bufio"
+	"errors"
+	"time"
+)
+
+// errors of the rate limits
+var (
+	ErrThrottled   = errors.New("throttled")     // too many commands (see Limits)
+	ErrLineTooLong = errors.New("line too long") // command line exceeds Limits.MaxLineLength
+)
+
+// Limits are the rate limits and timeouts of a single client connection (0 means no limit).
+type Limits struct {
+	CommandsPerIteration int           // Max. number of commands per world iteration or second while frozen (STATUS excluded)
+	StatusPerSecond      int           // Max. number of STATUS, MAP and query commands per second (each clones the world)
+	MaxLineLength        int           // Max. length of a command line in bytes
+	IdleTimeout          time.Duration // Max. time without a command before the connection is closed
+}
+
+// DefaultLimits are used by NewServer.
+// They are high enough for the GUI client (10 STATUS per second) and the basic AI.
+var DefaultLimits = Limits{
+	CommandsPerIteration: 30,
+	StatusPerSecond:      30,
+	MaxLineLength:        4096,
+	IdleTimeout:          5 * time.Minute,
+}
+
+// Usage counts the commands of a client connection.
+type Usage struct {
+	Commands  int // Accepted commands
+	Status    int // Accepted STATUS, MAP and query commands
+	Throttled int // Rejected commands (see ErrThrottled and ErrLineTooLong)
+}
+
+// limiter enforces the Limits of a client connection.
+type limiter struct {
+	limits   Limits
+	usage    Usage
+	window   commandWindow // Current command window
+	commands int           // Commands in the current window
+	second   int64         // Second of the current status window
+	status   int           // STATUS requests in the current second
+}
+
+// commandWindow is the window of Limits.CommandsPerIteration: an iteration or a second of a frozen world.
+type commandWindow struct {
+	iteration uint64 // Iteration of the world
+	second    int64  // Second while the world is frozen (0 = running)
+}
+
+// allowCommand counts a command in the given iteration and reports whether it is within the limit.
+// The iteration doesn't change while the world is frozen (waiting for players or paused),
+// so the limit applies per second instead.
+func (l *limiter) allowCommand(iteration uint64, frozen bool, now time.Time) bool {
+	window := commandWindow{iteration: iteration}
+	if frozen {
+		window.second = now.Unix()
+	}
+	if l.window != window {
+		l.window = window
+		l.commands = 0
+	}
+	if l.limits.CommandsPerIteration > 0 && l.commands >= l.limits.CommandsPerIteration {
+		l.usage.Throttled++
+		return false
+	}
+	l.commands++
+	l.usage.Commands++
+	return true
+}
+
+// allowStatus counts a STATUS request at the given time and reports whether it is within the limit.
+func (l *limiter) allowStatus(now time.Time) bool {
+	if sec := now.Unix(); l.second != sec {
+		l.second = sec
+		l.status = 0
+	}
+	if l.limits.StatusPerSecond > 0 && l.status >= l.limits.StatusPerSecond {
+		l.usage.Throttled++
+		return false
+	}
+	l.status++
+	l.usage.Status++
+	return true
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// readLine reads one line (ended with \n or \r\n) with a max. length in bytes (0 means no limit).
+// A longer line is consumed completely and ErrLineTooLong is returned.
+func readLine(r *bufio.Reader, maxLength int) (string, error) {
+	var line []byte
+	tooLong := false
+	for {
+		part, more, err := r.ReadLine()
+		if err != nil {
+			return "", err
+		}
+		if !tooLong {
+			line = append(line, part...)
+			if maxLength > 0 && len(line) > maxLength {
+				tooLong = true
+				line = nil
+			}
+		}
+		if !more {
+			break
+		}
+	}
+	if tooLong {
+		return "", ErrLineTooLong
+	}
+	return string(line), nil
+}
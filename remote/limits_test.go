@@ -0,0 +1,132 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiter(t *testing.T) {
+	l := &limiter{limits: Limits{CommandsPerIteration: 2, StatusPerSecond: 1}}
+
+	// commands per iteration
+	now := time.Unix(100, 0)
+	assert.True(t, l.allowCommand(5, false, now))
+	assert.True(t, l.allowCommand(5, false, now))
+	assert.False(t, l.allowCommand(5, false, now.Add(time.Second)))
+	assert.True(t, l.allowCommand(6, false, now))
+
+	// commands per second while frozen
+	assert.True(t, l.allowCommand(6, true, now))
+	assert.True(t, l.allowCommand(6, true, now))
+	assert.False(t, l.allowCommand(6, true, now.Add(500*time.Millisecond)))
+	assert.True(t, l.allowCommand(6, true, now.Add(time.Second)))
+
+	// status per second
+	assert.True(t, l.allowStatus(now))
+	assert.False(t, l.allowStatus(now.Add(500*time.Millisecond)))
+	assert.True(t, l.allowStatus(now.Add(time.Second)))
+
+	assert.Equal(t, Usage{Commands: 6, Status: 2, Throttled: 3}, l.usage)
+
+	// no limits
+	l = &limiter{}
+	for i := 0; i < 100; i++ {
+		assert.True(t, l.allowCommand(0, false, now))
+	}
+}
+
+func TestReadLine(t *testing.T) {
+	r := bufio.NewReaderSize(strings.NewReader("short\r\n"+strings.Repeat("x", 100)+"\nnext\n"), 16)
+
+	line, err := readLine(r, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "short", line)
+
+	_, err = readLine(r, 10)
+	assert.ErrorIs(t, err, ErrLineTooLong)
+
+	line, err = readLine(r, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, "next", line)
+
+	_, err = readLine(r, 10)
+	assert.ErrorIs(t, err, io.EOF)
+}
+
+func TestThrottle(t *testing.T) {
+	logs := new(bytes.Buffer)
+	left := make(chan bool, 1)
+	s, _, _ := testServer(t, core.NewWorld(3, 3), 1, func(s *Server) {
+		s.Limits = Limits{CommandsPerIteration: 2, StatusPerSecond: 100, MaxLineLength: 20}
+		s.Logger = log.New(logs, "", 0)
+		s.OnDisconnect = func(uint8, net.Addr) { left <- true }
+	})
+	rc := dialRaw(t, s.Addr().String())
+	require.True(t, rc.cmdV2("PROTOCOL 2").OK)
+
+	// nobody calls Update, so all commands are in the same iteration
+	assert.True(t, rc.cmdV2("PLAYER").OK)
+	resp := rc.cmdV2("PLAYER")
+	assert.Equal(t, CodeThrottled, resp.Code)
+	assert.Equal(t, "commands per iteration", resp.Details["Limit"])
+	assert.ErrorIs(t, responseError(resp), ErrThrottled)
+
+	// STATUS has its own limit
+	assert.True(t, rc.cmdV2("DIST 0 0 1 1").OK)
+
+	// line length
+	resp = rc.cmdV2(strings.Repeat("A", 30))
+	assert.Equal(t, CodeLineTooLong, resp.Code)
+
+	// usage in the server log
+	_ = rc.conn.Close()
+	select {
+	case <-left:
+	case <-time.After(time.Second):
+		t.Fatal("client not disconnected")
+	}
+	assert.Contains(t, logs.String(), "player 1 throttled: commands per iteration")
+	assert.Contains(t, logs.String(), "player 1 has left (2 commands, 1 status, 2 throttled)")
+}
+
+func TestThrottleFrozen(t *testing.T) {
+	s, _, _ := testServer(t, core.NewWorld(3, 3), 2, func(s *Server) {
+		s.Limits = Limits{CommandsPerIteration: 30}
+	})
+	rc := dialRaw(t, s.Addr().String())
+
+	// the world is frozen until player 2 joins (start at a new second)
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	for n := 0; n < 30; n++ {
+		assert.Equal(t, "1", rc.cmd("PLAYER"))
+	}
+	assert.Equal(t, "err: throttled", rc.cmd("PLAYER"))
+
+	// the next second has a new window
+	time.Sleep(time.Until(time.Now().Truncate(time.Second).Add(time.Second)))
+	assert.Equal(t, "1", rc.cmd("PLAYER"))
+	assert.False(t, s.Started())
+}
+
+func TestIdleTimeout(t *testing.T) {
+	s, _, _ := testServer(t, core.NewWorld(3, 3), 1, func(s *Server) {
+		s.Limits.IdleTimeout = 50 * time.Millisecond
+	})
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", rc.cmd("PLAYER"))
+
+	// the server closes the idle connection
+	_ = rc.conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, err := rc.tp.ReadLine()
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -0,0 +1,357 @@
+package remote
+
+/*
+  This file provides a lobby server that hosts several games on one port.
+  Clients list the games, create new games from the map directory and join them. A game created with a password
+  is private and can only be joined with this password.
+  Each game runs its own world loop and is removed from the lobby when it ends. A game that nobody joins
+  is closed after an idle timeout and the number of open games is limited, so clients can't exhaust the server.
+*/
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/maps"
+	"log"
+	"net"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// limits of a lobby server
+const (
+	maxLobbyPlayer   = 49              // Max. number of connections (players and observers) per game
+	maxLobbyGames    = 20              // Max. number of open games
+	lobbyIdleTimeout = 5 * time.Minute // Max. time of a game that nobody has joined
+)
+
+// GameInfo describes a game hosted by a lobby server (see RunLobby).
+type GameInfo struct {
+	ID        int    // Game identifier (see JOIN).
+	Map       string // Map file of the game.
+	Players   int    // Number of connected clients.
+	MaxPlayer int    // Number of players required to start the game.
+	Running   bool   // Indicates if the game has started.
+	Private   bool   // Indicates if the game requires a password (see JOIN).
+}
+
+// lobby manages all games of a lobby server.
+type lobby struct {
+	mapDir      string        // Directory with the map files.
+	maxGames    int           // Max. number of open games (see maxLobbyGames).
+	idleTimeout time.Duration // Max. time of a game that nobody has joined (see lobbyIdleTimeout).
+
+	mux    sync.Mutex     // Mutex for thread-safe operations
+	games  map[int]*match // All open games by ID.
+	nextID int            // Last assigned game ID.
+}
+
+// match is a single game hosted by the lobby.
+type match struct {
+	id          int           // Game identifier.
+	mapName     string        // Map file of the game.
+	server      *Server       // Server of this game (without listener).
+	created     time.Time     // Creation time of the game (see idleTimeout).
+	idleTimeout time.Duration // Max. time of the game without any player.
+	password    string        // Password of a private game (empty = public game).
+
+	mux   sync.Mutex // Mutex for thread-safe operations
+	ended bool       // Indicates if the game has ended.
+}
+
+// RunLobby runs a lobby server (BLOCKING!).
+// Clients can list the open games (LIST), create a new game with a map from mapDir (CREATE <map> [password])
+// and join a game (JOIN <game-id> [password]). After joining, the connection is handled by the Server of
+// the game. Each game starts as soon as all its players are connected.
+// A game that nobody joins is closed after 5 minutes and at most 20 games can be open.
+func RunLobby(host, port, mapDir string) {
+
+	// Listen for incoming connections.
+	l, err := net.Listen("tcp", host+":"+port)
+	if err != nil {
+		log.Fatalf("RunLobby: %v\n", err)
+	}
+
+	// Close the listener when the application closes.
+	defer func(l net.Listener) {
+		_ = l.Close()
+	}(l)
+
+	// start lobby
+	fmt.Println("START LOBBY [" + host + ":" + port + "]")
+	ServeLobby(l, mapDir)
+}
+
+// ServeLobby runs a lobby server like RunLobby on the given listener until it is closed (BLOCKING!),
+// e.g. on a TLS listener (see tls.Listen).
+func ServeLobby(l net.Listener, mapDir string) {
+	newLobby(mapDir).serve(l)
+}
+
+//--------  Lobby  ---------------------------------------------------------------------------------------------------//
+
+// newLobby creates a lobby with the default limits.
+func newLobby(mapDir string) *lobby {
+	return &lobby{
+		mapDir:      mapDir,
+		maxGames:    maxLobbyGames,
+		idleTimeout: lobbyIdleTimeout,
+		games:       make(map[int]*match),
+	}
+}
+
+// serve accepts lobby connections until the listener is closed.
+func (lb *lobby) serve(l net.Listener) {
+	for {
+		// wait for incoming connection
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return // EXIT
+		}
+		if err != nil {
+			fmt.Println("Error accepting: ", err.Error())
+			continue
+		}
+
+		// Handle connections in a new goroutine.
+		go lb.handleLobby(conn)
+	}
+}
+
+// handleLobby handles the lobby commands of a client until it joins a game.
+func (lb *lobby) handleLobby(conn net.Conn) {
+
+	// prepare line reader
+	tp := textproto.NewReader(bufio.NewReader(conn))
+
+	// close at end
+	defer func(conn net.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	// loop
+	failures := 0
+	for {
+		// close idle connections
+		_ = conn.SetReadDeadline(time.Now().Add(DefaultLimits.IdleTimeout))
+
+		// read one line (ended with \n or \r\n)
+		line, err := readLine(tp.R, DefaultLimits.MaxLineLength)
+		if errors.Is(err, ErrLineTooLong) {
+			comResponse(conn, "err: "+err.Error())
+			continue
+		}
+		if err != nil {
+			return // EXIT
+		}
+
+		// parse command
+		cmd, err := parseCommand(line, lobbyGrammar)
+		if err != nil {
+			comResponse(conn, "err: "+err.Error())
+			continue
+		}
+
+		// CHECK COMMANDS
+		switch cmd.Name {
+		case "LIST":
+			b, _ := json.Marshal(lb.list())
+			comResponse(conn, string(b))
+
+		case "CREATE":
+			m, err := lb.create(cmd.Arg(0), cmd.Arg(1))
+			if err != nil {
+				comResponse(conn, "err: "+err.Error())
+			} else {
+				comResponse(conn, strconv.Itoa(m.id))
+			}
+
+		case "JOIN":
+			m := lb.game(cmd.Int(0))
+			if m == nil {
+				comResponse(conn, "err: game not found")
+				continue
+			}
+			if subtle.ConstantTimeCompare([]byte(m.password), []byte(cmd.Arg(1))) != 1 {
+				comResponse(conn, "err: "+ErrAuthFailed.Error())
+				if failures++; failures >= maxAuthFailures {
+					return // EXIT
+				}
+				continue
+			}
+			ss := m.server.newSession(conn, tp)
+			ss.player, err = m.join(conn)
+			if err != nil {
+				comResponse(conn, "err: "+err.Error())
+				continue
+			}
+			comResponse(conn, strconv.Itoa(int(ss.player)))
+
+			// play the game
+			_ = conn.SetReadDeadline(time.Time{})
+			m.server.handle(ss)
+			return // EXIT
+
+		default:
+			comResponse(conn, "err: invalid command")
+		}
+	}
+}
+
+// list returns all open games sorted by ID.
+func (lb *lobby) list() []GameInfo {
+	lb.mux.Lock()
+	defer lb.mux.Unlock()
+
+	list := make([]GameInfo, 0, len(lb.games))
+	for _, m := range lb.games {
+		list = append(list, m.info())
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list
+}
+
+// game returns the open game with the given ID or nil.
+func (lb *lobby) game(id int) *match {
+	lb.mux.Lock()
+	defer lb.mux.Unlock()
+
+	return lb.games[id]
+}
+
+// create loads the map from the map directory and starts a new game (private with a password).
+// Only the file name of the map is used, so no files outside the map directory can be loaded.
+func (lb *lobby) create(mapName, password string) (*match, error) {
+
+	// check map name
+	if mapName == "" {
+		return nil, errors.New("no map given")
+	}
+	mapName = filepath.Base(mapName)
+	if ext := filepath.Ext(mapName); ext != ".json" && ext != maps.TextExt {
+		mapName += ".json"
+	}
+
+	// load map
+	world, err := maps.Loader(filepath.Join(lb.mapDir, mapName))
+	if err != nil {
+		return nil, fmt.Errorf("invalid map %s", mapName)
+	}
+
+	// register game
+	lb.mux.Lock()
+	if len(lb.games) >= lb.maxGames {
+		lb.mux.Unlock()
+		return nil, errors.New("too many games")
+	}
+	lb.nextID++
+	m := &match{
+		id:          lb.nextID,
+		mapName:     mapName,
+		server:      NewServer("", world, world.PlayerCount()),
+		created:     time.Now(),
+		idleTimeout: lb.idleTimeout,
+		password:    password,
+	}
+	m.server.MaxClients = maxLobbyPlayer
+	m.server.Logger = log.New(os.Stdout, fmt.Sprintf("game %d: ", m.id), 0)
+	lb.games[m.id] = m
+	lb.mux.Unlock()
+
+	// run game
+	go lb.runMatch(m)
+	fmt.Printf("game %d: created with map %s\n", m.id, mapName)
+	return m, nil
+}
+
+// runMatch runs the world loop of a game until it ends and then removes the game from the lobby
+// and closes the connections of the game.
+func (lb *lobby) runMatch(m *match) {
+	m.server.RunWorld(func() bool {
+		// check end of game
+		if winner, reason, ended := m.check(); ended {
+			m.server.World().End(winner, reason)
+			fmt.Printf("game %d: %s\n", m.id, reason)
+			return false
+		}
+		return true
+	})
+
+	// clean up
+	lb.mux.Lock()
+	delete(lb.games, m.id)
+	lb.mux.Unlock()
+	_ = m.server.Close()
+}
+
+//--------  Match  ---------------------------------------------------------------------------------------------------//
+
+// info returns the public information of the game.
+func (m *match) info() GameInfo {
+	m.server.mux.Lock()
+	defer m.server.mux.Unlock()
+
+	return GameInfo{
+		ID:        m.id,
+		Map:       m.mapName,
+		Players:   len(m.server.conns),
+		MaxPlayer: m.server.maxPlayer,
+		Running:   m.server.started,
+		Private:   m.password != "",
+	}
+}
+
+// join assigns the next player ID of the game server to a new connection.
+func (m *match) join(conn net.Conn) (uint8, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	if m.ended {
+		return 0, errors.New("game has ended")
+	}
+	return m.server.join(conn, 0)
+}
+
+// check decides whether the game has ended.
+// A game ends if all clients have left, if nobody has joined within the idle timeout
+// or if only one player is left in a running multiplayer game.
+func (m *match) check() (winner uint8, reason string, ended bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+
+	s := m.server
+	s.mux.Lock()
+	joined, online, running := s.players, len(s.conns), s.started
+	s.mux.Unlock()
+
+	switch {
+	case joined > 0 && online == 0:
+		reason = "all players have left"
+	case joined == 0 && time.Since(m.created) >= m.idleTimeout:
+		reason = "nobody has joined"
+	case running && s.maxPlayer > 1:
+		var over bool
+		if winner, over = s.World().GameOver(); !over {
+			return 0, "", false
+		}
+		reason = "no player is left"
+		if winner != 0 {
+			reason = fmt.Sprintf("player %d has won", winner)
+		}
+	default:
+		return 0, "", false
+	}
+
+	m.ended = true
+	return winner, reason, true
+}
@@ -0,0 +1,156 @@
+package remote
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lobbyMap is a small map for two players in the text format.
+const lobbyMap = "terrain:\nB G G\n G G B\n\nunits:\nT1. .\n . . T2\n"
+
+// testLobby starts a lobby with a map directory that contains 'duel.txt'.
+func testLobby(t *testing.T, config func(lb *lobby)) (*lobby, string) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "duel.txt"), []byte(lobbyMap), 0644))
+
+	lb := newLobby(dir)
+	if config != nil {
+		config(lb)
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	go lb.serve(l)
+	return lb, l.Addr().String()
+}
+
+// games returns the games of the LIST command.
+func games(t *testing.T, rc *rawConn) []GameInfo {
+	var list []GameInfo
+	require.NoError(t, json.Unmarshal([]byte(rc.cmd("LIST")), &list))
+	return list
+}
+
+func TestLobby(t *testing.T) {
+	lb, addr := testLobby(t, nil)
+	rc := dialRaw(t, addr)
+	assert.Empty(t, games(t, rc))
+
+	// create
+	assert.Equal(t, "1", rc.cmd("CREATE duel.txt"))
+	assert.Equal(t, "err: invalid map missing.json", rc.cmd("CREATE missing"))
+	assert.Equal(t, "err: invalid map passwd.json", rc.cmd("CREATE ../../etc/passwd"))
+	assert.Equal(t, []GameInfo{{ID: 1, Map: "duel.txt", MaxPlayer: 2}}, games(t, rc))
+
+	// join
+	assert.Equal(t, "err: game not found", rc.cmd("JOIN 2"))
+	p1 := dialRaw(t, addr)
+	assert.Equal(t, "1", p1.cmd("JOIN 1"))
+	assert.Equal(t, "1", p1.cmd("PLAYER")) // game commands after JOIN
+	p2 := dialRaw(t, addr)
+	assert.Equal(t, "2", p2.cmd("JOIN 1"))
+	assert.Equal(t, []GameInfo{{ID: 1, Map: "duel.txt", Players: 2, MaxPlayer: 2, Running: true}}, games(t, rc))
+
+	// player 2 loses: the game ends and all connections are closed
+	m := lb.game(1)
+	require.NotNil(t, m)
+	m.server.World().Forfeit(2, "test", false)
+	_ = p1.conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err := p1.tp.ReadLine()
+	assert.Error(t, err)
+	assert.Empty(t, games(t, rc))
+	assert.Equal(t, uint8(1), m.server.World().Clone().Result.Winner)
+	assert.Equal(t, "err: game not found", rc.cmd("JOIN 1"))
+}
+
+func TestLobbyAllLeft(t *testing.T) {
+	_, addr := testLobby(t, nil)
+	rc := dialRaw(t, addr)
+	assert.Equal(t, "1", rc.cmd("CREATE duel.txt"))
+
+	// the only player leaves
+	p1 := dialRaw(t, addr)
+	assert.Equal(t, "1", p1.cmd("JOIN 1"))
+	_ = p1.conn.Close()
+	require.Eventually(t, func() bool { return len(games(t, rc)) == 0 }, 2*time.Second, 20*time.Millisecond)
+}
+
+func TestLobbyLimits(t *testing.T) {
+	_, addr := testLobby(t, func(lb *lobby) {
+		lb.maxGames = 2
+		lb.idleTimeout = 300 * time.Millisecond
+	})
+	rc := dialRaw(t, addr)
+
+	// max. games
+	assert.Equal(t, "1", rc.cmd("CREATE duel.txt"))
+	assert.Equal(t, "2", rc.cmd("CREATE duel.txt"))
+	assert.Equal(t, "err: too many games", rc.cmd("CREATE duel.txt"))
+	p1 := dialRaw(t, addr)
+	assert.Equal(t, "1", p1.cmd("JOIN 2"))
+
+	// nobody has joined game 1
+	require.Eventually(t, func() bool { return len(games(t, rc)) == 1 }, 2*time.Second, 20*time.Millisecond)
+	assert.Equal(t, 2, games(t, rc)[0].ID)
+	assert.Equal(t, "3", rc.cmd("CREATE duel.txt"))
+}
+
+func TestLobbyPassword(t *testing.T) {
+	_, addr := testLobby(t, nil)
+	rc := dialRaw(t, addr)
+	assert.Equal(t, "1", rc.cmd("CREATE duel.txt pw"))
+
+	// wrong passwords close the connection
+	assert.Equal(t, "err: authentication failed", rc.cmd("JOIN 1"))
+	assert.Equal(t, "err: authentication failed", rc.cmd("JOIN 1 wrong"))
+	assert.Equal(t, "err: authentication failed", rc.cmd("JOIN 1 PW"))
+	_, err := rc.tp.ReadLine()
+	assert.Error(t, err)
+
+	p1 := dialRaw(t, addr)
+	assert.Equal(t, "1", p1.cmd("JOIN 1 pw"))
+}
+
+func TestLobbyPrivateTLS(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "duel.txt"), []byte(lobbyMap), 0644))
+	cert := selfSignedCert(t)
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: []tls.Certificate{cert}})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	go ServeLobby(l, dir)
+	host, port, err := net.SplitHostPort(l.Addr().String())
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(cert.Certificate[0])
+	require.NoError(t, err)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	secure := WithTLS(&tls.Config{RootCAs: roots})
+
+	// private game
+	id, err := CreateGame(host, port, "duel.txt", secure, WithPassword("secret"))
+	require.NoError(t, err)
+	list, err := ListGames(host, port, secure)
+	require.NoError(t, err)
+	assert.Equal(t, []GameInfo{{ID: id, Map: "duel.txt", MaxPlayer: 2, Private: true}}, list)
+
+	// join with the options of NewClient
+	_, err = JoinGame(host, port, id, secure)
+	assert.EqualError(t, err, "err: "+ErrAuthFailed.Error())
+	_, err = JoinGame(host, port, id, secure, WithSeat(1, "secret"))
+	assert.EqualError(t, err, "lobby games assign the seats")
+	c, err := JoinGame(host, port, id, secure, WithPassword("secret"), WithControl("A"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = c.Close() })
+	assert.Equal(t, uint8(1), c.Player())
+	assert.ErrorIs(t, c.Move(0, 0, 1, 0), ErrNotControlled) // tank
+}
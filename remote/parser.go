@@ -0,0 +1,251 @@
+package remote
+
+/*
+  This file provides the parser of the command lines sent by the clients.
+  Every command has a fixed grammar (see gameGrammar and lobbyGrammar): The name is case-insensitive,
+  the arguments are separated by whitespace and the number and types of the arguments are checked strictly.
+  A protocol mistake of a client is reported with an ArityError or ArgError instead of being ignored.
+*/
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// errors of the parser
+var (
+	ErrInvalidCommand = errors.New("invalid command")   // unknown command
+	ErrInvalidArgs    = errors.New("invalid arguments") // see ArityError and ArgError
+)
+
+// argument types
+const (
+	argInt    = "integer"   // decimal integer (e.g. coordinates)
+	argOnOff  = "ON or OFF" // switch (case-insensitive, normalized to uppercase)
+	argString = "string"    // any text without whitespace
+
+	argIteration = "iteration like @1200" // execution iteration of a scheduled command (see commandSpec.schedule)
+)
+
+// commandSpec is the grammar of a single command.
+type commandSpec struct {
+	args     []string               // Types of all arguments (see argInt, argOnOff, argString)
+	optional int                    // Number of optional arguments at the end
+	usage    string                 // Usage text for error messages
+	sub      map[string]commandSpec // Sub-commands selected by the first argument (e.g. DEBUG MARK)
+	schedule bool                   // Allows an execution iteration as last argument (e.g. MOVE 1 1 2 1 @1200)
+}
+
+// gameGrammar contains all in-game commands (see session).
+var gameGrammar = map[string]commandSpec{
+	"PROTOCOL": {args: []string{argInt}, usage: "PROTOCOL version"},
+	"AUTH":     {args: []string{argString, argString}, optional: 1, usage: "AUTH [seat] password"},
+	"PLAYER":   {usage: "PLAYER"},
+	"STATUS":   {usage: "STATUS"},
+	"RESULTS":  {usage: "RESULTS"},
+	"CONTROL":  {args: []string{argString}, usage: "CONTROL ALL|types,ids"},
+	"MAP":      {usage: "MAP"},
+	"COMPACT":  {args: []string{argOnOff}, usage: "COMPACT ON|OFF"},
+	"GZIP":     {args: []string{argOnOff}, usage: "GZIP ON|OFF"},
+	"PATH":     {args: []string{argInt, argInt, argInt, argInt}, usage: "PATH x1 y1 x2 y2"},
+	"RANGE":    {args: []string{argInt, argInt, argInt}, optional: 1, usage: "RANGE x y [radius]"},
+	"DIST":     {args: []string{argInt, argInt, argInt, argInt}, usage: "DIST x1 y1 x2 y2"},
+	"LOS":      {args: []string{argInt, argInt, argInt, argInt}, usage: "LOS x1 y1 x2 y2"},
+	"DEBUG": {usage: "DEBUG MARK|LINE|LABEL|CLEAR ...", sub: map[string]commandSpec{
+		"MARK":  {args: []string{argInt, argInt, argString, argInt}, usage: "DEBUG MARK x y color ttl"},
+		"LINE":  {args: []string{argInt, argInt, argInt, argInt, argString, argInt}, usage: "DEBUG LINE x1 y1 x2 y2 color ttl"},
+		"LABEL": {args: []string{argInt, argInt, argString, argInt, argString}, usage: "DEBUG LABEL x y color ttl text"},
+		"CLEAR": {usage: "DEBUG CLEAR"},
+	}},
+	"FIRE":  {args: []string{argInt, argInt, argInt, argInt}, usage: "FIRE x1 y1 x2 y2 [@iteration]", schedule: true},
+	"MOVE":  {args: []string{argInt, argInt, argInt, argInt}, usage: "MOVE x1 y1 x2 y2 [@iteration]", schedule: true},
+	"UFIRE": {args: []string{argInt, argInt, argInt}, usage: "UFIRE id x y [@iteration]", schedule: true},
+	"UMOVE": {args: []string{argInt, argInt, argInt}, usage: "UMOVE id x y [@iteration]", schedule: true},
+}
+
+// lobbyGrammar contains all lobby commands (see RunLobby).
+var lobbyGrammar = map[string]commandSpec{
+	"LIST":   {usage: "LIST"},
+	"CREATE": {args: []string{argString, argString}, optional: 1, usage: "CREATE map [password]"},
+	"JOIN":   {args: []string{argInt, argString}, optional: 1, usage: "JOIN id [password]"},
+}
+
+//--------  Command  -------------------------------------------------------------------------------------------------//
+
+// Command is a parsed command line.
+type Command struct {
+	Name string   // Command name in uppercase
+	Args []string // Checked arguments (ON and OFF in uppercase)
+	At   uint64   // Execution iteration of a scheduled command (0 = immediately)
+}
+
+// Arg returns the argument at index i ("" if the optional argument is missing).
+func (c *Command) Arg(i int) string {
+	if i < 0 || i >= len(c.Args) {
+		return ""
+	}
+	return c.Args[i]
+}
+
+// Int returns the integer argument at index i (0 if the optional argument is missing).
+func (c *Command) Int(i int) int {
+	n, _ := strconv.Atoi(c.Arg(i))
+	return n
+}
+
+// String returns the command line.
+func (c *Command) String() string {
+	s := strings.Join(append([]string{c.Name}, c.Args...), " ")
+	if c.At > 0 {
+		s += " @" + strconv.FormatUint(c.At, 10)
+	}
+	return s
+}
+
+//--------  Errors  --------------------------------------------------------------------------------------------------//
+
+// ArityError is returned if a command has too few or too many arguments.
+type ArityError struct {
+	Command string // Command name
+	Min     int    // Min. number of arguments
+	Max     int    // Max. number of arguments
+	Got     int    // Number of arguments sent
+	Usage   string // Usage of the command
+}
+
+// Error returns the error message.
+func (e *ArityError) Error() string {
+	want := strconv.Itoa(e.Min)
+	if e.Max != e.Min {
+		want = fmt.Sprintf("%d to %d", e.Min, e.Max)
+	}
+	return fmt.Sprintf("%s expects %s arguments, got %d (usage: %s)", e.Command, want, e.Got, e.Usage)
+}
+
+// Unwrap returns ErrInvalidArgs.
+func (e *ArityError) Unwrap() error {
+	return ErrInvalidArgs
+}
+
+// ArgError is returned if an argument has the wrong type.
+type ArgError struct {
+	Command  string // Command name
+	Index    int    // Index of the argument (0 is the first argument)
+	Value    string // Argument sent
+	Expected string // Expected type (e.g. "integer")
+	Usage    string // Usage of the command
+}
+
+// Error returns the error message.
+func (e *ArgError) Error() string {
+	return fmt.Sprintf("%s argument %d: %q is not %s (usage: %s)", e.Command, e.Index+1, e.Value, article(e.Expected), e.Usage)
+}
+
+// Unwrap returns ErrInvalidArgs.
+func (e *ArgError) Unwrap() error {
+	return ErrInvalidArgs
+}
+
+// errorDetails returns the details of a parser error for a Response (nil for other errors).
+func errorDetails(err error) map[string]interface{} {
+	var arity *ArityError
+	var arg *ArgError
+	switch {
+	case errors.As(err, &arity):
+		return map[string]interface{}{"Min": arity.Min, "Max": arity.Max, "Got": arity.Got, "Usage": arity.Usage}
+	case errors.As(err, &arg):
+		return map[string]interface{}{"Index": arg.Index, "Value": arg.Value, "Expected": arg.Expected, "Usage": arg.Usage}
+	}
+	return nil
+}
+
+//--------  Parser  --------------------------------------------------------------------------------------------------//
+
+// parseCommand parses a command line with the grammar.
+// The returned command is never nil: If the line is invalid, it contains at least the command name
+// (e.g. for the rate limits) and the error describes the mistake.
+func parseCommand(line string, grammar map[string]commandSpec) (*Command, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return &Command{}, ErrInvalidCommand
+	}
+	cmd := &Command{Name: strings.ToUpper(fields[0])}
+
+	// command
+	spec, ok := grammar[cmd.Name]
+	if !ok {
+		return cmd, ErrInvalidCommand
+	}
+	args := fields[1:]
+
+	// sub-command (first argument)
+	name, offset := cmd.Name, 0
+	if spec.sub != nil {
+		if len(args) == 0 {
+			return cmd, &ArityError{Command: cmd.Name, Min: 1, Max: 1, Got: 0, Usage: spec.usage}
+		}
+		sub := strings.ToUpper(args[0])
+		subSpec, ok := spec.sub[sub]
+		if !ok {
+			return cmd, &ArgError{Command: cmd.Name, Index: 0, Value: args[0], Expected: subCommands(spec), Usage: spec.usage}
+		}
+		cmd.Args = append(cmd.Args, sub)
+		name, offset, spec, args = cmd.Name+" "+sub, 1, subSpec, args[1:]
+	}
+
+	// execution iteration
+	if last := len(args) - 1; spec.schedule && last >= 0 && strings.HasPrefix(args[last], "@") {
+		at, err := strconv.ParseUint(args[last][1:], 10, 64)
+		if err != nil || at == 0 {
+			return cmd, &ArgError{Command: name, Index: last + offset, Value: args[last], Expected: argIteration, Usage: spec.usage}
+		}
+		cmd.At, args = at, args[:last]
+	}
+
+	// arity
+	minArgs, maxArgs := len(spec.args)-spec.optional, len(spec.args)
+	if len(args) < minArgs || len(args) > maxArgs {
+		return cmd, &ArityError{Command: name, Min: minArgs, Max: maxArgs, Got: len(args), Usage: spec.usage}
+	}
+
+	// types
+	for i, a := range args {
+		switch spec.args[i] {
+		case argInt:
+			if _, err := strconv.Atoi(a); err != nil {
+				return cmd, &ArgError{Command: name, Index: i + offset, Value: a, Expected: argInt, Usage: spec.usage}
+			}
+		case argOnOff:
+			a = strings.ToUpper(a)
+			if a != "ON" && a != "OFF" {
+				return cmd, &ArgError{Command: name, Index: i + offset, Value: args[i], Expected: argOnOff, Usage: spec.usage}
+			}
+		}
+		cmd.Args = append(cmd.Args, a)
+	}
+	return cmd, nil
+}
+
+// subCommands returns the names of all sub-commands ("CLEAR, LABEL, LINE or MARK").
+func subCommands(spec commandSpec) string {
+	names := make([]string, 0, len(spec.sub))
+	for name := range spec.sub {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " or " + names[len(names)-1]
+}
+
+// article returns the argument type with an indefinite article ("an integer").
+func article(typ string) string {
+	if typ == argInt || typ == argIteration {
+		return "an " + typ
+	}
+	return typ
+}
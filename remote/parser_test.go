@@ -0,0 +1,124 @@
+package remote
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCommand(t *testing.T) {
+	// valid commands (case and whitespace)
+	cmd, err := parseCommand("move 1  2\t3 -4", gameGrammar)
+	require.NoError(t, err)
+	assert.Equal(t, "MOVE", cmd.Name)
+	assert.Equal(t, []string{"1", "2", "3", "-4"}, cmd.Args)
+	assert.Equal(t, -4, cmd.Int(3))
+
+	cmd, err = parseCommand("Gzip on", gameGrammar)
+	require.NoError(t, err)
+	assert.Equal(t, "ON", cmd.Arg(0))
+
+	cmd, err = parseCommand("RANGE 1 2", gameGrammar)
+	require.NoError(t, err)
+	assert.Equal(t, "", cmd.Arg(2))
+
+	// invalid command
+	cmd, err = parseCommand("FOO 1", gameGrammar)
+	assert.ErrorIs(t, err, ErrInvalidCommand)
+	assert.Equal(t, "FOO", cmd.Name)
+	_, err = parseCommand("  ", gameGrammar)
+	assert.ErrorIs(t, err, ErrInvalidCommand)
+	_, err = parseCommand("MOVE 1 2 3 4", lobbyGrammar)
+	assert.ErrorIs(t, err, ErrInvalidCommand)
+
+	// arity
+	_, err = parseCommand("MOVE foo", gameGrammar)
+	var arity *ArityError
+	require.ErrorAs(t, err, &arity)
+	assert.ErrorIs(t, err, ErrInvalidArgs)
+	assert.Equal(t, 1, arity.Got)
+	assert.Equal(t, "MOVE expects 4 arguments, got 1 (usage: MOVE x1 y1 x2 y2 [@iteration])", err.Error())
+	_, err = parseCommand("RANGE 1 2 3 4", gameGrammar)
+	assert.EqualError(t, err, "RANGE expects 2 to 3 arguments, got 4 (usage: RANGE x y [radius])")
+
+	// types
+	_, err = parseCommand("FIRE 1 2 x 4", gameGrammar)
+	var arg *ArgError
+	require.ErrorAs(t, err, &arg)
+	assert.Equal(t, 2, arg.Index)
+	assert.Equal(t, `FIRE argument 3: "x" is not an integer (usage: FIRE x1 y1 x2 y2 [@iteration])`, err.Error())
+	assert.Equal(t, "x", errorDetails(err)["Value"])
+	_, err = parseCommand("COMPACT yes", gameGrammar)
+	assert.EqualError(t, err, `COMPACT argument 1: "yes" is not ON or OFF (usage: COMPACT ON|OFF)`)
+	assert.Equal(t, CodeInvalidArgs, errorCode(err))
+
+	// execution iteration
+	cmd, err = parseCommand("umove 7 2 3 @1200", gameGrammar)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"7", "2", "3"}, cmd.Args)
+	assert.Equal(t, uint64(1200), cmd.At)
+	assert.Equal(t, "UMOVE 7 2 3 @1200", cmd.String())
+	_, err = parseCommand("FIRE 1 2 3 4 @soon", gameGrammar)
+	assert.EqualError(t, err, `FIRE argument 5: "@soon" is not an iteration like @1200 (usage: FIRE x1 y1 x2 y2 [@iteration])`)
+	_, err = parseCommand("MOVE 1 2 3 @5", gameGrammar)
+	assert.ErrorIs(t, err, ErrInvalidArgs) // missing argument
+	_, err = parseCommand("DIST 1 2 3 4 @5", gameGrammar)
+	assert.ErrorIs(t, err, ErrInvalidArgs) // not schedulable
+
+	// sub-commands
+	cmd, err = parseCommand("debug line 1 2 3 4 Red 30", gameGrammar)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"LINE", "1", "2", "3", "4", "Red", "30"}, cmd.Args)
+	_, err = parseCommand("DEBUG", gameGrammar)
+	assert.EqualError(t, err, "DEBUG expects 1 arguments, got 0 (usage: DEBUG MARK|LINE|LABEL|CLEAR ...)")
+	_, err = parseCommand("DEBUG CIRCLE 1 1", gameGrammar)
+	assert.EqualError(t, err, `DEBUG argument 1: "CIRCLE" is not CLEAR, LABEL, LINE or MARK (usage: DEBUG MARK|LINE|LABEL|CLEAR ...)`)
+	_, err = parseCommand("DEBUG MARK 1 1 red", gameGrammar)
+	assert.EqualError(t, err, "DEBUG MARK expects 4 arguments, got 3 (usage: DEBUG MARK x y color ttl)")
+	_, err = parseCommand("DEBUG MARK 1 1 red x", gameGrammar)
+	assert.EqualError(t, err, `DEBUG MARK argument 5: "x" is not an integer (usage: DEBUG MARK x y color ttl)`)
+}
+
+func TestParseErrorsV2(t *testing.T) {
+	s, _, _ := testServer(t, queryWorld(), 1, nil)
+	rc := dialRaw(t, s.Addr().String())
+	require.True(t, rc.cmdV2("protocol 2").OK)
+
+	resp := rc.cmdV2("MOVE foo")
+	assert.Equal(t, CodeInvalidArgs, resp.Code)
+	assert.Equal(t, float64(4), resp.Details["Min"])
+	assert.Equal(t, "MOVE x1 y1 x2 y2 [@iteration]", resp.Details["Usage"])
+}
+
+func FuzzParseCommand(f *testing.F) {
+	for _, seed := range []string{"MOVE 1 2 3 4", "move 1 2 x 4", "RANGE 1 2", "GZIP on", "PROTOCOL", "", " \t ", "FIRE -1 +2 0 99999999999999999999", "debug Mark 1 2 red 3", "MOVE 1 2 3 4 @99", "UFIRE 1 2 3 @"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, line string) {
+		cmd, err := parseCommand(line, gameGrammar)
+		require.NotNil(t, cmd)
+		if err != nil {
+			return
+		}
+
+		// valid commands are in the grammar and have a valid arity
+		spec, ok := gameGrammar[cmd.Name]
+		require.True(t, ok)
+		args := cmd.Args
+		if spec.sub != nil {
+			spec, ok = spec.sub[cmd.Arg(0)]
+			require.True(t, ok)
+			args = args[1:]
+		}
+		require.LessOrEqual(t, len(args), len(spec.args))
+		require.GreaterOrEqual(t, len(args), len(spec.args)-spec.optional)
+		require.False(t, strings.ContainsAny(cmd.String(), "\r\n"))
+
+		// the normalized command line is parsed to the same command
+		again, err := parseCommand(cmd.String(), gameGrammar)
+		require.NoError(t, err)
+		require.Equal(t, cmd, again)
+	})
+}
@@ -0,0 +1,146 @@
+package remote
+
+/*
+  This file defines the structured responses of protocol version 2 and the machine-readable error codes.
+  Version 1 (default) answers with plain text lines, version 2 answers every command with a JSON Response.
+  A client switches the protocol version of its connection with the command 'PROTOCOL <version>'.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"strings"
+)
+
+// protocol versions
+const (
+	ProtocolV1 = 1 // plain text responses (default)
+	ProtocolV2 = 2 // structured JSON responses
+)
+
+// v1ErrorPrefix is the prefix of the error responses in protocol version 1.
+const v1ErrorPrefix = "err: "
+
+// error codes
+const (
+	CodeOK             = "OK"              // command was successful
+	CodeError          = "ERROR"           // unknown error
+	CodeInvalidCommand = "INVALID_COMMAND" // unknown command
+	CodeInvalidArgs    = "INVALID_ARGS"    // invalid command arguments
+	CodeNoMap          = "NO_MAP"          // compact STATUS without MAP
+	CodeThrottled      = "THROTTLED"       // see ErrThrottled
+	CodeLineTooLong    = "LINE_TOO_LONG"   // see ErrLineTooLong
+	CodeAuthRequired   = "AUTH_REQUIRED"   // see ErrAuthRequired
+	CodeAuthFailed     = "AUTH_FAILED"     // see ErrAuthFailed
+	CodeInvalidInput   = "INVALID_INPUT"   // see core.ErrInvalidInput
+	CodeNoUnit         = "NO_UNIT"         // see core.ErrNoUnit
+	CodeBusy           = "BUSY"            // see core.ErrBusy
+	CodeNoPath         = "NO_PATH"         // see core.ErrNoPath
+	CodeInvalidTarget  = "INVALID_TARGET"  // see core.ErrInvalidTarget
+	CodeNotInRange     = "NOT_IN_RANGE"    // see core.ErrNotInRange
+	CodeNoAmmo         = "NO_AMMO"         // see core.ErrNoAmmo
+	CodePastIteration  = "PAST_ITERATION"  // see core.ErrPastIteration
+	CodeQueueFull      = "QUEUE_FULL"      // see core.ErrQueueFull
+	CodeNotControlled  = "NOT_CONTROLLED"  // see ErrNotControlled
+)
+
+// codeErrors maps the error codes to the errors of the game world and the server.
+var codeErrors = map[string]error{
+	CodeInvalidCommand: ErrInvalidCommand,
+	CodeInvalidArgs:    ErrInvalidArgs,
+	CodeThrottled:      ErrThrottled,
+	CodeLineTooLong:    ErrLineTooLong,
+	CodeAuthRequired:   ErrAuthRequired,
+	CodeAuthFailed:     ErrAuthFailed,
+	CodeInvalidInput:   core.ErrInvalidInput,
+	CodeNoUnit:         core.ErrNoUnit,
+	CodeBusy:           core.ErrBusy,
+	CodeNoPath:         core.ErrNoPath,
+	CodeInvalidTarget:  core.ErrInvalidTarget,
+	CodeNotInRange:     core.ErrNotInRange,
+	CodeNoAmmo:         core.ErrNoAmmo,
+	CodePastIteration:  core.ErrPastIteration,
+	CodeQueueFull:      core.ErrQueueFull,
+	CodeNotControlled:  ErrNotControlled,
+}
+
+// Response is the answer of the server to every command in protocol version 2.
+type Response struct {
+	OK       bool                   // Indicates if the command was successful.
+	Code     string                 // Machine-readable result code (see CodeOK, CodeNoAmmo, ...).
+	Error    string                 `json:",omitempty"` // Human-readable error message.
+	Details  map[string]interface{} `json:",omitempty"` // Additional information about the error.
+	Activity *core.Activity         `json:",omitempty"` // Activity of the unit after MOVE or FIRE.
+	Data     json.RawMessage        `json:",omitempty"` // Result of the command (e.g. the world of STATUS).
+}
+
+// Error is a command error returned by the server.
+// It matches the errors of the game world and the server with errors.Is (e.g. errors.Is(err, core.ErrNoAmmo)).
+type Error struct {
+	Code    string                 // Machine-readable error code (see CodeNoAmmo, ...).
+	Message string                 // Human-readable error message.
+	Details map[string]interface{} // Additional information about the error.
+}
+
+// Error returns the error message.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether the error code corresponds to the target error (see codeErrors).
+func (e *Error) Is(target error) bool {
+	err, ok := codeErrors[e.Code]
+	return ok && err == target
+}
+
+// errorCode returns the error code of an error.
+func errorCode(err error) string {
+	if err == nil {
+		return CodeOK
+	}
+	for code, e := range codeErrors {
+		if errors.Is(err, e) {
+			return code
+		}
+	}
+	return CodeError
+}
+
+// v1Response converts a plain text response of protocol version 1 to a Response. Errors have the prefix 'err: ',
+// only the errors of MOVE and FIRE are sent without prefix and are recognized by their message (see codeErrors).
+func v1Response(line string) *Response {
+	msg, prefixed := strings.CutPrefix(line, v1ErrorPrefix)
+	code := messageCode(msg)
+	if !prefixed && code == CodeError {
+		resp := &Response{OK: true, Code: CodeOK}
+		if line != "OK" {
+			resp.Data = json.RawMessage(line)
+		}
+		return resp
+	}
+	return &Response{Code: code, Error: msg}
+}
+
+// messageCode returns the code of the error with the longest message that starts the text (CodeError if none).
+func messageCode(text string) string {
+	code, length := CodeError, 0
+	for c, err := range codeErrors {
+		if msg := err.Error(); len(msg) > length && strings.HasPrefix(text, msg) {
+			code, length = c, len(msg)
+		}
+	}
+	return code
+}
+
+// responseError converts an unsuccessful response to an Error (nil if the response is successful).
+func responseError(resp *Response) error {
+	if resp.OK {
+		return nil
+	}
+	return &Error{
+		Code:    resp.Code,
+		Message: resp.Error,
+		Details: resp.Details,
+	}
+}
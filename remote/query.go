@@ -0,0 +1,144 @@
+package remote
+
+/*
+  This file provides the read-only query commands PATH, RANGE, DIST and LOS.
+  They give clients in other languages the pathfinding and range checks of the core package.
+  All queries are evaluated on the censored world of the player (see core.Censorship),
+  so they never reveal hidden enemy units.
+*/
+
+import (
+	"encoding/json"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"sort"
+	"strconv"
+)
+
+// PathResult is the response of the PATH command.
+type PathResult struct {
+	Path [][2]int // Coordinates of all tiles from the start to the target tile.
+	Cost uint64   // Estimated number of iterations to move along the path (see core.PathCost).
+}
+
+// query evaluates a query command on the censored world of the player.
+func (ss *session) query(cmd *Command) {
+	w := core.Censorship(ss.world, ss.player)
+	if w == nil {
+		ss.replyError(CodeError, "world not available", nil)
+		return
+	}
+
+	from, to := w.Tile(cmd.Int(0), cmd.Int(1)), w.Tile(cmd.Int(2), cmd.Int(3))
+
+	switch cmd.Name {
+	case "PATH":
+		ss.queryPath(w, from, to)
+	case "RANGE":
+		ss.queryRange(w, from, cmd.Arg(2))
+	case "DIST":
+		if from == nil || to == nil {
+			ss.replyQueryError(core.ErrInvalidInput)
+			return
+		}
+		ss.replyData(w.Distance(from, to))
+	case "LOS":
+		ss.replyCommand(w.CanFire(from, to, ss.player), w.UnitAt(from))
+	}
+}
+
+// queryPath sends the path and the cost for the unit of the player on the 'from' tile.
+func (ss *session) queryPath(w *core.World, from, to *core.Tile) {
+	if from == nil || to == nil {
+		ss.replyQueryError(core.ErrInvalidInput)
+		return
+	}
+	unit := from.Unit
+	if unit == nil || unit.Player != ss.player {
+		ss.replyQueryError(core.ErrNoUnit)
+		return
+	}
+
+	// the target tile must be passable (see World.Move)
+	if unit.Type != core.SOLDIER && (to.Type == core.MOUNTAIN || to.Type == core.STRUCTURE || to.Type == core.WATER) {
+		ss.replyQueryError(core.ErrInvalidTarget)
+		return
+	}
+
+	// find path
+	path := []*core.Tile{from}
+	if from != to {
+		path = core.FindPath(w, unit.Type, from, to)
+	}
+	if len(path) < 1 {
+		ss.replyQueryError(core.ErrNoPath)
+		return
+	}
+
+	result := &PathResult{
+		Path: make([][2]int, 0, len(path)),
+		Cost: core.PathCost(unit.Type, path),
+	}
+	for _, t := range path {
+		result.Path = append(result.Path, [2]int{t.XCol, t.YRow})
+	}
+	ss.replyData(result)
+}
+
+// queryRange sends all tiles within the radius around the 'from' tile (sorted by distance, row and column).
+// Without a radius, the fire range of the unit of the player on the 'from' tile is used.
+func (ss *session) queryRange(w *core.World, from *core.Tile, radius string) {
+	if from == nil {
+		ss.replyQueryError(core.ErrInvalidInput)
+		return
+	}
+
+	// radius
+	r, err := strconv.Atoi(radius)
+	if radius == "" {
+		unit := from.Unit
+		if unit == nil || unit.Player != ss.player {
+			ss.replyQueryError(core.ErrNoUnit)
+			return
+		}
+		r, err = unit.FireRange, nil
+	}
+	if err != nil || r < 0 {
+		ss.replyError(CodeInvalidArgs, "invalid radius", nil)
+		return
+	}
+	if limit := w.XWidth + w.YHeight; r > limit {
+		r = limit // no tiles beyond the world
+	}
+
+	// tiles
+	tiles := make([][2]int, 0)
+	for _, ring := range w.ExtNeighbors(from, r) {
+		sort.Slice(ring, func(i, j int) bool {
+			if ring[i].YRow != ring[j].YRow {
+				return ring[i].YRow < ring[j].YRow
+			}
+			return ring[i].XCol < ring[j].XCol
+		})
+		for _, t := range ring {
+			tiles = append(tiles, [2]int{t.XCol, t.YRow})
+		}
+	}
+	ss.replyData(tiles)
+}
+
+//--------  Response  ------------------------------------------------------------------------------------------------//
+
+// replyData sends a small JSON result (without compression).
+func (ss *session) replyData(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		ss.replyError(CodeError, err.Error(), nil)
+		return
+	}
+	ss.reply(string(b), b)
+}
+
+// replyQueryError sends an error of the game world with its error code.
+func (ss *session) replyQueryError(err error) {
+	ss.replyError(errorCode(err), err.Error(), nil)
+}
@@ -0,0 +1,66 @@
+package remote
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// queryWorld returns a world with a red tank at 1,1, a water tile at 3,1 and a blue tank at 4,3.
+func queryWorld() *core.World {
+	world := core.NewWorld(6, 4)
+	for y := 0; y < world.YHeight; y++ {
+		for x := 0; x < world.XWidth; x++ {
+			world.Tile(x, y).Type = core.GRASS
+		}
+	}
+	world.Tile(3, 1).Type = core.WATER
+	world.Tile(1, 1).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(4, 3).Unit = core.NewUnit(core.BLUE, core.TANK)
+	world.Update()
+	return world
+}
+
+func TestQueryV1(t *testing.T) {
+	s, _, _ := testServer(t, queryWorld(), 1, nil)
+	rc := dialRaw(t, s.Addr().String())
+
+	assert.Equal(t, "3", rc.cmd("DIST 1 1 4 1"))
+	assert.Equal(t, "err: input is nil", rc.cmd("DIST 1 1 9 9"))
+	assert.Equal(t, "[[1,0],[2,0],[0,1],[2,1],[1,2],[2,2]]", rc.cmd("RANGE 1 1 1"))
+	assert.Equal(t, "err: no player unit found", rc.cmd("PATH 4 3 0 0"))
+	assert.Equal(t, "err: invalid target for this unit", rc.cmd("PATH 1 1 3 1"))
+	assert.Equal(t, "target is not in range", rc.cmd("LOS 1 1 5 3"))
+}
+
+func TestQueryV2(t *testing.T) {
+	world := queryWorld()
+	s, _, _ := testServer(t, world, 1, nil)
+	rc := dialRaw(t, s.Addr().String())
+	require.True(t, rc.cmdV2("PROTOCOL 2").OK)
+
+	// path around the water
+	resp := rc.cmdV2("PATH 1 1 5 3")
+	require.True(t, resp.OK, resp.Error)
+	path := new(PathResult)
+	require.NoError(t, json.Unmarshal(resp.Data, path))
+	assert.Equal(t, [2]int{1, 1}, path.Path[0])
+	assert.Equal(t, [2]int{5, 3}, path.Path[len(path.Path)-1])
+	assert.Equal(t, core.PathCost(core.TANK, core.FindPath(world, core.TANK, world.Tile(1, 1), world.Tile(5, 3))), path.Cost)
+
+	// range of the unit
+	resp = rc.cmdV2("RANGE 1 1")
+	require.True(t, resp.OK)
+	var tiles [][2]int
+	require.NoError(t, json.Unmarshal(resp.Data, &tiles))
+	assert.NotEmpty(t, tiles)
+	assert.Equal(t, CodeInvalidArgs, rc.cmdV2("RANGE 1 1 x").Code)
+
+	// fire check without side effects
+	assert.True(t, rc.cmdV2("LOS 1 1 2 1").OK)
+	assert.Nil(t, world.UnitAt(world.Tile(1, 1)).Activity)
+	assert.Equal(t, CodeNotInRange, rc.cmdV2("LOS 1 1 1 1").Code)
+}
@@ -0,0 +1,2 @@
+// Package remotetest provides an in-process game server for unit tests of AI clients.
+package remotetest
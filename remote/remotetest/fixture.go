@@ -0,0 +1,56 @@
+package remotetest
+
+/*
+  This file provides fixtures to build small worlds for tests. A fixture is a map in the text format of the
+  package maps (see maps.ReadASCII), so the same text can be saved as a map file and loaded by the game:
+
+    # comment
+    terrain:
+    G G G B
+     F W G G
+
+    units:
+    T1. . .
+     . . A2.
+
+  The common indentation of all lines is removed, so a fixture can be indented in the Go source of a test.
+*/
+
+import (
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/SchnorcherSepp/TankWars2/maps"
+	"strings"
+)
+
+// ParseWorld builds a world from a fixture in the text format of the maps (see maps.ReadASCII).
+func ParseWorld(ascii string) (*core.World, error) {
+	f, err := maps.ReadASCII(strings.NewReader(dedent(ascii)))
+	if err != nil {
+		return nil, err
+	}
+	return f.World(), nil
+}
+
+// dedent removes the common indentation (spaces and tabs) of all non-empty lines.
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	prefix := ""
+	first := true
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if first {
+			prefix, first = indent, false
+			continue
+		}
+		for !strings.HasPrefix(indent, prefix) {
+			prefix = prefix[:len(prefix)-1]
+		}
+	}
+	for i, line := range lines {
+		lines[i] = strings.TrimPrefix(line, prefix)
+	}
+	return strings.Join(lines, "\n")
+}
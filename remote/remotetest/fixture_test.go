@@ -0,0 +1,56 @@
+package remotetest
+
+import (
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/SchnorcherSepp/TankWars2/maps"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorld(t *testing.T) {
+	world, err := ParseWorld(`
+		# test world
+		rules: MaxSupply=7
+
+		terrain:
+		G G B
+		 F W B
+
+		units:
+		. T1.
+		 . . A2
+	`)
+	require.NoError(t, err)
+	assert.Equal(t, 3, world.XWidth)
+	assert.Equal(t, 2, world.YHeight)
+	assert.Equal(t, byte(core.GRASS), world.Tile(0, 0).Type)
+	assert.Equal(t, byte(core.WATER), world.Tile(1, 1).Type)
+	assert.Equal(t, uint8(core.RED), world.Tile(1, 0).Unit.Player)
+	assert.Equal(t, byte(core.TANK), world.Tile(1, 0).Unit.Type)
+	assert.Equal(t, byte(core.BASE), world.Tile(2, 1).Type)
+	assert.Equal(t, byte(core.ARTILLERY), world.Tile(2, 1).Unit.Type)
+	assert.Equal(t, uint8(core.BLUE), world.Tile(2, 1).Unit.Player)
+	assert.Nil(t, world.Tile(0, 1).Unit)
+	require.NotNil(t, world.Rules)
+	assert.Equal(t, 7, world.Rules.MaxSupply)
+
+	// errors
+	_, err = ParseWorld("# nothing")
+	assert.ErrorIs(t, err, maps.ErrInvalidMap)
+	_, err = ParseWorld("terrain:\nG G\n G")
+	assert.ErrorIs(t, err, maps.ErrInvalidMap)
+	_, err = ParseWorld("terrain:\nG X")
+	assert.ErrorIs(t, err, maps.ErrInvalidMap)
+	_, err = ParseWorld("terrain:\nG G\n\nunits:\nT9")
+	assert.ErrorIs(t, err, maps.ErrInvalidMap)
+	_, err = ParseWorld("GT1 G G B2") // old format
+	assert.ErrorIs(t, err, maps.ErrInvalidMap)
+}
+
+func TestDedent(t *testing.T) {
+	assert.Equal(t, "\nterrain:\nG G\n G\n", dedent("\n\t\tterrain:\n\t\tG G\n\t\t G\n"))
+	assert.Equal(t, "a\n b", dedent("  a\n   b"))
+	assert.Equal(t, "a\nb", dedent("a\nb"))
+}
@@ -0,0 +1,171 @@
+package remotetest
+
+/*
+  This file provides a game server for unit tests of AI clients. In contrast to remote.RunServer, the world
+  doesn't run in real time: the test advances the world by hand with Step. All commands of the clients are
+  recorded (see Commands), so a test can check what an AI has sent and how the world has changed.
+  The units are visible to the clients after the first update:
+
+    world, _ := remotetest.ParseWorld("terrain:\nG G B\n\nunits:\nT1. .\n")
+    s := remotetest.NewServer(t, world, 1)
+    client, _ := s.Connect()
+    go ai.RunAI(client)
+    err := s.Step(1)
+    move, err := s.WaitCommand(1, core.MOVE)
+    err = s.Step(10)
+*/
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/SchnorcherSepp/TankWars2/remote"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// SyncTimeout is the max. time Step waits for the clients to fetch the new world (see Step).
+var SyncTimeout = 2 * time.Second
+
+// Server is a game server on a loopback listener for a single test.
+// It embeds the remote.Server, so all server methods (e.g. Kick, End and ConnectBot) are available.
+type Server struct {
+	*remote.Server
+	Host string // Listen host
+	Port string // Listen port
+
+	mux     sync.Mutex                // Mutex for thread-safe operations
+	entries []*remote.TranscriptEntry // All received commands with their responses
+}
+
+// NewServer starts a server for the world on a random local port. The game starts when all players
+// are connected. The server has no rate limits and is closed at the end of the test.
+func NewServer(t testing.TB, world *core.World, players int) *Server {
+	t.Helper()
+
+	s := &Server{Server: remote.NewServer("127.0.0.1:0", world, players)}
+	s.Server.Limits = remote.Limits{}
+	s.Server.Logger = log.New(io.Discard, "", 0)
+	s.Server.Transcript = remote.NewTranscript(recorder{s})
+	if err := s.Start(context.Background()); err != nil {
+		t.Fatalf("remotetest: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+
+	var err error
+	if s.Host, s.Port, err = net.SplitHostPort(s.Addr().String()); err != nil {
+		t.Fatalf("remotetest: %v", err)
+	}
+	return s
+}
+
+// Connect connects a new client (see remote.NewClient).
+func (s *Server) Connect(opts ...remote.ClientOption) (*remote.Client, error) {
+	return remote.NewClient(s.Host, s.Port, opts...)
+}
+
+// Step updates the world n times (see core.World.Update). A frozen world (e.g. before all players
+// are connected) doesn't change. Then Step waits until every client that polls STATUS has stored the
+// new world, so the clients never act on an old world. An error is returned after SyncTimeout.
+func (s *Server) Step(n int) error {
+
+	// update world
+	world := s.World()
+	for i := 0; i < n; i++ {
+		world.Update()
+	}
+	iteration := world.CurrentIteration()
+
+	// wait for clients
+	deadline := time.Now().Add(SyncTimeout)
+	for {
+		missing := s.unsynced(iteration)
+		if missing == "" {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("client %s has not fetched iteration %d", missing, iteration)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// Commands returns the received commands of the player (negative = all players) with one of
+// the names (none = all commands), e.g. Commands(1, core.MOVE, core.FIRE).
+func (s *Server) Commands(player int, names ...string) []*remote.TranscriptEntry {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return remote.FilterTranscript(s.entries, player, names)
+}
+
+// WaitCommand waits until a command of the player (negative = all players) with one of the names
+// (none = all commands) is received and returns the first one. An error is returned after SyncTimeout.
+func (s *Server) WaitCommand(player int, names ...string) (*remote.TranscriptEntry, error) {
+	deadline := time.Now().Add(SyncTimeout)
+	for {
+		if list := s.Commands(player, names...); len(list) > 0 {
+			return list[0], nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no command %v of player %d received", names, player)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// unsynced returns the address of a connected client that has polled STATUS before, but has not
+// stored the world of the iteration yet (empty = all clients are synced). A client has stored the world
+// when it sends the next command after the STATUS, because the client sends one command at a time.
+func (s *Server) unsynced(iteration uint64) string {
+	online := make(map[string]bool)
+	for _, p := range s.Players() {
+		online[p.Addr] = true
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	polled := make(map[string]bool)
+	fetched := make(map[string]bool)
+	synced := make(map[string]bool)
+	for _, e := range s.entries {
+		synced[e.Conn] = synced[e.Conn] || fetched[e.Conn]
+		if e.Name() == "STATUS" {
+			polled[e.Conn] = true
+			fetched[e.Conn] = fetched[e.Conn] || e.Iteration >= iteration
+		}
+	}
+	for addr := range polled {
+		if online[addr] && !synced[addr] {
+			return addr
+		}
+	}
+	return ""
+}
+
+// recorder stores the transcript of the server (see remote.Transcript).
+type recorder struct {
+	s *Server
+}
+
+// Write stores a JSON line of the transcript.
+func (r recorder) Write(p []byte) (int, error) {
+	e := new(remote.TranscriptEntry)
+	if err := json.Unmarshal(bytes.TrimSpace(p), e); err != nil {
+		return 0, err
+	}
+
+	r.s.mux.Lock()
+	defer r.s.mux.Unlock()
+	r.s.entries = append(r.s.entries, e)
+	return len(p), nil
+}
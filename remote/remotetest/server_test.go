@@ -0,0 +1,60 @@
+package remotetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	world, err := ParseWorld("terrain:\nG G G G\n\nunits:\nT1. . T2\n")
+	require.NoError(t, err)
+	s := NewServer(t, world, 2)
+
+	// frozen until all players are connected
+	c1, err := s.Connect()
+	require.NoError(t, err)
+	require.NoError(t, s.Step(10))
+	assert.Equal(t, uint64(0), world.CurrentIteration())
+	c2, err := s.Connect()
+	require.NoError(t, err)
+	assert.Equal(t, uint8(2), c2.Player())
+
+	// step by hand
+	require.NoError(t, s.Step(5))
+	assert.Equal(t, uint64(5), world.CurrentIteration())
+	assert.Equal(t, uint64(5), c1.Status().Iteration)
+	assert.Equal(t, uint64(5), c2.Status().Iteration)
+
+	// commands
+	require.NoError(t, c1.Move(0, 0, 1, 0))
+	assert.ErrorIs(t, c2.Move(0, 0, 1, 0), core.ErrNoUnit)
+	for i := 0; i < 10 && world.Tile(1, 0).Unit == nil; i++ {
+		require.NoError(t, s.Step(core.GameSpeed))
+	}
+	assert.Nil(t, world.Tile(0, 0).Unit)
+	assert.Equal(t, uint8(core.RED), world.Tile(1, 0).Unit.Player)
+	assert.Equal(t, world.CurrentIteration(), c1.Status().Iteration)
+
+	// recorded commands
+	moves := s.Commands(-1, core.MOVE)
+	require.Len(t, moves, 2)
+	assert.Equal(t, "MOVE 0 0 1 0", moves[0].Command)
+	assert.Equal(t, uint8(1), moves[0].Player)
+	assert.Equal(t, uint64(5), moves[0].Iteration)
+	assert.Contains(t, moves[1].Response, "NO_UNIT")
+	assert.Len(t, s.Commands(2, core.MOVE), 1)
+	assert.NotEmpty(t, s.Commands(1, "STATUS"))
+
+	// wait for commands
+	move, err := s.WaitCommand(2, core.MOVE)
+	require.NoError(t, err)
+	assert.Equal(t, moves[1], move)
+	SyncTimeout = 50 * time.Millisecond
+	defer func() { SyncTimeout = 2 * time.Second }()
+	_, err = s.WaitCommand(1, core.FIRE)
+	assert.EqualError(t, err, "no command [FIRE] of player 1 received")
+}
@@ -6,140 +6,573 @@ package remote
 
 import (
 	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"github.com/SchnorcherSepp/TankWars2/core"
 	"log"
+	"math"
 	"net"
 	"net/textproto"
-	"strconv"
-	"strings"
+	"os"
+	"sort"
+	"sync"
+	"time"
 )
 
-// RunServer runs a server (BLOCKING!).
+// ErrServerClosed is returned by the Server methods after a call to Close.
+var ErrServerClosed = errors.New("server closed")
+
+// Server is a game server for a single world.
 // The server receives commands from the clients and implements them in "World".
 // The first connecting client controls player 1.
 // The second connecting client controls player 2, and so on.
+// Further clients are observers. With SeatConnections, several clients can share a seat (see AUTH and CONTROL).
+type Server struct {
+	addr      string      // Listen address (host:port)
+	world     *core.World // World controlled by the clients
+	maxPlayer int         // Number of players required to start the game
+
+	// optional settings (set before Start)
+	MaxClients      int                               // Max. number of clients (players and observers); 0 means no limit
+	Limits          Limits                            // Rate limits of each client connection (default: DefaultLimits)
+	Password        string                            // Password for all seats (see AUTH); empty means no password
+	SeatPasswords   map[uint8]string                  // Passwords of single seats (see AUTH); these seats are never assigned automatically
+	SeatConnections int                               // Max. connections per seat chosen with AUTH (0 or 1 = a taken seat is rejected)
+	SeatControls    []SeatControl                     // Passwords of shared seats with a fixed restriction; these seats are never assigned automatically
+	BotSeats        []uint8                           // Seats reserved for in-process bots (see ConnectBot); never assigned automatically or with AUTH
+	TLSConfig       *tls.Config                       // Enables TLS for the listener (see Start)
+	WSOrigins       []string                          // Origins of other web pages allowed to use the WebSocket gateway ("*" = all)
+	JoinTimeout     time.Duration                     // Max. time after Start to wait for all players (0 = wait forever)
+	InactiveTimeout time.Duration                     // Max. time of a player without game commands in a running game (0 = no limit)
+	TimeoutPolicy   TimeoutPolicy                     // What happens to a seat after a timeout (default: PolicyForfeit)
+	TimeoutBot      func(client *Client)              // Bot that takes over a seat with PolicyBot (see ai.RunAI)
+	Transcript      *Transcript                       // Records the commands and responses of all connections (optional)
+	Announce        string                            // UDP address of the LAN announcements, e.g. "255.255.255.255:41234" (see Discover)
+	MapName         string                            // Name of the map in the LAN announcements
+	Logger          *log.Logger                       // Server log (default: stdout)
+	OnConnect       func(player uint8, addr net.Addr) // Called when a client got its player ID
+	OnDisconnect    func(player uint8, addr net.Addr) // Called when a client has left
+	OnStart         func()                            // Called when all players are connected and the game starts
+
+	mux      sync.Mutex          // Mutex for thread-safe operations
+	tickRate int                 // Updates per second of RunWorld (see SetTickRate)
+	ticking  bool                // Indicates if RunWorld is running
+	listener net.Listener        // Listener (nil if not started)
+	conns    map[net.Conn]uint8  // All open connections with their player ID
+	pending  map[net.Conn]bool   // Connections waiting for authentication
+	seats    map[uint8]bool      // All assigned player IDs
+	players  int                 // Number of assigned player IDs
+	active   map[uint8]time.Time // Time of the last game command of each player (see InactiveTimeout)
+	lost     map[uint8]bool      // Players who have forfeited their seat
+	started  bool                // Indicates if the game has started
+	paused   bool                // Indicates if the game is paused by Pause (the start doesn't unfreeze the world)
+	startAt  time.Time           // Time of Start (see JoinTimeout)
+	closed   bool                // Indicates if the server is closed
+	done     chan struct{}       // Closed by Close
+	wg       sync.WaitGroup      // All server goroutines
+}
+
+// NewServer creates a new server for the world.
+// The world stays frozen until maxPlayer clients are connected.
+// Use Start to listen on the given address (host:port) or ServeConn to add connections manually.
+func NewServer(addr string, world *core.World, maxPlayer int) *Server {
+	world.SetFreeze(true) // wait for all player
+
+	return &Server{
+		addr:      addr,
+		world:     world,
+		maxPlayer: maxPlayer,
+		Limits:    DefaultLimits,
+		tickRate:  core.GameSpeed,
+		conns:     make(map[net.Conn]uint8),
+		pending:   make(map[net.Conn]bool),
+		seats:     make(map[uint8]bool),
+		active:    make(map[uint8]time.Time),
+		lost:      make(map[uint8]bool),
+		done:      make(chan struct{}),
+	}
+}
+
+// RunServer runs a server (BLOCKING!).
+// This is the command line variant of Server: It exits the program if the server can't listen
+// on the address and never returns.
 func RunServer(host, port string, world *core.World, maxPlayer int) {
-	world.Freeze = true // wait for all player
+	s := NewServer(host+":"+port, world, maxPlayer)
 
 	// Listen for incoming connections.
-	l, err := net.Listen("tcp", host+":"+port)
-	if err != nil {
+	if err := s.Start(context.Background()); err != nil {
 		log.Fatalf("RunServer: %v\n", err)
 	}
 
-	// Close the listener when the application closes.
-	defer func(l net.Listener) {
-		_ = l.Close()
-	}(l)
+	// wait forever
+	fmt.Println("START SERVER [" + s.Addr().String() + "]")
+	<-s.Done()
+}
 
-	// start server
-	fmt.Println("START SERVER [" + host + ":" + port + "]")
-	for player := uint8(1); player < 50; player++ {
+//--------  Lifecycle  -----------------------------------------------------------------------------------------------//
 
-		// wait for incoming connection
-		conn, err := l.Accept()
-		if err != nil {
-			fmt.Println("Error accepting: ", err.Error())
-			continue
+// Start listens on the server address and accepts clients in the background.
+// The server is closed when the context is canceled or Close is called.
+func (s *Server) Start(ctx context.Context) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	// check state
+	if s.closed {
+		return ErrServerClosed
+	}
+	if s.listener != nil {
+		return errors.New("server already started")
+	}
+	for _, sc := range s.SeatControls {
+		if _, err := parseControl(sc.Units); err != nil || sc.Seat == 0 || sc.Password == "" {
+			return fmt.Errorf("invalid seat control for seat %d: %q", sc.Seat, sc.Units)
 		}
+	}
+	for _, seat := range s.BotSeats {
+		if seat == 0 || int(seat) > s.maxPlayer {
+			return fmt.Errorf("invalid bot seat %d", seat)
+		}
+	}
 
-		// Handle connections in a new goroutine.
-		go handleRequest(conn, world, player)
-		fmt.Printf("player %d from %v\n", player, conn.RemoteAddr())
+	// Listen for incoming connections.
+	var l net.Listener
+	var err error
+	if s.TLSConfig != nil {
+		l, err = tls.Listen("tcp", s.addr, s.TLSConfig)
+	} else {
+		l, err = net.Listen("tcp", s.addr)
+	}
+	if err != nil {
+		return err
+	}
+	s.listener = l
+	s.startAt = time.Now()
+
+	// accept clients
+	s.wg.Add(1)
+	go s.acceptLoop(l)
 
-		// start game with all player
-		if int(player) == maxPlayer {
-			world.Freeze = false // START GAME
-			fmt.Printf("START GAME\n")
+	// check timeouts
+	if s.JoinTimeout > 0 || s.InactiveTimeout > 0 {
+		s.wg.Add(1)
+		go s.watchdog()
+	}
+
+	// LAN announcements
+	if s.Announce != "" {
+		_, port, _ := net.SplitHostPort(l.Addr().String())
+		s.wg.Add(1)
+		go s.announce(port)
+	}
+
+	// close with context
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = s.Close()
+		case <-s.done:
 		}
+	}()
+
+	return nil
+}
+
+// Addr returns the listen address of the running server (useful with port 0).
+// It returns nil if the server is not started.
+func (s *Server) Addr() net.Addr {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if s.listener == nil {
+		return nil
 	}
+	return s.listener.Addr()
+}
+
+// Done returns a channel that is closed when the server is closed.
+func (s *Server) Done() <-chan struct{} {
+	return s.done
 }
 
-// handleRequest handles incoming requests from a client.
-func handleRequest(conn net.Conn, w *core.World, player uint8) {
+// Close stops the listener, closes all client connections and waits for all server goroutines.
+func (s *Server) Close() error {
+	s.mux.Lock()
+	if s.closed {
+		s.mux.Unlock()
+		return ErrServerClosed
+	}
+	s.closed = true
+	close(s.done)
+
+	// close listener and connections
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	for conn := range s.pending {
+		_ = conn.Close()
+	}
+	s.mux.Unlock()
+
+	// wait for goroutines
+	s.wg.Wait()
+	return err
+}
 
-	// prepare line reader
-	reader := bufio.NewReader(conn)
-	tp := textproto.NewReader(reader)
+// ServeConn handles a client connection that was accepted outside the server (BLOCKING!).
+// The connection gets the next player ID (or the seat chosen with AUTH) and is closed at the end.
+func (s *Server) ServeConn(conn net.Conn) error {
+	ss := s.newSession(conn, textproto.NewReader(bufio.NewReader(conn)))
 
-	// close at end
-	defer func(conn net.Conn) {
+	// assign player ID
+	var err error
+	if s.authRequired() {
+		err = s.authenticate(ss)
+	} else {
+		ss.player, err = s.join(conn, 0)
+	}
+	if err != nil {
 		_ = conn.Close()
-	}(conn)
+		return err
+	}
+
+	// handle commands
+	s.handle(ss)
+	return nil
+}
+
+// RunWorld updates the world with the tick rate of the server until the server is closed (BLOCKING!).
+// The optional afterUpdate function is called after every update; the loop stops if it returns false.
+// Without RunWorld, the world must be updated by the caller (e.g. by the GUI).
+func (s *Server) RunWorld(afterUpdate func() bool) {
+	s.mux.Lock()
+	s.ticking = true
+	rate := s.tickRate
+	s.mux.Unlock()
+
+	defer func() {
+		s.mux.Lock()
+		s.ticking = false
+		s.mux.Unlock()
+	}()
+
+	ticker := time.NewTicker(time.Second / time.Duration(rate))
+	defer ticker.Stop()
 
-	// loop
 	for {
-		// read one line (ended with \n or \r\n)
-		line, err := tp.ReadLine()
-		if err != nil {
-			break // EXIT
+		select {
+		case <-s.done:
+			return // EXIT
+		case <-ticker.C:
 		}
 
-		// trim line and split args
-		args := strings.Split(strings.TrimSpace(line), " ")
+		// update world
+		s.world.Update()
+		if afterUpdate != nil && !afterUpdate() {
+			return // EXIT
+		}
 
-		// extract com
-		var com string
-		if len(args) > 0 {
-			com = args[0]
+		// apply a new tick rate
+		if r := s.TickRate(); r != rate {
+			rate = r
+			ticker.Reset(time.Second / time.Duration(rate))
 		}
+	}
+}
+
+//--------  Control  -------------------------------------------------------------------------------------------------//
+
+// Pause freezes the world (see core.World.Freeze) until Resume is called, even if the game starts meanwhile.
+func (s *Server) Pause() {
+	s.mux.Lock()
+	s.paused = true
+	s.world.SetFreeze(true)
+	s.mux.Unlock()
+	s.logf("PAUSE GAME\n")
+}
+
+// Resume unfreezes the world, even if not all players are connected.
+func (s *Server) Resume() {
+	s.mux.Lock()
+	s.paused = false
+	s.world.SetFreeze(false)
+	s.mux.Unlock()
+	s.logf("RESUME GAME\n")
+}
 
-		// CHECK COMMANDS
-		switch com {
-		case "PLAYER":
-			comResponse(conn, strconv.Itoa(int(player)))
-		case "STATUS":
-			world := core.Censorship(w, player)
-			comResponse(conn, world.Json())
-		case core.FIRE:
-			x1, y1, x2, y2 := saveNums(args)
-			comResponseErr(conn, w.Fire(w.Tile(x1, y1), w.Tile(x2, y2), player))
-		case core.MOVE:
-			x1, y1, x2, y2 := saveNums(args)
-			_, err = w.Move(w.Tile(x1, y1), w.Tile(x2, y2), player)
-			comResponseErr(conn, err)
-		default:
-			comResponse(conn, "err: invalid command")
+// SetTickRate changes the number of world updates per second (see RunWorld).
+// It returns an error if the rate is out of range (1 to 1000) or the world isn't updated by RunWorld.
+func (s *Server) SetTickRate(rate int) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if rate < 1 || rate > 1000 {
+		return errors.New("tick rate must be between 1 and 1000")
+	}
+	if !s.ticking {
+		return errors.New("world is not updated by the server")
+	}
+	s.tickRate = rate
+	s.logf("tick rate %d\n", rate)
+	return nil
+}
+
+// Kick closes all connections of the player and returns the number of closed connections.
+// The seat stays assigned to the player, so it is never given to a new client automatically,
+// but the player can take it again at once with 'AUTH seat password' (see Password and SeatPasswords).
+func (s *Server) Kick(player uint8) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	n := 0
+	for conn, p := range s.conns {
+		if p == player {
+			_ = conn.Close()
+			delete(s.conns, conn) // the seat can be taken again with AUTH
+			n++
 		}
 	}
+	if n > 0 {
+		s.logf("kick player %d\n", player)
+	}
+	return n
+}
 
-	// exit
-	fmt.Printf("player %d has left\n", player)
+// End finishes the game with the given winner and reason (see core.World.End).
+func (s *Server) End(winner uint8, reason string) {
+	s.world.End(winner, reason)
+	s.logf("END GAME: %s\n", reason)
+}
+
+//--------  Getter  --------------------------------------------------------------------------------------------------//
+
+// World returns the world of this server.
+func (s *Server) World() *core.World {
+	return s.world
+}
+
+// Online returns the number of connected clients.
+func (s *Server) Online() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return len(s.conns)
+}
+
+// PlayerInfo describes a connected client.
+type PlayerInfo struct {
+	Player uint8  // Player ID (see PLAYER)
+	Addr   string // Remote address of the connection
+}
+
+// Players returns all connected clients sorted by player ID.
+func (s *Server) Players() []PlayerInfo {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	list := make([]PlayerInfo, 0, len(s.conns))
+	for conn, player := range s.conns {
+		list = append(list, PlayerInfo{Player: player, Addr: conn.RemoteAddr().String()})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Player < list[j].Player
+	})
+	return list
+}
+
+// TickRate returns the number of world updates per second of RunWorld.
+func (s *Server) TickRate() int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.tickRate
+}
+
+// Started returns true if all players have been connected and the game has started.
+func (s *Server) Started() bool {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	return s.started
 }
 
 //--------  Helper  --------------------------------------------------------------------------------------------------//
 
-// comResponse is a helper function and sends messages back to the clients.
-func comResponse(conn net.Conn, s string) {
-	_, err := conn.Write([]byte(fmt.Sprintf("%s\r\n", s)))
-	if err != nil {
-		fmt.Printf("err: %v\n", err)
+// acceptLoop accepts clients until the listener is closed.
+func (s *Server) acceptLoop(l net.Listener) {
+	defer s.wg.Done()
+
+	for {
+		// wait for incoming connection
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return // EXIT
+			}
+			s.logf("Error accepting: %v\n", err)
+			continue
+		}
+
+		// authenticate in a new goroutine
+		if s.authRequired() {
+			s.wg.Add(1)
+			go func(conn net.Conn) {
+				defer s.wg.Done()
+				if err := s.ServeConn(conn); err != nil {
+					s.logf("reject %v: %v\n", conn.RemoteAddr(), err)
+				}
+			}(conn)
+			continue
+		}
+
+		// assign player ID
+		ss := s.newSession(conn, textproto.NewReader(bufio.NewReader(conn)))
+		ss.player, err = s.join(conn, 0)
+		if err != nil {
+			s.logf("reject %v: %v\n", conn.RemoteAddr(), err)
+			_ = conn.Close()
+			continue
+		}
+
+		// Handle connections in a new goroutine.
+		go s.handle(ss)
 	}
 }
 
-// comResponseErr is a helper function and sends messages back to the clients with error handling.
-func comResponseErr(conn net.Conn, err error) {
-	if err != nil {
-		comResponse(conn, err.Error())
-	} else {
-		comResponse(conn, "OK")
+// join assigns a player ID to a new connection: the given seat or the next free seat without
+// a seat password (seat 0). A seat can be taken again, if no connection uses it. The given seat
+// can be shared by up to SeatConnections connections.
+// The game starts as soon as all players are connected.
+func (s *Server) join(conn net.Conn, seat uint8) (uint8, error) {
+	s.mux.Lock()
+
+	// check limits
+	limit := s.MaxClients
+	if limit <= 0 || limit > math.MaxUint8 {
+		limit = math.MaxUint8
+	}
+	if s.closed {
+		s.mux.Unlock()
+		return 0, ErrServerClosed
+	}
+
+	// find seat
+	player := seat
+	for id := 1; player == 0 && id <= math.MaxUint8; id++ {
+		if !s.seats[uint8(id)] && s.SeatPasswords[uint8(id)] == "" && !s.boundSeat(uint8(id)) && !s.botSeat(uint8(id)) {
+			player = uint8(id)
+		}
+	}
+	perSeat, taken := s.SeatConnections, 0
+	if perSeat < 1 {
+		perSeat = 1
+	}
+	for _, p := range s.conns {
+		if seat != 0 && p == seat {
+			taken++
+		}
+	}
+	if taken >= perSeat {
+		s.mux.Unlock()
+		return 0, errors.New("seat is taken")
+	}
+	if s.lost[player] {
+		s.mux.Unlock()
+		return 0, errors.New("seat has forfeited")
+	}
+	if player == 0 || (!s.seats[player] && s.players >= limit) {
+		s.mux.Unlock()
+		return 0, errors.New("server is full")
+	}
+
+	// assign player ID
+	s.seats[player] = true
+	s.players = len(s.seats)
+	s.conns[conn] = player
+	s.active[player] = time.Now()
+	s.wg.Add(1) // released by handle
+
+	// start game with all player
+	start := !s.started
+	for id := 1; id <= s.maxPlayer && id <= math.MaxUint8; id++ {
+		start = start && s.seats[uint8(id)]
+	}
+	if start {
+		s.started = true
+		s.touchAll()
+		s.world.SetFreeze(s.paused) // START GAME (see Pause)
+	}
+	s.mux.Unlock()
+
+	// events
+	s.logf("player %d from %v\n", player, conn.RemoteAddr())
+	if s.OnConnect != nil {
+		s.OnConnect(player, conn.RemoteAddr())
+	}
+	if start {
+		s.logf("START GAME\n")
+		if s.OnStart != nil {
+			s.OnStart()
+		}
 	}
+	return player, nil
 }
 
-// saveArgs is a helper function and returns 4 string arguments from the client commands.
-func saveArgs(args []string) (a1, a2, a3, a4 string) {
-	sArgs := make([]string, 5)
-	copy(sArgs, args)
-	return sArgs[1], sArgs[2], sArgs[3], sArgs[4]
+// newSession creates the session of a new connection (player ID 0 until join).
+func (s *Server) newSession(conn net.Conn, tp *textproto.Reader) *session {
+	ss := newSession(conn, tp, s.world, 0, s.Limits)
+	ss.logf = s.logf
+	ss.onCommand = func() { s.touch(ss.player) }
+	ss.transcript = s.Transcript
+	return ss
 }
 
-// saveNums is a helper function and returns 4 integer arguments from the client commands.
-func saveNums(args []string) (n1, n2, n3, n4 int) {
-	a1, a2, a3, a4 := saveArgs(args)
-	n1, _ = strconv.Atoi(a1)
-	n2, _ = strconv.Atoi(a2)
-	n3, _ = strconv.Atoi(a3)
-	n4, _ = strconv.Atoi(a4)
-	return
+// handle processes the commands of a connection and releases it at the end.
+func (s *Server) handle(ss *session) {
+	defer s.wg.Done()
+	conn, player := ss.conn, ss.player
+
+	// process commands
+	ss.serve()
+
+	// close and release
+	_ = conn.Close()
+	s.mux.Lock()
+	delete(s.conns, conn)
+	s.mux.Unlock()
+
+	// exit
+	u := ss.limiter.usage
+	s.logf("player %d has left (%d commands, %d status, %d throttled)\n", player, u.Commands, u.Status, u.Throttled)
+	if s.OnDisconnect != nil {
+		s.OnDisconnect(player, conn.RemoteAddr())
+	}
+}
+
+// logf writes to the server log.
+func (s *Server) logf(format string, v ...any) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, v...)
+	} else {
+		defaultLogger.Printf(format, v...)
+	}
+}
+
+// defaultLogger writes the server log to stdout.
+var defaultLogger = log.New(os.Stdout, "", 0)
+
+//--------  Response  ------------------------------------------------------------------------------------------------//
+
+// comResponse is a helper function and sends messages back to the clients.
+func comResponse(conn net.Conn, s string) {
+	_, err := conn.Write([]byte(fmt.Sprintf("%s\r\n", s)))
+	if err != nil {
+		fmt.Printf("err: %v\n", err)
+	}
 }
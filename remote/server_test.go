@@ -0,0 +1,94 @@
+package remote
+
+import (
+	"context"
+	"io"
+	"log"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testServer starts a server on a random local port.
+// The config function can set the optional server settings before the start.
+func testServer(t *testing.T, world *core.World, maxPlayer int, config func(s *Server)) (s *Server, host, port string) {
+	s = NewServer("127.0.0.1:0", world, maxPlayer)
+	s.Logger = log.New(io.Discard, "", 0)
+	if config != nil {
+		config(s)
+	}
+	require.NoError(t, s.Start(context.Background()))
+	t.Cleanup(func() { _ = s.Close() })
+
+	host, port, err := net.SplitHostPort(s.Addr().String())
+	require.NoError(t, err)
+	return s, host, port
+}
+
+func TestServerStartGame(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(4, 4).Unit = core.NewUnit(core.BLUE, core.TANK)
+
+	started := make(chan bool, 1)
+	s, host, port := testServer(t, world, 2, func(s *Server) {
+		s.OnStart = func() { started <- true }
+	})
+
+	// first player
+	c1, err := NewClient(host, port)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(1), c1.Player())
+	assert.False(t, s.Started())
+	assert.True(t, world.Clone().Freeze)
+
+	// second player starts the game
+	c2, err := NewClient(host, port)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(2), c2.Player())
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("game not started")
+	}
+	assert.True(t, s.Started())
+	assert.False(t, world.Clone().Freeze)
+	assert.Equal(t, 2, s.Online())
+}
+
+func TestServerClose(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	s := NewServer("127.0.0.1:0", core.NewWorld(3, 3), 2)
+	s.Logger = log.New(io.Discard, "", 0)
+	left := make(chan uint8, 1)
+	s.OnDisconnect = func(player uint8, _ net.Addr) { left <- player }
+	require.NoError(t, s.Start(ctx))
+	assert.Error(t, s.Start(ctx))
+
+	// connect a client
+	conn, err := net.Dial("tcp", s.Addr().String())
+	require.NoError(t, err)
+	defer func() { _ = conn.Close() }()
+	_, err = conn.Write([]byte("PLAYER\r\n"))
+	require.NoError(t, err)
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	require.NoError(t, err)
+	assert.Equal(t, "1\r\n", string(buf[:n]))
+
+	// cancel the context
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatal("server not closed")
+	}
+	assert.Equal(t, uint8(1), <-left)
+	assert.ErrorIs(t, s.Close(), ErrServerClosed)
+	assert.ErrorIs(t, s.ServeConn(conn), ErrServerClosed)
+}
@@ -0,0 +1,408 @@
+package remote
+
+/*
+  This file provides the session of a single client connection. A session reads the commands of the client,
+  implements them in the world and sends the responses in the protocol version chosen by the client.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"net"
+	"net/textproto"
+	"strconv"
+	"time"
+)
+
+// session holds the state of a single client connection.
+type session struct {
+	conn      net.Conn                      // Client connection
+	tp        *textproto.Reader             // Line reader of the connection
+	world     *core.World                   // World controlled by the client
+	player    uint8                         // Player ID of the client
+	version   int                           // Protocol version (see ProtocolV1 and ProtocolV2)
+	compact   bool                          // Send the compact STATUS (see CompactStatus)
+	gzip      bool                          // Compress MAP and STATUS payloads (see gzipBase64)
+	mapInfo   *MapInfo                      // Static map sent to the client (see MAP)
+	control   *control                      // Units controlled by the connection (nil = all, see CONTROL)
+	bound     bool                          // The restriction is set by the server (see SeatControl)
+	limiter   *limiter                      // Rate limits and usage of the connection
+	throttled bool                          // Indicates if the last command was throttled
+	logf      func(format string, v ...any) // Server log (optional)
+	onCommand func()                        // Called for every game command, e.g. MOVE (optional, see InactiveTimeout)
+
+	transcript *Transcript // Records the commands and responses (optional)
+	line       string      // Last command line (see transcript)
+	received   time.Time   // Time of the last command line (see transcript)
+	iteration  uint64      // Iteration of the world at the last command line (see transcript)
+}
+
+// newSession creates a new session with protocol version 1.
+func newSession(conn net.Conn, tp *textproto.Reader, w *core.World, player uint8, limits Limits) *session {
+	return &session{
+		conn:    conn,
+		tp:      tp,
+		world:   w,
+		player:  player,
+		version: ProtocolV1,
+		limiter: &limiter{limits: limits},
+	}
+}
+
+// serve processes the in-game commands of a client until the connection is closed.
+func (ss *session) serve() {
+	w := ss.world
+
+	// loop
+	for {
+		// close idle connections
+		if timeout := ss.limiter.limits.IdleTimeout; timeout > 0 {
+			_ = ss.conn.SetReadDeadline(time.Now().Add(timeout))
+		}
+
+		// read one line (ended with \n or \r\n)
+		line, err := readLine(ss.tp.R, ss.limiter.limits.MaxLineLength)
+		ss.read(line)
+		if errors.Is(err, ErrLineTooLong) {
+			ss.limiter.usage.Throttled++
+			ss.throttle(ErrLineTooLong, "line length", map[string]interface{}{"Max": ss.limiter.limits.MaxLineLength})
+			continue
+		}
+		var netErr net.Error
+		if errors.As(err, &netErr) && netErr.Timeout() && ss.logf != nil {
+			ss.logf("player %d idle timeout\n", ss.player)
+		}
+		if err != nil {
+			break // EXIT
+		}
+
+		// parse command
+		cmd, err := parseCommand(line, gameGrammar)
+
+		// rate limits
+		if !ss.allow(cmd.Name) {
+			continue
+		}
+
+		// invalid command
+		if err != nil {
+			ss.replyError(errorCode(err), err.Error(), errorDetails(err))
+			continue
+		}
+
+		// restricted connection (see CONTROL), scheduled commands are checked early
+		if !ss.controls(cmd) {
+			continue
+		}
+
+		// only game commands keep the player active (STATUS polling doesn't)
+		if gameGrammar[cmd.Name].schedule && ss.onCommand != nil {
+			ss.onCommand()
+		}
+
+		// scheduled command (e.g. MOVE 1 1 2 1 @1200)
+		if cmd.At > 0 {
+			ss.schedule(cmd)
+			continue
+		}
+
+		// CHECK COMMANDS
+		switch cmd.Name {
+		case "PROTOCOL":
+			ss.protocol(cmd)
+		case "AUTH", "PLAYER": // already authenticated
+			id := strconv.Itoa(int(ss.player))
+			ss.reply(id, []byte(id))
+		case "STATUS":
+			ss.status()
+		case "RESULTS":
+			ss.results()
+		case "CONTROL":
+			ss.setControl(cmd)
+		case "MAP":
+			ss.mapInfo = NewMapInfo(w)
+			ss.replyPayload(ss.mapInfo)
+		case "COMPACT":
+			ss.compact = cmd.Arg(0) == "ON"
+			ss.reply("OK", nil)
+		case "GZIP":
+			ss.gzip = cmd.Arg(0) == "ON"
+			ss.reply("OK", nil)
+		case "PATH", "RANGE", "DIST", "LOS":
+			ss.query(cmd)
+		case "DEBUG":
+			ss.debug(cmd)
+		case core.FIRE, core.MOVE, "UFIRE", "UMOVE":
+			ss.execute(cmd)
+		}
+	}
+}
+
+// protocol switches the protocol version of the session (see PROTOCOL).
+func (ss *session) protocol(cmd *Command) {
+	version := cmd.Int(0)
+	if version != ProtocolV1 && version != ProtocolV2 {
+		ss.replyError(CodeInvalidArgs, "unsupported protocol version", nil)
+		return
+	}
+	ss.version = version
+	ss.reply("OK", nil)
+}
+
+// allow checks the rate limits of the command and sends a throttle response if a limit is exceeded.
+func (ss *session) allow(com string) bool {
+	l := ss.limiter
+	switch com {
+	case "STATUS", "MAP", "PATH", "RANGE", "DIST", "LOS": // clone the world
+		if !l.allowStatus(time.Now()) {
+			ss.throttle(ErrThrottled, "STATUS per second", map[string]interface{}{"Max": l.limits.StatusPerSecond})
+			return false
+		}
+	default:
+		if !l.allowCommand(ss.world.CurrentIteration(), ss.world.Frozen(), time.Now()) {
+			ss.throttle(ErrThrottled, "commands per iteration", map[string]interface{}{"Max": l.limits.CommandsPerIteration})
+			return false
+		}
+	}
+	ss.throttled = false
+	return true
+}
+
+// throttle sends a throttle response and logs the first one of a row.
+func (ss *session) throttle(err error, limit string, details map[string]interface{}) {
+	if !ss.throttled && ss.logf != nil {
+		ss.logf("player %d throttled: %s\n", ss.player, limit)
+	}
+	ss.throttled = true
+	details["Limit"] = limit
+	ss.replyError(errorCode(err), err.Error(), details)
+}
+
+// command converts a MOVE, FIRE, UMOVE or UFIRE command of the player. The restriction of the connection
+// is checked when the command starts (see CONTROL).
+func (ss *session) command(cmd *Command) core.Scheduled {
+	sc := core.Scheduled{Player: ss.player, Iteration: cmd.At, Filter: ss.control.filter()}
+	switch cmd.Name {
+	case core.FIRE, core.MOVE:
+		sc.Name = cmd.Name
+		sc.From = [2]int{cmd.Int(0), cmd.Int(1)}
+		sc.To = [2]int{cmd.Int(2), cmd.Int(3)}
+	case "UFIRE", "UMOVE":
+		sc.Name = cmd.Name[1:]
+		sc.Unit = cmd.Int(0)
+		sc.To = [2]int{cmd.Int(1), cmd.Int(2)}
+	}
+	return sc
+}
+
+// execute starts a MOVE, FIRE, UMOVE or UFIRE command now (see core.World.Execute).
+func (ss *session) execute(cmd *Command) {
+	sc := ss.command(cmd)
+	err := ss.world.Execute(sc)
+
+	// unit after the command
+	if sc.Unit != 0 {
+		ss.replyCommand(err, ss.world.UnitByID(sc.Unit))
+	} else {
+		ss.replyCommand(err, ss.world.UnitAt(ss.world.Tile(sc.From[0], sc.From[1])))
+	}
+}
+
+// schedule queues a MOVE, FIRE, UMOVE or UFIRE command for its execution iteration (see core.World.Schedule).
+// Protocol version 2 sends the queued command with its command ID as data.
+func (ss *session) schedule(cmd *Command) {
+	queued, err := ss.world.Schedule(ss.command(cmd))
+	if err != nil {
+		ss.replyCommand(err, nil)
+		return
+	}
+	b, _ := json.Marshal(queued)
+	ss.reply("OK", b)
+}
+
+// results sends and removes the outcomes of the player's commands (see core.Outcome).
+// A restricted connection only gets the outcomes of its units (see CONTROL).
+func (ss *session) results() {
+	list := ss.world.TakeOutcomesFunc(ss.player, ss.control.matches)
+	if list == nil {
+		list = []*core.Outcome{} // always a JSON array
+	}
+	ss.replyPayload(list)
+}
+
+// status sends the censored world of the player (full or compact).
+func (ss *session) status() {
+	world := core.Censorship(ss.world, ss.player)
+
+	// full world
+	if !ss.compact {
+		ss.replyPayload(world)
+		return
+	}
+
+	// compact world
+	if ss.mapInfo == nil {
+		ss.replyError(CodeNoMap, "compact STATUS requires MAP", nil)
+		return
+	}
+	ss.replyPayload(NewCompactStatus(world, ss.mapInfo, ss.player))
+}
+
+// debug adds or removes debug annotations of the player (see core.World.AddDebug).
+func (ss *session) debug(cmd *Command) {
+	var err error
+	switch cmd.Arg(0) {
+	case core.DebugMark:
+		shape := core.DebugShape{Kind: core.DebugMark, X: cmd.Int(1), Y: cmd.Int(2), Color: cmd.Arg(3)}
+		err = ss.world.AddDebug(ss.player, shape, cmd.Int(4))
+	case core.DebugLine:
+		shape := core.DebugShape{Kind: core.DebugLine, X: cmd.Int(1), Y: cmd.Int(2), X2: cmd.Int(3), Y2: cmd.Int(4), Color: cmd.Arg(5)}
+		err = ss.world.AddDebug(ss.player, shape, cmd.Int(6))
+	case core.DebugLabel:
+		shape := core.DebugShape{Kind: core.DebugLabel, X: cmd.Int(1), Y: cmd.Int(2), Color: cmd.Arg(3), Text: cmd.Arg(5)}
+		err = ss.world.AddDebug(ss.player, shape, cmd.Int(4))
+	case "CLEAR":
+		ss.world.ClearDebug(ss.player)
+	}
+
+	// reply
+	if err != nil {
+		code := errorCode(err)
+		if code == CodeError {
+			code = CodeInvalidArgs // invalid color, lifetime or label
+		}
+		ss.replyError(code, err.Error(), nil)
+		return
+	}
+	ss.reply("OK", nil)
+}
+
+//--------  Response  ------------------------------------------------------------------------------------------------//
+
+// replyPayload sends a large JSON payload (MAP and STATUS).
+// If gzip is enabled, the JSON is compressed and sent as base64 (in version 2 as JSON string).
+func (ss *session) replyPayload(v interface{}) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		ss.replyError(CodeError, err.Error(), nil)
+		return
+	}
+
+	// compress
+	if ss.gzip {
+		s, err := gzipBase64(b)
+		if err != nil {
+			ss.replyError(CodeError, err.Error(), nil)
+			return
+		}
+		ss.reply(s, []byte(strconv.Quote(s)))
+		return
+	}
+
+	ss.reply(string(b), b)
+}
+
+// reply sends a successful response.
+// Protocol version 1 sends the text, version 2 embeds the data (JSON) in a Response.
+func (ss *session) reply(text string, data []byte) {
+	if ss.version == ProtocolV1 {
+		ss.write(text)
+		return
+	}
+	ss.send(&Response{
+		OK:   true,
+		Code: CodeOK,
+		Data: data,
+	})
+}
+
+// replyError sends an error response.
+// Protocol version 1 sends the message with the prefix 'err: '.
+func (ss *session) replyError(code, msg string, details map[string]interface{}) {
+	if ss.version == ProtocolV1 {
+		ss.write(v1ErrorPrefix + msg)
+		return
+	}
+	ss.send(&Response{
+		Code:    code,
+		Error:   msg,
+		Details: details,
+	})
+}
+
+// replyCommand sends the result of a MOVE, FIRE, UMOVE or UFIRE command (unit = copy after the command).
+// Protocol version 1 sends 'OK' or the plain error message (see core errors).
+// Version 2 also sends the error code, details about the unit and the resulting activity.
+func (ss *session) replyCommand(err error, unit *core.Unit) {
+	if ss.version == ProtocolV1 {
+		if err != nil {
+			ss.write(err.Error())
+		} else {
+			ss.write("OK")
+		}
+		return
+	}
+
+	// success
+	if err == nil {
+		resp := &Response{OK: true, Code: CodeOK}
+		if unit != nil {
+			resp.Activity = unit.Activity
+		}
+		ss.send(resp)
+		return
+	}
+
+	// error details
+	var details map[string]interface{}
+	if unit != nil && unit.Player == ss.player {
+		switch {
+		case errors.Is(err, core.ErrBusy) && unit.Activity != nil:
+			details = map[string]interface{}{"Activity": unit.Activity.Name, "End": unit.Activity.End}
+		case errors.Is(err, core.ErrNotInRange):
+			details = map[string]interface{}{"FireRange": unit.FireRange}
+		case errors.Is(err, core.ErrNoAmmo):
+			details = map[string]interface{}{"Ammunition": unit.Ammunition}
+		case errors.Is(err, core.ErrInvalidTarget):
+			details = map[string]interface{}{"UnitType": string(unit.Type)}
+		}
+	}
+	ss.send(&Response{
+		Code:    errorCode(err),
+		Error:   err.Error(),
+		Details: details,
+	})
+}
+
+// send writes a Response as a single JSON line.
+func (ss *session) send(resp *Response) {
+	b, err := json.Marshal(resp)
+	if err != nil {
+		b, _ = json.Marshal(&Response{Code: CodeError, Error: err.Error()})
+	}
+	ss.write(string(b))
+}
+
+// read stores a command line with its time and iteration for the transcript.
+func (ss *session) read(line string) {
+	if ss.transcript != nil {
+		ss.line, ss.received, ss.iteration = line, time.Now(), ss.world.CurrentIteration()
+	}
+}
+
+// write sends a response line and records it with the last command (see transcript).
+func (ss *session) write(s string) {
+	comResponse(ss.conn, s)
+
+	if ss.transcript != nil {
+		ss.transcript.Record(TranscriptEntry{
+			Time:      ss.received,
+			Conn:      ss.conn.RemoteAddr().String(),
+			Player:    ss.player,
+			Iteration: ss.iteration,
+			Command:   ss.line,
+			Response:  s,
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package remote
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/textproto"
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rawConn is a line based test connection without the Client logic.
+type rawConn struct {
+	t    *testing.T
+	conn net.Conn
+	tp   *textproto.Reader
+}
+
+// dialRaw connects to the server address.
+func dialRaw(t *testing.T, addr string) *rawConn {
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+	return &rawConn{t: t, conn: conn, tp: textproto.NewReader(bufio.NewReader(conn))}
+}
+
+// cmd sends a line and returns the response line.
+func (rc *rawConn) cmd(line string) string {
+	_, err := rc.conn.Write([]byte(line + "\r\n"))
+	require.NoError(rc.t, err)
+	resp, err := rc.tp.ReadLine()
+	require.NoError(rc.t, err)
+	return resp
+}
+
+// cmdV2 sends a line and parses the structured response.
+func (rc *rawConn) cmdV2(line string) *Response {
+	resp := &Response{}
+	require.NoError(rc.t, json.Unmarshal([]byte(rc.cmd(line)), resp))
+	return resp
+}
+
+func TestProtocolV1(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(1, 1).Unit = core.NewUnit(core.RED, core.TANK)
+	s, _, _ := testServer(t, world, 1, nil)
+
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", rc.cmd("PLAYER"))
+	assert.Equal(t, "err: invalid command", rc.cmd("FOO"))
+	assert.Equal(t, "no player unit found", rc.cmd("MOVE 2 2 3 3"))
+	assert.Equal(t, "input is nil", rc.cmd("FIRE 1 1 9 9"))
+	assert.Equal(t, "err: unsupported protocol version", rc.cmd("PROTOCOL 3"))
+}
+
+func TestProtocolV2(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(1, 1).Unit = core.NewUnit(core.RED, core.TANK)
+	s, _, _ := testServer(t, world, 1, nil)
+
+	rc := dialRaw(t, s.Addr().String())
+	assert.True(t, rc.cmdV2("PROTOCOL 2").OK)
+
+	// player
+	resp := rc.cmdV2("PLAYER")
+	assert.True(t, resp.OK)
+	assert.Equal(t, "1", string(resp.Data))
+
+	// invalid command
+	resp = rc.cmdV2("FOO")
+	assert.False(t, resp.OK)
+	assert.Equal(t, CodeInvalidCommand, resp.Code)
+
+	// typed errors
+	resp = rc.cmdV2("MOVE 2 2 3 3")
+	assert.Equal(t, CodeNoUnit, resp.Code)
+	assert.Equal(t, core.ErrNoUnit.Error(), resp.Error)
+
+	// move with activity
+	resp = rc.cmdV2("MOVE 1 1 2 1")
+	assert.True(t, resp.OK)
+	require.NotNil(t, resp.Activity)
+	assert.Equal(t, core.MOVE, resp.Activity.Name)
+	assert.Equal(t, [2]int{2, 1}, resp.Activity.To)
+
+	// busy with details
+	resp = rc.cmdV2("FIRE 1 1 2 1")
+	assert.Equal(t, CodeBusy, resp.Code)
+	assert.Equal(t, core.MOVE, resp.Details["Activity"])
+}
+
+func TestUnitCommands(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	tank := core.NewUnit(core.RED, core.TANK)
+	world.Tile(1, 1).Unit = tank
+	world.Tile(3, 3).Unit = core.NewUnit(core.BLUE, core.TANK)
+	s, _, _ := testServer(t, world, 1, nil)
+
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "no player unit found", rc.cmd(fmt.Sprintf("UMOVE %d 1 1", tank.ID+1)))
+	assert.Equal(t, "no player unit found", rc.cmd(fmt.Sprintf("UMOVE %d 2 2", world.Tile(3, 3).Unit.ID))) // other player
+	assert.True(t, rc.cmdV2("PROTOCOL 2").OK)
+
+	// move by ID
+	resp := rc.cmdV2(fmt.Sprintf("UMOVE %d 2 1", tank.ID))
+	assert.True(t, resp.OK)
+	require.NotNil(t, resp.Activity)
+	assert.Equal(t, core.MOVE, resp.Activity.Name)
+
+	// busy with details
+	resp = rc.cmdV2(fmt.Sprintf("UFIRE %d 2 1", tank.ID))
+	assert.Equal(t, CodeBusy, resp.Code)
+	assert.Equal(t, core.MOVE, resp.Details["Activity"])
+
+	// invalid arguments
+	resp = rc.cmdV2("UFIRE x 2 1")
+	assert.Equal(t, CodeInvalidArgs, resp.Code)
+	assert.Equal(t, "UFIRE id x y [@iteration]", resp.Details["Usage"])
+}
+
+func TestResults(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(1, 1).Unit = core.NewUnit(core.RED, core.TANK)
+	s, _, _ := testServer(t, world, 1, nil)
+
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "[]", rc.cmd("RESULTS"))
+	assert.True(t, rc.cmdV2("PROTOCOL 2").OK)
+
+	// command ID
+	resp := rc.cmdV2("MOVE 1 1 2 1")
+	require.True(t, resp.OK)
+	require.NotNil(t, resp.Activity)
+	assert.Equal(t, uint64(1), resp.Activity.ID)
+
+	// outcome
+	for i := 0; i < 3; i++ {
+		world.Update()
+	}
+	resp = rc.cmdV2("RESULTS")
+	require.True(t, resp.OK)
+	var list []*core.Outcome
+	require.NoError(t, json.Unmarshal(resp.Data, &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, uint64(1), list[0].Command)
+	assert.Equal(t, core.OutcomeCompleted, list[0].Result)
+	assert.Equal(t, "[]", string(rc.cmdV2("RESULTS").Data))
+}
+
+func TestScheduledCommands(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	tank := core.NewUnit(core.RED, core.TANK)
+	world.Tile(1, 1).Unit = tank
+	s, _, _ := testServer(t, world, 1, nil)
+
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "OK", rc.cmd("MOVE 1 1 2 1 @3"))
+	assert.Equal(t, "input is nil", rc.cmd("MOVE 1 1 9 9 @3"))
+	assert.True(t, rc.cmdV2("PROTOCOL 2").OK)
+
+	// queued by unit ID
+	resp := rc.cmdV2(fmt.Sprintf("UFIRE %d 1 2 @3", tank.ID))
+	require.True(t, resp.OK)
+	var queued core.Scheduled
+	require.NoError(t, json.Unmarshal(resp.Data, &queued))
+	assert.Equal(t, core.Scheduled{ID: 2, Player: core.RED, Name: core.FIRE, Unit: tank.ID, To: [2]int{1, 2}, Iteration: 3}, queued)
+
+	// start at iteration 3: the move is started, the fire is rejected (busy)
+	for i := 0; i < 4; i++ {
+		world.Update()
+	}
+	assert.Equal(t, uint64(3), world.UnitByID(tank.ID).Activity.Start)
+	resp = rc.cmdV2("RESULTS")
+	var list []*core.Outcome
+	require.NoError(t, json.Unmarshal(resp.Data, &list))
+	require.Len(t, list, 1)
+	assert.Equal(t, core.OutcomeRejected, list[0].Result)
+	assert.Equal(t, uint64(2), list[0].Command)
+	assert.Equal(t, core.ErrBusy.Error(), list[0].Error)
+
+	// too late
+	resp = rc.cmdV2("MOVE 1 1 2 1 @2")
+	assert.Equal(t, CodePastIteration, resp.Code)
+}
+
+func TestDebugCommands(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	s, _, _ := testServer(t, world, 1, nil)
+
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "OK", rc.cmd("debug mark 1 1 red 30"))
+	assert.Equal(t, "OK", rc.cmd("DEBUG LINE 0 0 4 4 #00ff00 30"))
+	assert.Equal(t, "OK", rc.cmd("DEBUG LABEL 2 2 blue 30 next_target"))
+	debug := world.Clone().Debug[core.RED]
+	require.Len(t, debug, 3)
+	assert.Equal(t, core.DebugShape{Kind: core.DebugLine, X2: 4, Y2: 4, Color: "#00ff00", Expires: 30}, *debug[1])
+	assert.Equal(t, "next_target", debug[2].Text)
+
+	// errors
+	require.True(t, rc.cmdV2("PROTOCOL 2").OK)
+	assert.Equal(t, CodeInvalidInput, rc.cmdV2("DEBUG MARK 9 9 red 30").Code)
+	assert.Equal(t, CodeInvalidArgs, rc.cmdV2("DEBUG MARK 1 1 pink 30").Code)
+	assert.Equal(t, CodeInvalidArgs, rc.cmdV2("DEBUG MARK 1 1 red 0").Code)
+	assert.Equal(t, CodeInvalidArgs, rc.cmdV2("DEBUG CIRCLE 1 1").Code)
+
+	// clear
+	assert.True(t, rc.cmdV2("DEBUG CLEAR").OK)
+	assert.Empty(t, world.Clone().Debug)
+}
+
+func TestError(t *testing.T) {
+	var err error = &Error{Code: CodeNoAmmo, Message: core.ErrNoAmmo.Error()}
+	assert.ErrorIs(t, err, core.ErrNoAmmo)
+	assert.NotErrorIs(t, err, core.ErrBusy)
+	assert.Equal(t, CodeNotInRange, errorCode(core.ErrNotInRange))
+	assert.Equal(t, CodeError, errorCode(assert.AnError))
+}
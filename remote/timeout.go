@@ -0,0 +1,164 @@
+package remote
+
+/*
+  This file provides the timeouts of a server, so a game never stalls on a broken client.
+  If not all players are connected within the JoinTimeout or a player sends no game command (MOVE, FIRE,
+  UMOVE or UFIRE, also scheduled) within the InactiveTimeout of a running game, the seat is handled by the
+  TimeoutPolicy: the player forfeits (all units are removed) or a bot takes over the seat. Every case is
+  recorded in the result of the game (see core.Forfeit).
+*/
+
+import (
+	"fmt"
+	"time"
+)
+
+// TimeoutPolicy decides what happens to a seat after a timeout (see Server.JoinTimeout and Server.InactiveTimeout).
+type TimeoutPolicy int
+
+// timeout policies
+const (
+	PolicyForfeit TimeoutPolicy = iota // the player loses all units and leaves the game
+	PolicyBot                          // a bot takes over the seat (see Server.TimeoutBot)
+)
+
+// timeout reasons (see core.Forfeit)
+const (
+	reasonJoinTimeout = "join timeout"
+	reasonInactive    = "inactive"
+)
+
+// watchdog checks the timeouts until the server is closed.
+func (s *Server) watchdog() {
+	defer s.wg.Done()
+
+	// check interval
+	interval := time.Second
+	for _, timeout := range []time.Duration{s.JoinTimeout, s.InactiveTimeout} {
+		if timeout > 0 && timeout/10 < interval {
+			interval = timeout / 10
+		}
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return // EXIT
+		case now := <-ticker.C:
+			s.checkTimeouts(now)
+		}
+	}
+}
+
+// checkTimeouts applies the timeout policy to all seats with an expired timeout.
+func (s *Server) checkTimeouts(now time.Time) {
+	world := s.world.Clone()
+	if world == nil || world.Result != nil {
+		return // game has ended
+	}
+
+	// find expired seats
+	expired := make(map[uint8]string)
+	s.mux.Lock()
+	if s.started && world.Freeze {
+		s.touchAll() // paused game (see Pause)
+	}
+	for id := 1; id <= s.maxPlayer; id++ {
+		player := uint8(id)
+		if s.lost[player] {
+			continue
+		}
+		switch {
+		case !s.started && s.JoinTimeout > 0 && !s.seats[player] && now.Sub(s.startAt) >= s.JoinTimeout:
+			expired[player] = reasonJoinTimeout
+		case s.started && s.InactiveTimeout > 0 && now.Sub(s.active[player]) >= s.InactiveTimeout:
+			expired[player] = reasonInactive
+		}
+	}
+	s.mux.Unlock()
+
+	// apply policy
+	for id := 1; id <= s.maxPlayer; id++ {
+		if reason, ok := expired[uint8(id)]; ok {
+			s.timeout(uint8(id), reason)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+
+	// start the game without the missing players
+	s.mux.Lock()
+	start := !s.started
+	if start {
+		s.started = true
+		s.touchAll()
+		s.world.SetFreeze(s.paused) // START GAME (see Pause)
+	}
+	s.mux.Unlock()
+	if start {
+		s.logf("START GAME\n")
+		if s.OnStart != nil {
+			s.OnStart()
+		}
+	}
+
+	// end the game if fewer than two players are left
+	if world = s.world.Clone(); world == nil {
+		return
+	}
+	if winner, over := world.GameOver(); over && (s.maxPlayer > 1 || winner == 0) {
+		reason := "no player is left"
+		if winner != 0 {
+			reason = fmt.Sprintf("player %d has won", winner)
+		}
+		s.End(winner, reason)
+	}
+}
+
+// timeout closes the connections of the player and lets a bot take over the seat (PolicyBot)
+// or removes the player from the game (PolicyForfeit).
+func (s *Server) timeout(player uint8, reason string) {
+	s.Kick(player)
+
+	// replace with bot
+	if s.TimeoutPolicy == PolicyBot && s.TimeoutBot != nil {
+		client, err := s.ConnectBot(player)
+		if err == nil {
+			s.logf("player %d %s: replaced by bot\n", player, reason)
+			s.world.Forfeit(player, reason, true)
+			go s.TimeoutBot(client)
+			return
+		}
+		s.logf("player %d %s: bot failed: %v\n", player, reason, err)
+	}
+
+	// forfeit
+	s.mux.Lock()
+	s.lost[player] = true
+	s.seats[player] = true // never assigned again
+	s.players = len(s.seats)
+	s.mux.Unlock()
+	s.logf("player %d %s: forfeit\n", player, reason)
+	s.world.Forfeit(player, reason, false)
+}
+
+// touch sets the time of the last game command of the player (see InactiveTimeout).
+func (s *Server) touch(player uint8) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if player != 0 {
+		s.active[player] = time.Now()
+	}
+}
+
+// touchAll resets the idle time of all players, e.g. when the game starts (mutex must be locked).
+func (s *Server) touchAll() {
+	now := time.Now()
+	for id := 1; id <= s.maxPlayer; id++ {
+		s.active[uint8(id)] = now
+	}
+}
@@ -0,0 +1,129 @@
+package remote
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// timeoutWorld returns a world with a tank for player 1 and 2.
+func timeoutWorld() *core.World {
+	world := core.NewWorld(5, 5)
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	world.Tile(4, 4).Unit = core.NewUnit(core.BLUE, core.TANK)
+	return world
+}
+
+// result waits for the end of the game.
+func result(t *testing.T, world *core.World) *core.Result {
+	require.Eventually(t, func() bool { return world.Clone().Result != nil }, 2*time.Second, 10*time.Millisecond)
+	return world.Clone().Result
+}
+
+func TestJoinTimeout(t *testing.T) {
+	world := timeoutWorld()
+	s, _, _ := testServer(t, world, 2, func(s *Server) {
+		s.JoinTimeout = 100 * time.Millisecond
+	})
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", rc.cmd("PLAYER"))
+
+	// player 2 never joins
+	res := result(t, world)
+	assert.True(t, s.Started())
+	assert.Equal(t, uint8(1), res.Winner)
+	assert.Equal(t, []core.Forfeit{{Player: 2, Reason: reasonJoinTimeout}}, res.Forfeits)
+
+	// the seat is lost
+	rc = dialRaw(t, s.Addr().String())
+	assert.Equal(t, "3", rc.cmd("PLAYER"))
+}
+
+func TestInactiveTimeout(t *testing.T) {
+	world := timeoutWorld()
+	bot := make(chan uint8, 1)
+	s, _, _ := testServer(t, world, 2, func(s *Server) {
+		s.InactiveTimeout = 200 * time.Millisecond
+		s.TimeoutPolicy = PolicyBot
+		s.TimeoutBot = func(client *Client) { bot <- client.Player() }
+	})
+	active := dialRaw(t, s.Addr().String())
+	idle := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "2", idle.cmd("PLAYER"))
+	require.True(t, s.Started())
+
+	// player 2 is replaced by a bot
+	for n := 0; n < 10; n++ {
+		assert.NotEmpty(t, active.cmd("MOVE 0 0 0 1"))
+		time.Sleep(40 * time.Millisecond)
+	}
+	select {
+	case player := <-bot:
+		assert.Equal(t, uint8(2), player)
+	case <-time.After(time.Second):
+		t.Fatal("no bot")
+	}
+	_, err := idle.tp.ReadLine()
+	assert.Error(t, err) // kicked
+	assert.Equal(t, []core.Forfeit{{Player: 2, Reason: reasonInactive, Replaced: true}}, world.Clone().Forfeits)
+	assert.NotNil(t, world.UnitAt(world.Tile(4, 4)))
+	assert.Nil(t, world.Clone().Result)
+}
+
+func TestInactiveForfeit(t *testing.T) {
+	world := timeoutWorld()
+	s, _, _ := testServer(t, world, 2, func(s *Server) {
+		s.InactiveTimeout = 100 * time.Millisecond
+	})
+	rc := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "1", rc.cmd("PLAYER"))
+	go func() {
+		// player 1 stays active
+		for world.Clone().Result == nil && rc.conn.SetDeadline(time.Now().Add(time.Second)) == nil {
+			if _, err := rc.conn.Write([]byte("MOVE 0 0 0 1\r\n")); err != nil {
+				return
+			}
+			_, _ = rc.tp.ReadLine()
+			time.Sleep(20 * time.Millisecond)
+		}
+	}()
+
+	// player 2 leaves
+	left := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "2", left.cmd("PLAYER"))
+	require.True(t, s.Started())
+	_ = left.conn.Close()
+
+	res := result(t, world)
+	assert.Equal(t, uint8(1), res.Winner)
+	assert.Equal(t, []core.Forfeit{{Player: 2, Reason: reasonInactive, Iteration: res.Iteration}}, res.Forfeits)
+	assert.Nil(t, world.UnitAt(world.Tile(4, 4)))
+}
+
+func TestInactivePolling(t *testing.T) {
+	world := timeoutWorld()
+	s, _, _ := testServer(t, world, 2, func(s *Server) {
+		s.InactiveTimeout = 200 * time.Millisecond
+	})
+	active := dialRaw(t, s.Addr().String())
+	polling := dialRaw(t, s.Addr().String())
+	assert.Equal(t, "2", polling.cmd("PLAYER"))
+	require.True(t, s.Started())
+
+	// player 2 only polls STATUS (e.g. a hung AI with remote.Client)
+	for n := 0; n < 10; n++ {
+		assert.NotEmpty(t, active.cmd("MOVE 0 0 0 1"))
+		assert.NotEmpty(t, active.cmd("STATUS"))
+		if _, err := polling.conn.Write([]byte("STATUS\r\n")); err == nil {
+			_, _ = polling.tp.ReadLine()
+		}
+		time.Sleep(40 * time.Millisecond)
+	}
+
+	res := result(t, world)
+	assert.Equal(t, uint8(1), res.Winner)
+	assert.Equal(t, []core.Forfeit{{Player: 2, Reason: reasonInactive, Iteration: res.Iteration}}, res.Forfeits)
+}
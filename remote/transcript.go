@@ -0,0 +1,197 @@
+package remote
+
+/*
+  This file provides the transcript of the protocol for debugging client AIs. A transcript records every
+  command of a connection with its response, the time, the player and the iteration of the world as one
+  JSON line (see TranscriptEntry). It can be written by the server (see Server.Transcript) for all
+  connections and by a client (see WithTranscript) for its own connection. The large payloads of STATUS and
+  MAP are summarised (e.g. 'iteration 120, 14 units'), all other responses are recorded as they are and
+  passwords are never recorded.
+*/
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TranscriptEntry is a single command with its response.
+type TranscriptEntry struct {
+	Time      time.Time // Time of the command
+	Conn      string    `json:",omitempty"` // Remote address of the connection
+	Player    uint8     // Player ID (0 = not assigned or unknown)
+	Iteration uint64    // Iteration of the world when the command was received
+	Command   string    // Command line (passwords are replaced by '***')
+	Response  string    // Response line (a summary for the payload of STATUS and MAP)
+	Size      int       `json:",omitempty"` // Length of a summarised response in bytes
+}
+
+// Name returns the command name of the entry in upper case (e.g. 'MOVE').
+func (e *TranscriptEntry) Name() string {
+	name, _, _ := strings.Cut(strings.TrimSpace(e.Command), " ")
+	return strings.ToUpper(name)
+}
+
+// String returns the entry as a single readable line.
+func (e *TranscriptEntry) String() string {
+	resp := e.Response
+	if e.Size > 0 {
+		resp = fmt.Sprintf("%s (%d bytes)", resp, e.Size)
+	}
+	return fmt.Sprintf("%s  player %d  iter %d  %s  >>  %s", e.Time.Format("15:04:05.000"), e.Player, e.Iteration, e.Command, resp)
+}
+
+//--------  Transcript  ----------------------------------------------------------------------------------------------//
+
+// Transcript writes the entries as JSON lines. It can be shared by many connections.
+type Transcript struct {
+	mux sync.Mutex    // Mutex for thread-safe operations
+	enc *json.Encoder // JSON lines writer
+}
+
+// NewTranscript creates a new transcript that writes to w.
+func NewTranscript(w io.Writer) *Transcript {
+	return &Transcript{enc: json.NewEncoder(w)}
+}
+
+// Record writes the entry. The password of AUTH is removed and the payload of STATUS and MAP is summarised.
+// A nil transcript records nothing.
+func (t *Transcript) Record(e TranscriptEntry) {
+	if t == nil {
+		return
+	}
+
+	e.Command = redact(e.Command)
+	if name := e.Name(); name == "STATUS" || name == "MAP" {
+		if summary, ok := summarise(e.Response); ok {
+			e.Size = len(e.Response)
+			e.Response = summary
+		}
+	}
+
+	t.mux.Lock()
+	defer t.mux.Unlock()
+	if err := t.enc.Encode(&e); err != nil {
+		fmt.Printf("err: transcript: %v\n", err)
+	}
+}
+
+// ReadTranscript reads all entries of a transcript.
+func ReadTranscript(r io.Reader) ([]*TranscriptEntry, error) {
+	entries := make([]*TranscriptEntry, 0)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		e := new(TranscriptEntry)
+		if err := json.Unmarshal(scanner.Bytes(), e); err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// FilterTranscript returns the entries of the player (negative = all players)
+// with one of the command names (empty = all commands, e.g. 'MOVE' or 'status').
+func FilterTranscript(entries []*TranscriptEntry, player int, names []string) []*TranscriptEntry {
+	result := make([]*TranscriptEntry, 0, len(entries))
+	for _, e := range entries {
+		if player >= 0 && int(e.Player) != player {
+			continue
+		}
+		if len(names) > 0 && !containsFold(names, e.Name()) {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// redact replaces the password of an AUTH command with '***'.
+func redact(line string) string {
+	fields := strings.Fields(line)
+	if len(fields) < 2 || strings.ToUpper(fields[0]) != "AUTH" {
+		return line
+	}
+	fields[len(fields)-1] = "***"
+	return strings.Join(fields, " ")
+}
+
+// summarise returns a short summary of a STATUS or MAP payload (protocol version 1 or 2).
+// Errors and unknown responses are not summarised (ok = false).
+func summarise(resp string) (summary string, ok bool) {
+	if strings.HasPrefix(resp, v1ErrorPrefix) {
+		return "", false // keep errors
+	}
+	data := []byte(resp)
+	if strings.HasPrefix(resp, `{"OK"`) {
+		r := new(Response)
+		if err := json.Unmarshal(data, r); err != nil || !r.OK || len(r.Data) == 0 {
+			return "", false // keep errors
+		}
+		data = r.Data
+	}
+
+	// compressed (see GZIP): version 1 sends the plain base64 text, version 2 a JSON string
+	if len(data) > 0 && data[0] != '{' {
+		return "compressed payload", true
+	}
+
+	// world (STATUS and MAP), CompactStatus or MapInfo (see COMPACT)
+	var payload struct {
+		Iteration *uint64
+		XWidth    int
+		YHeight   int
+		Tiles     [][]*struct{ Unit *struct{} }
+		Units     []json.RawMessage
+		Result    *core.Result
+	}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return "", false
+	}
+	parts := make([]string, 0, 4)
+	if payload.Iteration != nil {
+		parts = append(parts, fmt.Sprintf("iteration %d", *payload.Iteration))
+	}
+	if payload.XWidth > 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d tiles", payload.XWidth, payload.YHeight))
+	}
+	units := len(payload.Units)
+	for _, col := range payload.Tiles {
+		for _, tile := range col {
+			if tile != nil && tile.Unit != nil {
+				units++
+			}
+		}
+	}
+	if payload.Iteration != nil {
+		parts = append(parts, fmt.Sprintf("%d units", units))
+	}
+	if payload.Result != nil {
+		parts = append(parts, "finished")
+	}
+	if len(parts) == 0 {
+		return "", false
+	}
+	return strings.Join(parts, ", "), true
+}
+
+// containsFold reports whether the list contains s (case-insensitive).
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,155 @@
+package remote
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// lockedBuffer is a thread-safe buffer for transcripts.
+type lockedBuffer struct {
+	mux sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	return b.buf.Write(p)
+}
+
+// entries reads the transcript.
+func (b *lockedBuffer) entries(t *testing.T) []*TranscriptEntry {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	entries, err := ReadTranscript(bytes.NewReader(b.buf.Bytes()))
+	require.NoError(t, err)
+	return entries
+}
+
+func TestTranscript(t *testing.T) {
+	world := core.NewWorld(3, 2)
+	world.Iteration = 8
+	world.Tile(1, 1).Unit = core.NewUnit(core.RED, core.TANK)
+	b, err := json.Marshal(world)
+	require.NoError(t, err)
+	status := string(b)
+
+	buf := new(bytes.Buffer)
+	tr := NewTranscript(buf)
+	now := time.Now()
+	tr.Record(TranscriptEntry{Time: now, Player: 1, Iteration: 7, Command: "AUTH 1 secret", Response: "1"})
+	tr.Record(TranscriptEntry{Time: now, Player: 1, Iteration: 8, Command: "status", Response: status})
+	tr.Record(TranscriptEntry{Time: now, Player: 2, Iteration: 8, Command: "MOVE 1 1 1 2", Response: "OK"})
+	(*Transcript)(nil).Record(TranscriptEntry{Command: "PLAYER"}) // no transcript
+	assert.NotContains(t, buf.String(), "secret")
+
+	// read
+	entries, err := ReadTranscript(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	require.Len(t, entries, 3)
+	assert.Equal(t, "AUTH 1 ***", entries[0].Command)
+	assert.Equal(t, uint64(7), entries[0].Iteration)
+	assert.Equal(t, "STATUS", entries[1].Name())
+	assert.Equal(t, "iteration 8, 3x2 tiles, 1 units", entries[1].Response)
+	assert.Equal(t, len(status), entries[1].Size)
+	assert.Contains(t, entries[1].String(), fmt.Sprintf("(%d bytes)", len(status)))
+
+	// filter
+	assert.Len(t, FilterTranscript(entries, -1, nil), 3)
+	assert.Len(t, FilterTranscript(entries, 1, nil), 2)
+	assert.Len(t, FilterTranscript(entries, 3, nil), 0)
+	assert.Equal(t, entries[1:], FilterTranscript(entries, -1, []string{"Status", " move"}))
+	assert.Equal(t, entries[2:], FilterTranscript(entries, 2, []string{"MOVE"}))
+
+	// error
+	_, err = ReadTranscript(strings.NewReader("{}\n\nnot json\n"))
+	assert.EqualError(t, err, "line 3: invalid character 'o' in literal null (expecting 'u')")
+}
+
+func TestTranscriptSummary(t *testing.T) {
+	compact, err := json.Marshal(&Response{OK: true, Code: CodeOK, Data: []byte(`{"Iteration":42,"Units":[{"X":1},{"X":2}]}`)})
+	require.NoError(t, err)
+	result, err := json.Marshal(&Response{OK: true, Code: CodeOK, Data: []byte(`{"Iteration":50,"Units":[],"Result":{}}`)})
+	require.NoError(t, err)
+	failed, err := json.Marshal(&Response{OK: false, Code: CodeError, Error: "boom"})
+	require.NoError(t, err)
+	long := strings.Repeat("[1,2],", 100)
+
+	tests := []struct {
+		command  string
+		response string
+		want     string
+		summary  bool
+	}{
+		{"STATUS", string(compact), "iteration 42, 2 units", true},                          // version 2, CompactStatus
+		{"STATUS", string(result), "iteration 50, 0 units, finished", true},                 // finished game
+		{"MAP", `{"XWidth":15,"YHeight":8,"Terrain":[]}`, "15x8 tiles", true},               // MapInfo
+		{"MAP", "H4sIAAAAAAAA/6pWKs9ILEpVslIqyUhVqgUEAAD//w==", "compressed payload", true}, // GZIP, version 1
+		{"STATUS", `{"OK":true,"Code":"OK","Data":"H4sIAAAA"}`, "compressed payload", true}, // GZIP, version 2
+		{"STATUS", "err: not authenticated", "err: not authenticated", false},               // version 1 error
+		{"STATUS", string(failed), string(failed), false},                                   // version 2 error
+		{"RANGE 1 1", long, long, false},                                                    // other commands are intact
+	}
+	for _, tt := range tests {
+		buf := new(bytes.Buffer)
+		NewTranscript(buf).Record(TranscriptEntry{Command: tt.command, Response: tt.response})
+		entries, err := ReadTranscript(buf)
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		assert.Equal(t, tt.want, entries[0].Response, tt.response)
+		if tt.summary {
+			assert.Equal(t, len(tt.response), entries[0].Size, tt.response)
+		} else {
+			assert.Zero(t, entries[0].Size, tt.response)
+		}
+	}
+}
+
+func TestServerTranscript(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(0, 0).Unit = core.NewUnit(core.RED, core.TANK)
+	serverLog := new(lockedBuffer)
+	s, host, port := testServer(t, world, 1, func(s *Server) {
+		s.Password = "secret"
+		s.Transcript = NewTranscript(serverLog)
+	})
+
+	clientLog := new(lockedBuffer)
+	c, err := NewClient(host, port, WithPassword("secret"), WithTranscript(NewTranscript(clientLog)))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return c.Snapshot() != nil // first STATUS
+	}, time.Second, 10*time.Millisecond)
+	assert.ErrorIs(t, c.Move(4, 4, 3, 4), core.ErrNoUnit)
+	require.NoError(t, s.Close())
+
+	// server: all commands of the connection
+	entries := serverLog.entries(t)
+	require.GreaterOrEqual(t, len(entries), 4)
+	assert.Equal(t, "PROTOCOL 2", entries[0].Command)
+	assert.Equal(t, uint8(0), entries[0].Player)
+	assert.Equal(t, "AUTH ***", entries[1].Command)
+	assert.Equal(t, uint8(1), entries[1].Player)
+	moves := FilterTranscript(entries, 1, []string{"MOVE"})
+	require.Len(t, moves, 1)
+	assert.Contains(t, moves[0].Response, CodeNoUnit)
+	assert.NotEmpty(t, moves[0].Conn)
+	assert.NotEmpty(t, FilterTranscript(entries, 1, []string{"STATUS"}))
+
+	// client: own commands
+	entries = clientLog.entries(t)
+	require.GreaterOrEqual(t, len(entries), 3)
+	assert.Equal(t, "AUTH ***", entries[1].Command)
+	moves = FilterTranscript(entries, 1, []string{"MOVE"})
+	require.Len(t, moves, 1)
+	assert.Equal(t, "MOVE 4 4 3 4", moves[0].Command)
+}
@@ -0,0 +1,363 @@
+package remote
+
+/*
+  This file provides a WebSocket gateway (RFC 6455) for browser clients. Every WebSocket message is a JSON
+  command like {"Command":"MOVE","Args":[1,1,2,1]} and every answer is a Response of protocol version 2.
+  The gateway converts the messages into command lines, so a WebSocket connection is handled by the same
+  session as a TCP connection (same player seats, censorship and rate limits).
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// wsGUID is the magic value of the WebSocket handshake.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// WebSocket opcodes
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xA
+)
+
+// wsMaxMessage is the max. size of a WebSocket message in bytes.
+const wsMaxMessage = 1 << 16
+
+// WSCommand is a command sent by a WebSocket client.
+// The arguments can be numbers or strings, e.g. {"Command":"MOVE","Args":[1,1,2,1]} or {"Command":"STATUS"}.
+type WSCommand struct {
+	Command string            // Command name (or the complete command line)
+	Args    []json.RawMessage `json:",omitempty"` // Arguments of the command
+}
+
+// WebSocketHandler returns an HTTP handler that upgrades requests to WebSocket connections
+// and serves them like TCP clients (see ServeConn). Browser pages of other hosts are rejected (see WSOrigins).
+func (s *Server) WebSocketHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !s.allowOrigin(r) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		conn, err := wsUpgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := s.ServeConn(conn); err != nil {
+			s.logf("reject %v: %v\n", conn.RemoteAddr(), err)
+		}
+	})
+}
+
+//--------  Handshake  -----------------------------------------------------------------------------------------------//
+
+// allowOrigin reports whether the request may open a WebSocket connection. Requests without Origin (no browser)
+// and pages of the same host are allowed, pages of other hosts only if they are listed in WSOrigins.
+func (s *Server) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true // no browser
+	}
+	for _, o := range s.WSOrigins {
+		if o == "*" || strings.EqualFold(strings.TrimSuffix(o, "/"), origin) {
+			return true
+		}
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// wsUpgrade checks the handshake of the request and takes over the connection.
+func wsUpgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if r.Method != http.MethodGet ||
+		!headerContains(r.Header, "Connection", "upgrade") ||
+		!headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, errors.New("websocket upgrade required")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("websocket version 13 required")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("websocket key missing")
+	}
+
+	// take over the connection
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, errors.New("websocket not supported")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	// accept
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + wsAccept(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	// all answers are JSON (see Response)
+	return &wsConn{
+		Conn:    conn,
+		br:      rw.Reader,
+		pending: []byte("PROTOCOL 2\r\n"),
+		skip:    1,
+	}, nil
+}
+
+// wsAccept returns the accept value of the handshake for the key of the client.
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// headerContains reports whether a comma-separated header contains the token (case-insensitive).
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+//--------  Connection  ----------------------------------------------------------------------------------------------//
+
+// wsConn converts WebSocket messages into command lines and the response lines into WebSocket messages.
+// It implements net.Conn, so it can be served like a TCP connection.
+type wsConn struct {
+	net.Conn               // Hijacked connection
+	br       *bufio.Reader // Buffered reader of the connection
+	pending  []byte        // Command lines not yet read
+	skip     int           // Number of response lines to discard (answer of PROTOCOL 2)
+	wmux     sync.Mutex    // Mutex for writing frames
+}
+
+// Read returns the commands of the client as command lines.
+func (c *wsConn) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		msg, err := c.readMessage()
+		if err != nil {
+			return 0, err
+		}
+		line, err := wsCommandLine(msg)
+		if err != nil {
+			b, _ := json.Marshal(&Response{Code: CodeInvalidCommand, Error: err.Error()})
+			if err := c.writeFrame(wsText, b); err != nil {
+				return 0, err
+			}
+			continue
+		}
+		c.pending = []byte(line + "\r\n")
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write sends every response line as a WebSocket text message.
+func (c *wsConn) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(string(p), "\r\n") {
+		if line == "" {
+			continue
+		}
+		if c.skip > 0 {
+			c.skip--
+			continue
+		}
+		if err := c.writeFrame(wsText, []byte(line)); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close sends a close frame and closes the connection.
+func (c *wsConn) Close() error {
+	_ = c.Conn.SetWriteDeadline(time.Now().Add(time.Second)) // don't wait for a blocked client
+	_ = c.writeFrame(wsClose, nil)
+	return c.Conn.Close()
+}
+
+// readMessage reads the next text message and answers control frames.
+func (c *wsConn) readMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		fin, opcode, payload, err := readFrame(c.br, true)
+		if err != nil {
+			return nil, err
+		}
+		switch opcode {
+		case wsPing:
+			if err := c.writeFrame(wsPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			return nil, io.EOF
+		case wsBinary:
+			return nil, errors.New("websocket: binary messages not supported")
+		}
+
+		msg = append(msg, payload...)
+		if len(msg) > wsMaxMessage {
+			return nil, errors.New("websocket: message too large")
+		}
+		if fin {
+			return msg, nil
+		}
+	}
+}
+
+// writeFrame writes a single unmasked frame (server to client).
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.wmux.Lock()
+	defer c.wmux.Unlock()
+
+	_, err := c.Conn.Write(encodeFrame(opcode, payload, nil))
+	return err
+}
+
+//--------  Helper  --------------------------------------------------------------------------------------------------//
+
+// wsCommandLine converts a JSON command into a command line. PROTOCOL is rejected (see wsUpgrade).
+func wsCommandLine(msg []byte) (string, error) {
+	cmd := new(WSCommand)
+	if err := json.Unmarshal(msg, cmd); err != nil {
+		return "", errors.New("invalid JSON command")
+	}
+
+	parts := []string{cmd.Command}
+	for _, raw := range cmd.Args {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			s = string(raw) // number
+		}
+		parts = append(parts, s)
+	}
+
+	// a message is exactly one command line
+	line := strings.Join(parts, " ")
+	if strings.ContainsAny(line, "\r\n") {
+		return "", errors.New("invalid JSON command")
+	}
+
+	// the JSON framing needs protocol version 2
+	if name, _, _ := strings.Cut(strings.TrimSpace(line), " "); strings.EqualFold(name, "PROTOCOL") {
+		return "", errors.New("PROTOCOL is always version 2 on WebSocket connections")
+	}
+	return line, nil
+}
+
+// readFrame reads a single frame. Frames of clients must be masked.
+func readFrame(r io.Reader, masked bool) (fin bool, opcode byte, payload []byte, err error) {
+	head := make([]byte, 2)
+	if _, err = io.ReadFull(r, head); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = head[0] & 0x0F
+	if (head[1]&0x80 != 0) != masked {
+		err = errors.New("websocket: invalid mask")
+		return
+	}
+
+	// length
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err = io.ReadFull(r, ext); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err = io.ReadFull(r, ext); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	if length > wsMaxMessage {
+		err = errors.New("websocket: message too large")
+		return
+	}
+
+	// payload
+	var mask [4]byte
+	if masked {
+		if _, err = io.ReadFull(r, mask[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(r, payload); err != nil {
+		return
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// encodeFrame encodes a single final frame (masked if a mask is given).
+func encodeFrame(opcode byte, payload []byte, mask []byte) []byte {
+	buf := new(bytes.Buffer)
+	buf.WriteByte(0x80 | opcode)
+
+	// length
+	var maskBit byte
+	if mask != nil {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		buf.WriteByte(maskBit | byte(n))
+	case n <= 0xFFFF:
+		buf.WriteByte(maskBit | 126)
+		_ = binary.Write(buf, binary.BigEndian, uint16(n))
+	default:
+		buf.WriteByte(maskBit | 127)
+		_ = binary.Write(buf, binary.BigEndian, uint64(n))
+	}
+
+	// payload
+	if mask == nil {
+		buf.Write(payload)
+		return buf.Bytes()
+	}
+	buf.Write(mask)
+	for i, b := range payload {
+		buf.WriteByte(b ^ mask[i%4])
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,155 @@
+package remote
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SchnorcherSepp/TankWars2/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// wsClient is an in-process WebSocket test client.
+type wsClient struct {
+	t    *testing.T
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+// dialWS connects to the WebSocket gateway of the server.
+func dialWS(t *testing.T, s *Server) *wsClient {
+	hs := httptest.NewServer(s.WebSocketHandler())
+	t.Cleanup(hs.Close)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(hs.URL, "http://"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	// handshake
+	const key = "dGhlIHNhbXBsZSBub25jZQ=="
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	require.NoError(t, err)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	require.Equal(t, "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=", resp.Header.Get("Sec-WebSocket-Accept"))
+
+	return &wsClient{t: t, conn: conn, br: br}
+}
+
+// send sends a JSON message and returns the response.
+func (c *wsClient) send(msg string) *Response {
+	_, err := c.conn.Write(encodeFrame(wsText, []byte(msg), []byte{1, 2, 3, 4}))
+	require.NoError(c.t, err)
+	return c.read()
+}
+
+// read reads the next response.
+func (c *wsClient) read() *Response {
+	_, opcode, payload, err := readFrame(c.br, false)
+	require.NoError(c.t, err)
+	require.Equal(c.t, byte(wsText), opcode)
+	resp := new(Response)
+	require.NoError(c.t, json.Unmarshal(payload, resp))
+	return resp
+}
+
+func TestWebSocket(t *testing.T) {
+	world := core.NewWorld(5, 5)
+	world.Tile(1, 1).Unit = core.NewUnit(core.RED, core.TANK)
+	s, _, _ := testServer(t, world, 1, nil)
+	c := dialWS(t, s)
+
+	// same commands as TCP (protocol version 2)
+	resp := c.send(`{"Command":"PLAYER"}`)
+	assert.True(t, resp.OK)
+	assert.Equal(t, "1", string(resp.Data))
+
+	resp = c.send(`{"Command":"MOVE","Args":[1,1,2,1]}`)
+	require.True(t, resp.OK, resp.Error)
+	assert.Equal(t, core.MOVE, resp.Activity.Name)
+
+	resp = c.send(`{"Command":"compact","Args":["ON"]}`)
+	assert.True(t, resp.OK)
+
+	resp = c.send(`{"Command":"DIST 0 0 2 0"}`)
+	assert.Equal(t, "2", string(resp.Data))
+
+	// errors
+	assert.Equal(t, CodeInvalidArgs, c.send(`{"Command":"MOVE","Args":["foo"]}`).Code)
+	assert.Equal(t, CodeInvalidCommand, c.send(`no json`).Code)
+	assert.Equal(t, CodeInvalidCommand, c.send(`{"Command":"PLAYER\nPLAYER"}`).Code)
+
+	// ping
+	_, err := c.conn.Write(encodeFrame(wsPing, []byte("hi"), []byte{1, 2, 3, 4}))
+	require.NoError(t, err)
+	_, opcode, payload, err := readFrame(c.br, false)
+	require.NoError(t, err)
+	assert.Equal(t, byte(wsPong), opcode)
+	assert.Equal(t, "hi", string(payload))
+}
+
+func TestWebSocketProtocol(t *testing.T) {
+	s, _, _ := testServer(t, core.NewWorld(3, 3), 1, nil)
+	c := dialWS(t, s)
+
+	// PROTOCOL would break the JSON framing
+	for _, msg := range []string{`{"Command":"PROTOCOL","Args":[1]}`, `{"Command":" protocol 1"}`, `{"Command":"PROTOCOL 2"}`} {
+		resp := c.send(msg)
+		assert.False(t, resp.OK, msg)
+		assert.Equal(t, CodeInvalidCommand, resp.Code, msg)
+	}
+
+	// still version 2
+	resp := c.send(`{"Command":"PLAYER"}`)
+	assert.True(t, resp.OK)
+	assert.Equal(t, "1", string(resp.Data))
+}
+
+func TestWebSocketHandshake(t *testing.T) {
+	s, _, _ := testServer(t, core.NewWorld(3, 3), 1, nil)
+	hs := httptest.NewServer(s.WebSocketHandler())
+	defer hs.Close()
+
+	resp, err := http.Get(hs.URL)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	assert.Equal(t, 0, s.Online())
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	s, _, _ := testServer(t, core.NewWorld(3, 3), 1, func(s *Server) {
+		s.WSOrigins = []string{"https://example.com/"}
+	})
+	hs := httptest.NewServer(s.WebSocketHandler())
+	defer hs.Close()
+
+	// status of a request without upgrade (400 = origin accepted)
+	status := func(origin string) int {
+		req, err := http.NewRequest(http.MethodGet, hs.URL, nil)
+		require.NoError(t, err)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		_ = resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, http.StatusBadRequest, status(""))                    // no browser
+	assert.Equal(t, http.StatusBadRequest, status(hs.URL))                // same host
+	assert.Equal(t, http.StatusBadRequest, status("https://example.com")) // allowed
+	assert.Equal(t, http.StatusForbidden, status("https://evil.example"))
+	assert.Equal(t, http.StatusForbidden, status("null"))
+
+	s.WSOrigins = []string{"*"}
+	assert.Equal(t, http.StatusBadRequest, status("https://evil.example"))
+}